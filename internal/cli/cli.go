@@ -0,0 +1,179 @@
+// Package cli implements zsm's non-interactive subcommands.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/config"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"list", "print sessions as JSON, NDJSON, TSV or a template", runList},
+	{"kill", "kill sessions matching name patterns and resource predicates", runKill},
+	{"grep", "search the history of every session for a pattern", runGrep},
+	{"export", "save session history as text, ANSI, HTML or asciicast", runExport},
+	{"pick", "choose sessions in the TUI and print their names", runPick},
+	{"config", "check the config file and print it, or the defaults", runConfig},
+}
+
+// Run executes the subcommand name with args and returns the process exit
+// code: 0 on success, 1 on failure and 2 on usage errors.
+func Run(name string, args []string) int {
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(args, os.Stdout, os.Stderr)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "zsm: unknown command %q\n\n", name)
+	Usage(os.Stderr)
+	return 2
+}
+
+// Usage writes the list of subcommands to w.
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: zsm [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Without a command, zsm starts the interactive session manager.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.summary)
+	}
+}
+
+// newFlagSet returns a FlagSet for subcommand name that reports errors to
+// stderr instead of exiting.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("zsm "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parseFlags parses args into fs and returns the exit code to use when
+// parsing stopped early (-h or a bad flag), or -1 to continue.
+func parseFlags(fs *flag.FlagSet, args []string) int {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	return -1
+}
+
+// parseArgs is like parseFlags but allows flags and positional arguments to
+// be interleaved, returning the positional arguments in order.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, int) {
+	var positional []string
+	for {
+		if code := parseFlags(fs, args); code >= 0 {
+			return nil, code
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, -1
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), -1
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// sources holds the -backend and -host flags shared by every command that
+// lists sessions.
+type sources struct {
+	backend string
+	hosts   stringList
+}
+
+func registerSourceFlags(fs *flag.FlagSet) *sources {
+	src := &sources{}
+	fs.StringVar(&src.backend, "backend", "", "comma-separated session backends: zmx, tmux, screen or auto (default zmx, or auto if zmx is not installed)")
+	fs.Var(&src.hosts, "host", "also manage zmx sessions on this ssh host (repeatable; adds to hosts in the config file)")
+	return src
+}
+
+// apply activates the selected backends and the hosts from the config file
+// plus any -host flags. It returns the loaded config for other settings.
+func (src *sources) apply() (config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return config.Config{}, err
+	}
+	spec := src.backend
+	if spec == "" {
+		spec = "zmx"
+		if _, err := exec.LookPath("zmx"); err != nil {
+			spec = "auto"
+		}
+	}
+	if err := zmx.UseBackends(spec); err != nil {
+		return config.Config{}, err
+	}
+	zmx.UseHosts(append(cfg.Hosts, src.hosts...))
+	return cfg, nil
+}
+
+// loadProtection loads the protect patterns from the config file and the
+// sessions marked as protected from the TUI.
+func loadProtection(cfg config.Config) (*zmx.Protection, error) {
+	return zmx.NewProtection(cfg.Protect, config.ProtectedPath())
+}
+
+// loadMeta loads the aliases, tags and notes recorded from the TUI.
+func loadMeta() (*zmx.MetaStore, error) {
+	return zmx.LoadMetaStore(config.MetaPath())
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// loadSessions fetches all sessions and enriches them with process info and
+// metadata, mirroring what the TUI shows. Backends and hosts that fail are reported on
+// stderr as long as at least one session could be listed.
+func loadSessions(stderr io.Writer) ([]zmx.Session, error) {
+	all, err := zmx.FetchSessions()
+	var sessions []zmx.Session
+	for _, s := range all {
+		if s.Degraded() {
+			fmt.Fprintf(stderr, "zsm: warning: %s: %s\n", s.Host, s.Error)
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	if err != nil {
+		if len(sessions) == 0 {
+			return nil, err
+		}
+		fmt.Fprintf(stderr, "zsm: warning: %v\n", err)
+	}
+	zmx.ApplyProcessInfo(sessions, zmx.FetchProcessInfo(sessions))
+	if meta, err := loadMeta(); err != nil {
+		fmt.Fprintf(stderr, "zsm: warning: %v\n", err)
+	} else {
+		meta.Apply(sessions)
+	}
+	return sessions, nil
+}
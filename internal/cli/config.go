@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/config"
+)
+
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("config", stderr)
+	printDefault := fs.Bool("print-default", false, "print the default configuration instead of the current one")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: zsm config [flags]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintf(stderr, "Checks %s and prints the configuration in effect.\n", config.Path())
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := config.Default()
+	if !*printDefault {
+		var err error
+		if cfg, err = config.Load(); err != nil {
+			fmt.Fprintf(stderr, "zsm config: %v\n", err)
+			return 1
+		}
+	}
+	if err := config.Write(stdout, cfg); err != nil {
+		fmt.Fprintf(stderr, "zsm config: %v\n", err)
+		return 1
+	}
+	return 0
+}
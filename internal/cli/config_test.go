@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	var stdout, stderr strings.Builder
+	if code := runConfig([]string{"--print-default"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, `kill = ["k"]`) || !strings.Contains(out, `log_height = 4`) {
+		t.Fatalf("default config missing keys:\n%s", out)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "zsm"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "zsm", "config.toml"), []byte("[keys]\nkill = [\"s\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := runConfig(nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, `kill = ["s"]`) || !strings.Contains(out, "sort = []") {
+		t.Fatalf("current config should show the rebinding:\n%s", out)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "zsm", "config.toml"), []byte("log_height = 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	if code := runConfig(nil, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "log_height: must be between 1 and 50") {
+		t.Fatalf("exit %d, stderr %q; want the validation error", code, stderr.String())
+	}
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+func runExport(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("export", stderr)
+	format := fs.String("format", "", "text, ansi, html or cast (default from the -o extension, else text)")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	dir := fs.String("dir", "", "write one file per matching session into this directory")
+	width := fs.Int("width", zmx.ExportWidth, "terminal width for html rendering and the cast header")
+	height := fs.Int("height", zmx.ExportHeight, "terminal height for html rendering and the cast header")
+	regex := fs.Bool("regex", false, "treat session patterns as regular expressions instead of globs")
+	src := registerSourceFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: zsm export [flags] session...")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Writes the full history of a session as plain text, raw ANSI, HTML or an")
+		fmt.Fprintln(stderr, "asciicast v2 recording. Session names may be globs; with -dir every match")
+		fmt.Fprintln(stderr, "is exported to its own file.")
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+	patterns, code := parseArgs(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if len(patterns) == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *out != "" && *dir != "" {
+		fmt.Fprintln(stderr, "zsm export: -o and -dir are mutually exclusive")
+		return 2
+	}
+	if *width < 1 || *height < 1 {
+		fmt.Fprintln(stderr, "zsm export: -width and -height must be >= 1")
+		return 2
+	}
+	f := zmx.ExportText
+	if *format != "" {
+		var err error
+		if f, err = zmx.ParseExportFormat(*format); err != nil {
+			fmt.Fprintf(stderr, "zsm export: %v\n", err)
+			return 2
+		}
+	} else if guessed, ok := zmx.ExportFormatForPath(*out); ok {
+		f = guessed
+	}
+	var names []*regexp.Regexp
+	for _, p := range patterns {
+		e := zmx.GlobRegexp(p)
+		if *regex {
+			e = p
+		}
+		re, err := regexp.Compile(e)
+		if err != nil {
+			fmt.Fprintf(stderr, "zsm export: invalid pattern %q: %v\n", p, err)
+			return 2
+		}
+		names = append(names, re)
+	}
+
+	if _, err := src.apply(); err != nil {
+		fmt.Fprintf(stderr, "zsm export: %v\n", err)
+		return 1
+	}
+	all, err := loadSessions(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm export: %v\n", err)
+		return 1
+	}
+	zmx.SortSessions(all, zmx.SortByName, true)
+	var targets []zmx.Session
+	for _, s := range all {
+		if matchesAny(names, s) {
+			targets = append(targets, s)
+		}
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(stderr, "zsm export: no matching sessions")
+		return 1
+	}
+	opts := zmx.ExportOptions{Width: *width, Height: *height}
+
+	if *dir == "" {
+		if len(targets) > 1 {
+			fmt.Fprintf(stderr, "zsm export: %d sessions match; use -dir to export several\n", len(targets))
+			return 2
+		}
+		if err := exportOne(targets[0], *out, f, opts, stdout); err != nil {
+			fmt.Fprintf(stderr, "zsm export: %s: %v\n", targets[0].Key(), firstLine(err))
+			return 1
+		}
+		return 0
+	}
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		fmt.Fprintf(stderr, "zsm export: %v\n", err)
+		return 1
+	}
+	failed := 0
+	paths := zmx.ExportPaths(*dir, targets, f)
+	for i, s := range targets {
+		path := paths[i]
+		if err := zmx.ExportToFile(path, s, f, opts); err != nil {
+			fmt.Fprintf(stderr, "zsm export: %s: %v\n", s.Key(), firstLine(err))
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "exported %s to %s\n", s.Key(), path)
+	}
+	if failed > 0 {
+		fmt.Fprintf(stderr, "zsm export: %d of %d session(s) not exported\n", failed, len(targets))
+		return 1
+	}
+	return 0
+}
+
+// exportOne writes the history of s to path, or to stdout if path is empty.
+func exportOne(s zmx.Session, path string, f zmx.ExportFormat, opts zmx.ExportOptions, stdout io.Writer) error {
+	if path != "" {
+		return zmx.ExportToFile(path, s, f, opts)
+	}
+	history, err := zmx.ReadHistory(s)
+	if err != nil {
+		return err
+	}
+	return zmx.Export(stdout, s, history, f, opts)
+}
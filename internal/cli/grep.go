@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+// grepWidth and grepHeight size the virtual terminal histories are replayed
+// through; the width is generous so that long lines are not wrapped.
+const (
+	grepWidth  = 512
+	grepHeight = 100
+)
+
+func runGrep(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("grep", stderr)
+	ignoreCase := fs.Bool("i", false, "ignore case")
+	fixed := fs.Bool("F", false, "treat the pattern as a fixed string instead of a regular expression")
+	context := fs.Int("C", 0, "print this many lines of context around each match")
+	lines := fs.Int("lines", 10000, "lines of history searched per session")
+	workers := fs.Int("j", zmx.GrepWorkers, "sessions searched at once")
+	regex := fs.Bool("regex", false, "treat session patterns as regular expressions instead of globs")
+	src := registerSourceFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: zsm grep [flags] pattern [session...]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Searches the history of every session (or those matching a session pattern)")
+		fmt.Fprintln(stderr, "and prints matching lines as session:line:text. Exits 1 if nothing matched.")
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+	positional, code := parseArgs(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if len(positional) == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *context < 0 || *lines < 1 || *workers < 1 {
+		fmt.Fprintln(stderr, "zsm grep: -C must be >= 0, -lines and -j >= 1")
+		return 2
+	}
+
+	expr := positional[0]
+	if *fixed {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if *ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm grep: invalid pattern %q: %v\n", positional[0], err)
+		return 2
+	}
+	var names []*regexp.Regexp
+	for _, p := range positional[1:] {
+		e := zmx.GlobRegexp(p)
+		if *regex {
+			e = p
+		}
+		nre, err := regexp.Compile(e)
+		if err != nil {
+			fmt.Fprintf(stderr, "zsm grep: invalid session pattern %q: %v\n", p, err)
+			return 2
+		}
+		names = append(names, nre)
+	}
+
+	if _, err := src.apply(); err != nil {
+		fmt.Fprintf(stderr, "zsm grep: %v\n", err)
+		return 1
+	}
+	all, err := loadSessions(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm grep: %v\n", err)
+		return 1
+	}
+	zmx.SortSessions(all, zmx.SortByName, true)
+	var sessions []zmx.Session
+	for _, s := range all {
+		if matchesAny(names, s) {
+			sessions = append(sessions, s)
+		}
+	}
+
+	results := zmx.Grep(sessions, re.MatchString, zmx.GrepOptions{
+		Width:   grepWidth,
+		Height:  grepHeight,
+		Lines:   *lines,
+		Context: *context,
+		Workers: *workers,
+	})
+	found := false
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(stderr, "zsm grep: %s: %v\n", r.Session.Key(), firstLine(r.Err))
+			continue
+		}
+		found = true
+	}
+	if err := writeGrep(stdout, results, *context > 0); err != nil {
+		fmt.Fprintf(stderr, "zsm grep: %v\n", err)
+		return 1
+	}
+	if !found {
+		return 1
+	}
+	return 0
+}
+
+// matchesAny reports whether the key of s matches one of patterns, or
+// whether there are no patterns at all. Placeholders for unreachable hosts
+// never match.
+func matchesAny(patterns []*regexp.Regexp, s zmx.Session) bool {
+	if s.Degraded() {
+		return false
+	}
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, re := range patterns {
+		if re.MatchString(s.Key()) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeGrep prints matches like grep does for several files: matching lines
+// as key:line:text and context lines as key-line-text, with "--" between
+// groups of lines that are not adjacent. Line numbers start at 1. Sessions
+// that failed are skipped.
+func writeGrep(w io.Writer, results []zmx.GrepResult, separators bool) error {
+	printed := false
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		key := r.Session.Key()
+		lines := make(map[int]string)
+		matched := make(map[int]bool)
+		for _, m := range r.Matches {
+			for i, text := range m.Before {
+				lines[m.Line-len(m.Before)+i] = text
+			}
+			for i, text := range m.After {
+				lines[m.Line+1+i] = text
+			}
+			lines[m.Line] = m.Text
+			matched[m.Line] = true
+		}
+		order := slices.Sorted(maps.Keys(lines))
+		for i, n := range order {
+			if separators && printed && (i == 0 || n > order[i-1]+1) {
+				if _, err := fmt.Fprintln(w, "--"); err != nil {
+					return err
+				}
+			}
+			sep := "-"
+			if matched[n] {
+				sep = ":"
+			}
+			if _, err := fmt.Fprintf(w, "%s%s%d%s%s\n", key, sep, n+1, sep, lines[n]); err != nil {
+				return err
+			}
+			printed = true
+		}
+	}
+	return nil
+}
+
+// firstLine returns the first line of err's message; backend errors append
+// the command's output after a newline.
+func firstLine(err error) string {
+	line, _, _ := strings.Cut(err.Error(), "\n")
+	return line
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+func TestWriteGrep(t *testing.T) {
+	results := []zmx.GrepResult{
+		{Session: zmx.Session{Name: "api"}, Matches: []zmx.GrepMatch{
+			{Line: 1, Text: "error: a", Before: []string{"l0"}, After: []string{"error: b"}},
+			{Line: 2, Text: "error: b", Before: []string{"error: a"}, After: []string{"l3"}},
+			{Line: 9, Text: "error: c", Before: []string{"l8"}},
+		}},
+		{Session: zmx.Session{Name: "down"}, Err: errors.New("timed out")},
+		{Session: zmx.Session{Name: "web", Backend: "tmux"}, Matches: []zmx.GrepMatch{
+			{Line: 0, Text: "error: d", After: []string{"l1"}},
+		}},
+	}
+	var b strings.Builder
+	if err := writeGrep(&b, results, true); err != nil {
+		t.Fatalf("writeGrep error: %v", err)
+	}
+	want := strings.Join([]string{
+		"api-1-l0",
+		"api:2:error: a",
+		"api:3:error: b",
+		"api-4-l3",
+		"--",
+		"api-9-l8",
+		"api:10:error: c",
+		"--",
+		"tmux:web:1:error: d",
+		"tmux:web-2-l1",
+	}, "\n") + "\n"
+	if b.String() != want {
+		t.Fatalf("writeGrep =\n%s\nwant\n%s", b.String(), want)
+	}
+}
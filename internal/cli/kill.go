@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+func runKill(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("kill", stderr)
+	var opts killOptions
+	opts.register(fs)
+	src := registerSourceFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: zsm kill [flags] [pattern...]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Kills every session whose name matches any pattern and all given predicates.")
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+	patterns, code := parseArgs(fs, args)
+	if code >= 0 {
+		return code
+	}
+
+	f, err := newKillFilter(fs, patterns, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm kill: %v\n", err)
+		return 2
+	}
+
+	cfg, err := src.apply()
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm kill: %v\n", err)
+		return 1
+	}
+	grace := cfg.KillGrace
+	if opts.grace >= 0 {
+		grace = opts.grace
+	}
+	protection, err := loadProtection(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm kill: %v\n", err)
+		return 1
+	}
+	sessions, err := loadSessions(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm kill: %v\n", err)
+		return 1
+	}
+	zmx.SortSessions(sessions, zmx.SortByName, true)
+
+	targets, protected := killTargets(sessions, f, protection, stderr)
+	if len(targets) == 0 && protected == 0 {
+		fmt.Fprintln(stderr, "zsm kill: no matching sessions")
+		return 0
+	}
+	if opts.dryRun {
+		for _, s := range targets {
+			fmt.Fprintf(stdout, "would kill %s\n", s.Key())
+		}
+		return 0
+	}
+
+	workers := cfg.KillConcurrency
+	if opts.workers > 0 {
+		workers = opts.workers
+	}
+	status := 0
+	if failed := killAll(targets, grace, workers, stdout, stderr); failed > 0 {
+		fmt.Fprintf(stderr, "zsm kill: %d of %d session(s) not killed\n", failed, len(targets))
+		status = 1
+	}
+	// Protected sessions matched and survived, just like failed ones.
+	if protected > 0 {
+		fmt.Fprintf(stderr, "zsm kill: %d matching session(s) protected, not killed\n", protected)
+		status = 1
+	}
+	return status
+}
+
+// killTargets returns the sessions f matches, leaving out protected ones,
+// which it reports on stderr and counts. Protected sessions can only be
+// killed from the TUI, by typing their name.
+func killTargets(sessions []zmx.Session, f killFilter, protection *zmx.Protection, stderr io.Writer) (targets []zmx.Session, protected int) {
+	for _, s := range sessions {
+		if !f.match(s) {
+			continue
+		}
+		if protection.Protected(s) {
+			fmt.Fprintf(stderr, "zsm kill: skipping protected session %s\n", s.Key())
+			protected++
+			continue
+		}
+		targets = append(targets, s)
+	}
+	return targets, protected
+}
+
+// killAll kills sessions through a pool of workers, printing each one as
+// it finishes. Each session escalates from SIGINT through SIGTERM and the
+// backend's kill to SIGKILL, stopping at the first stage that removes it.
+// It returns the number of sessions that survived.
+func killAll(sessions []zmx.Session, grace time.Duration, workers int, stdout, stderr io.Writer) int {
+	var mu sync.Mutex
+	failed := 0
+	jobs := make(chan zmx.Session)
+	var wg sync.WaitGroup
+	for range min(max(workers, 1), len(sessions)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range jobs {
+				stage, err := zmx.KillGracefully(s, grace)
+				mu.Lock()
+				if err != nil {
+					fmt.Fprintf(stderr, "zsm kill: %v\n", strings.TrimSpace(err.Error()))
+					failed++
+				} else {
+					fmt.Fprintf(stdout, "killed %s (%s)\n", s.Key(), stage.Label(s))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, s := range sessions {
+		jobs <- s
+	}
+	close(jobs)
+	wg.Wait()
+	return failed
+}
+
+type killOptions struct {
+	regex     bool
+	clients   int
+	olderThan string
+	memOver   string
+	dryRun    bool
+	grace     time.Duration // -1 means use the config file
+	workers   int           // 0 means use the config file
+}
+
+func (o *killOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.regex, "regex", false, "treat patterns as regular expressions instead of globs")
+	fs.IntVar(&o.clients, "clients", 0, "only sessions with exactly this many attached clients")
+	fs.StringVar(&o.olderThan, "older-than", "", "only sessions running longer than this (e.g. 90m, 3d, 2w)")
+	fs.StringVar(&o.memOver, "mem-over", "", "only sessions using more memory than this (e.g. 512M, 2G)")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "print the matching sessions without killing them")
+	fs.DurationVar(&o.grace, "grace", -1, "wait this long after SIGINT and after SIGTERM before escalating, or 0 to skip the signals (default from kill_grace in the config file, 3s)")
+	fs.IntVar(&o.workers, "j", 0, "sessions killed at once (default from kill_concurrency in the config file, 4)")
+}
+
+// killFilter selects sessions by name pattern and resource predicates. A
+// session must match at least one pattern (if any) and every predicate set.
+type killFilter struct {
+	patterns  []*regexp.Regexp
+	clients   int // -1 = any
+	olderThan int // seconds; 0 = any
+	memOver   uint64
+}
+
+func newKillFilter(fs *flag.FlagSet, patterns []string, opts killOptions) (killFilter, error) {
+	f := killFilter{clients: -1}
+	set := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	for _, p := range patterns {
+		expr := zmx.GlobRegexp(p)
+		if opts.regex {
+			expr = p
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return f, fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	if set["clients"] {
+		if opts.clients < 0 {
+			return f, errors.New("-clients must be >= 0")
+		}
+		f.clients = opts.clients
+	}
+	if opts.olderThan != "" {
+		d, err := zmx.ParseDuration(opts.olderThan)
+		if err != nil {
+			return f, err
+		}
+		f.olderThan = int(d / time.Second)
+	}
+	if opts.memOver != "" {
+		b, err := zmx.ParseBytes(opts.memOver)
+		if err != nil {
+			return f, err
+		}
+		f.memOver = b
+	}
+	if len(f.patterns) == 0 && f.clients < 0 && f.olderThan == 0 && opts.memOver == "" {
+		return f, errors.New("refusing to kill every session; pass a pattern (e.g. '*') or a predicate")
+	}
+	return f, nil
+}
+
+func (f killFilter) match(s zmx.Session) bool {
+	if s.Degraded() {
+		return false
+	}
+	if len(f.patterns) > 0 {
+		matched := false
+		for _, re := range f.patterns {
+			if re.MatchString(s.Key()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.clients >= 0 && s.Clients != f.clients {
+		return false
+	}
+	if f.olderThan > 0 && s.Uptime <= f.olderThan {
+		return false
+	}
+	if f.memOver > 0 && s.Memory <= f.memOver {
+		return false
+	}
+	return true
+}
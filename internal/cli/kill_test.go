@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob, name string
+		want       bool
+	}{
+		{"tmp*", "tmp3", true},
+		{"tmp*", "my-tmp", false},
+		{"proj/*", "proj/api", true},
+		{"build-?", "build-1", true},
+		{"build-?", "build-12", false},
+		{"[ab]*", "api", true},
+		{"[!ab]*", "api", false},
+		{"a.b", "axb", false},
+	}
+	for _, tt := range tests {
+		f := parseKillFilter(t, []string{"-regex=false"}, tt.glob)
+		if got := f.match(zmx.Session{Name: tt.name}); got != tt.want {
+			t.Errorf("glob %q match %q = %v, want %v", tt.glob, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKillFilterPredicates(t *testing.T) {
+	idle := zmx.Session{Name: "idle", Clients: 0, Uptime: 4 * 86400, Memory: 3 << 30}
+	busy := zmx.Session{Name: "busy", Clients: 2, Uptime: 60, Memory: 1 << 20}
+
+	f := parseKillFilter(t, []string{"-clients=0", "-older-than=3d", "-mem-over=2G"})
+	if !f.match(idle) {
+		t.Errorf("idle session should match")
+	}
+	if f.match(busy) {
+		t.Errorf("busy session should not match")
+	}
+
+	f = parseKillFilter(t, []string{"-regex"}, "^b", "^x")
+	if !f.match(busy) || f.match(idle) {
+		t.Errorf("regex patterns matched incorrectly")
+	}
+}
+
+func TestKillFilterSkipsUnreachableHosts(t *testing.T) {
+	f := parseKillFilter(t, nil, "*")
+	if f.match(zmx.Session{Host: "build1", Error: "connection refused"}) {
+		t.Error("the placeholder of an unreachable host should not match")
+	}
+	if !matchesAny(nil, zmx.Session{Name: "api"}) || matchesAny(nil, zmx.Session{Host: "build1", Error: "timeout"}) {
+		t.Error("matchesAny should match every session but unreachable hosts")
+	}
+}
+
+func TestKillTargetsCountProtectedSessions(t *testing.T) {
+	protection, err := zmx.NewProtection([]string{"prod-*"}, filepath.Join(t.TempDir(), "protected"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := []zmx.Session{{Name: "prod-api"}, {Name: "prod-web"}, {Name: "tmp"}}
+	var stderr strings.Builder
+	targets, protected := killTargets(sessions, parseKillFilter(t, nil, "prod-*"), protection, &stderr)
+	if len(targets) != 0 || protected != 2 {
+		t.Fatalf("targets = %v, protected = %d; want none and 2", targets, protected)
+	}
+	if !strings.Contains(stderr.String(), "skipping protected session prod-api") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+}
+
+func TestKillFilterRequiresCriteria(t *testing.T) {
+	fs := flag.NewFlagSet("kill", flag.ContinueOnError)
+	if _, err := newKillFilter(fs, nil, killOptions{}); err == nil {
+		t.Fatal("expected an error when no pattern or predicate is given")
+	}
+}
+
+func parseKillFilter(t *testing.T, flags []string, patterns ...string) killFilter {
+	t.Helper()
+	fs := flag.NewFlagSet("kill", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var opts killOptions
+	opts.register(fs)
+	if err := fs.Parse(flags); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	f, err := newKillFilter(fs, patterns, opts)
+	if err != nil {
+		t.Fatalf("newKillFilter error: %v", err)
+	}
+	return f
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+var templateFuncs = template.FuncMap{
+	"bytes":  zmx.FormatBytes,
+	"uptime": zmx.FormatUptime,
+	"cpu":    zmx.FormatCPU,
+}
+
+func runList(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("list", stderr)
+	format := fs.String("format", "tsv", "output format: json, ndjson or tsv")
+	tmpl := fs.String("template", "", "Go text/template applied to each session (overrides -format)")
+	sortBy := fs.String("sort", "name", "sort mode: name, clients, pid, memory, uptime or cpu")
+	desc := fs.Bool("desc", false, "sort in descending order")
+	filter := fs.String("filter", "", "only show sessions matching this query, e.g. 'clients:0 mem:>1G' (same syntax as the TUI's / filter)")
+	noHeader := fs.Bool("no-header", false, "omit the header row in tsv output")
+	src := registerSourceFlags(fs)
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+
+	mode, err := zmx.ParseSortMode(*sortBy)
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm list: %v\n", err)
+		return 2
+	}
+	query, err := zmx.ParseQuery(*filter)
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm list: -filter: %v\n", err)
+		return 2
+	}
+	var t *template.Template
+	if *tmpl != "" {
+		t, err = template.New("list").Funcs(templateFuncs).Parse(*tmpl)
+		if err != nil {
+			fmt.Fprintf(stderr, "zsm list: %v\n", err)
+			return 2
+		}
+	} else if *format != "json" && *format != "ndjson" && *format != "tsv" {
+		fmt.Fprintf(stderr, "zsm list: unknown format %q\n", *format)
+		return 2
+	}
+
+	if _, err := src.apply(); err != nil {
+		fmt.Fprintf(stderr, "zsm list: %v\n", err)
+		return 1
+	}
+	sessions, err := loadSessions(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm list: %v\n", err)
+		return 1
+	}
+	sessions = zmx.FilterSessions(sessions, query)
+	zmx.SortSessions(sessions, mode, !*desc)
+
+	if t != nil {
+		err = writeTemplate(stdout, sessions, t)
+	} else {
+		err = writeSessions(stdout, sessions, *format, !*noHeader)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "zsm list: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeSessions renders sessions in one of the built-in formats.
+func writeSessions(w io.Writer, sessions []zmx.Session, format string, header bool) error {
+	if sessions == nil {
+		sessions = []zmx.Session{}
+	}
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sessions)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, s := range sessions {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "tsv":
+		if header {
+			if _, err := io.WriteString(w, "name\tpid\tclients\tmemory\tuptime\tstarted_in\tcmd\tbackend\thost\n"); err != nil {
+				return err
+			}
+		}
+		for _, s := range sessions {
+			fields := []string{
+				s.Name,
+				s.PID,
+				strconv.Itoa(s.Clients),
+				strconv.FormatUint(s.Memory, 10),
+				strconv.Itoa(s.Uptime),
+				s.StartedIn,
+				s.Cmd,
+				backendName(s),
+				s.Host,
+			}
+			for i, f := range fields {
+				fields[i] = tsvField(f)
+			}
+			if _, err := io.WriteString(w, strings.Join(fields, "\t")+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// writeTemplate executes t once per session, terminating each with a newline.
+func writeTemplate(w io.Writer, sessions []zmx.Session, t *template.Template) error {
+	for _, s := range sessions {
+		if err := t.Execute(w, s); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backendName returns the session's backend, defaulting to zmx.
+func backendName(s zmx.Session) string {
+	if s.Backend == "" {
+		return "zmx"
+	}
+	return s.Backend
+}
+
+// tsvField replaces characters that would break TSV row/column structure.
+func tsvField(s string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+var testSessions = []zmx.Session{
+	{Name: "api", PID: "10", Clients: 1, StartedIn: "/srv/api", Cmd: "go run .", Memory: 2 << 20, Uptime: 90},
+	{Name: "web", PID: "20", StartedIn: "/srv/web\tx", Backend: "tmux", Host: "build1"},
+}
+
+func TestWriteSessionsJSON(t *testing.T) {
+	var b strings.Builder
+	if err := writeSessions(&b, testSessions, "json", true); err != nil {
+		t.Fatalf("writeSessions error: %v", err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("invalid json %q: %v", b.String(), err)
+	}
+	if len(got) != 2 || got[0]["name"] != "api" || got[0]["memory"] != float64(2<<20) {
+		t.Fatalf("unexpected json: %v", got)
+	}
+}
+
+func TestWriteSessionsEmptyJSONIsArray(t *testing.T) {
+	var b strings.Builder
+	if err := writeSessions(&b, nil, "json", true); err != nil {
+		t.Fatalf("writeSessions error: %v", err)
+	}
+	if got := strings.TrimSpace(b.String()); got != "[]" {
+		t.Fatalf("empty json = %q, want []", got)
+	}
+}
+
+func TestWriteSessionsNDJSON(t *testing.T) {
+	var b strings.Builder
+	if err := writeSessions(&b, testSessions, "ndjson", true); err != nil {
+		t.Fatalf("writeSessions error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"name":"web"`) {
+		t.Fatalf("unexpected ndjson: %q", b.String())
+	}
+}
+
+func TestWriteSessionsTSV(t *testing.T) {
+	var b strings.Builder
+	if err := writeSessions(&b, testSessions, "tsv", true); err != nil {
+		t.Fatalf("writeSessions error: %v", err)
+	}
+	want := "name\tpid\tclients\tmemory\tuptime\tstarted_in\tcmd\tbackend\thost\n" +
+		"api\t10\t1\t2097152\t90\t/srv/api\tgo run .\tzmx\t\n" +
+		"web\t20\t0\t0\t0\t/srv/web x\t\ttmux\tbuild1\n"
+	if b.String() != want {
+		t.Fatalf("tsv =\n%q\nwant\n%q", b.String(), want)
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("t").Funcs(templateFuncs).Parse("{{.Name}} {{bytes .Memory}} {{uptime .Uptime}}"))
+	var b strings.Builder
+	if err := writeTemplate(&b, testSessions[:1], tmpl); err != nil {
+		t.Fatalf("writeTemplate error: %v", err)
+	}
+	if b.String() != "api 2M 1m\n" {
+		t.Fatalf("template output = %q", b.String())
+	}
+}
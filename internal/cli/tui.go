@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/mdsakalu/zmx-session-manager/internal/config"
+	"github.com/mdsakalu/zmx-session-manager/internal/tui"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+// Exit codes for picker mode, following fzf's conventions.
+const (
+	pickChosen    = 0
+	pickNoneFound = 1
+	pickCancelled = 130
+)
+
+// RunTUI starts the interactive session manager. Unless -print is given, it
+// replaces the process with the backend's attach command (e.g. `zmx attach`)
+// when the user picks a session.
+func RunTUI(args []string) int {
+	fs := newFlagSet("", os.Stderr)
+	var opts pickOptions
+	fs.BoolVar(&opts.print, "print", false, "print the chosen session name(s) instead of attaching (same as `zsm pick`)")
+	stay := fs.Bool("stay", false, "return to zsm after detaching instead of exiting")
+	opts.register(fs)
+	src := registerSourceFlags(fs)
+	fs.Usage = func() {
+		Usage(os.Stderr)
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Flags:")
+		fs.PrintDefaults()
+	}
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	cfg, err := src.apply()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	tuiOpts, err := opts.tuiOptions(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if opts.print {
+		return pick(tuiOpts, opts.null, os.Stdout, os.Stderr)
+	}
+
+	tuiOpts.Stay = *stay
+	p := tea.NewProgram(tui.NewModel(tuiOpts))
+	finalModel, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// If the user pressed Enter to attach, exec into the backend's attach
+	if m, ok := finalModel.(tui.Model); ok && m.AttachTarget() != "" {
+		argv := m.AttachArgs()
+		path, err := exec.LookPath(argv[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if dir := m.AttachDir(); dir != "" {
+			if err := os.Chdir(dir); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+		env := os.Environ()
+		err = syscall.Exec(path, argv, env)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+type pickOptions struct {
+	print   bool
+	null    bool
+	refresh time.Duration // -1 means use the config file
+}
+
+func (o *pickOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.null, "0", false, "separate printed names with NUL instead of newline")
+	fs.DurationVar(&o.refresh, "refresh", -1, "auto-refresh interval, e.g. 2s, or 0 to disable (default from refresh_interval in the config file, 5s)")
+}
+
+// tuiOptions builds the TUI options shared by the attach and pick modes.
+func (o *pickOptions) tuiOptions(cfg config.Config) (tui.Options, error) {
+	refresh := cfg.RefreshInterval
+	if o.refresh >= 0 {
+		if err := config.ValidateRefreshInterval(o.refresh); err != nil {
+			return tui.Options{}, fmt.Errorf("-refresh: %w", err)
+		}
+		refresh = o.refresh
+	}
+	protection, err := loadProtection(cfg)
+	if err != nil {
+		return tui.Options{}, err
+	}
+	meta, err := loadMeta()
+	if err != nil {
+		return tui.Options{}, err
+	}
+	sortMode, err := zmx.ParseSortMode(cfg.Sort)
+	if err != nil {
+		return tui.Options{}, err
+	}
+	return tui.Options{
+		Pick:            o.print,
+		CPUThreshold:    cfg.CPUThreshold,
+		RefreshInterval: refresh,
+		KillGrace:       cfg.KillGrace,
+		KillConcurrency: cfg.KillConcurrency,
+		GroupDelimiter:  cfg.GroupDelimiter,
+		Protection:      protection,
+		Meta:            meta,
+		SortMode:        sortMode,
+		SortDesc:        cfg.SortDesc,
+		Keys:            cfg.Keys,
+		Colors:          cfg.Colors,
+		Columns:         cfg.Columns,
+		LogHeight:       cfg.LogHeight,
+		FollowInterval:  cfg.FollowInterval,
+	}, nil
+}
+
+func runPick(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pick", stderr)
+	var opts pickOptions
+	opts.register(fs)
+	src := registerSourceFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: zsm pick [flags]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Runs the TUI on /dev/tty and prints the chosen session name(s) to stdout.")
+		fmt.Fprintln(stderr, "Exits 0 when sessions were chosen, 1 when there are no sessions and 130 when cancelled.")
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	cfg, err := src.apply()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	opts.print = true
+	tuiOpts, err := opts.tuiOptions(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	return pick(tuiOpts, opts.null, stdout, stderr)
+}
+
+// pick runs the TUI in picker mode on the controlling terminal so that
+// stdout carries only the chosen names.
+func pick(opts tui.Options, null bool, stdout, stderr io.Writer) int {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: opening terminal: %v\n", err)
+		return 2
+	}
+	defer tty.Close()
+
+	p := tea.NewProgram(tui.NewModel(opts), tea.WithInput(tty), tea.WithOutput(tty))
+	finalModel, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	m, ok := finalModel.(tui.Model)
+	if !ok {
+		return pickCancelled
+	}
+	if picked := m.Picked(); len(picked) > 0 {
+		sep := "\n"
+		if null {
+			sep = "\x00"
+		}
+		for _, name := range picked {
+			fmt.Fprint(stdout, name+sep)
+		}
+		return pickChosen
+	}
+	if m.SessionCount() == 0 {
+		return pickNoneFound
+	}
+	return pickCancelled
+}
@@ -0,0 +1,226 @@
+// Package config loads zsm's optional TOML configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config is the contents of config.toml. Every field is optional.
+type Config struct {
+	// Hosts are ssh destinations whose zmx sessions are listed alongside
+	// the local ones, e.g. "build1" or "me@build2.example.com".
+	Hosts []string `toml:"hosts"`
+
+	// CPUThreshold is the CPU usage, in percent of one core, above which
+	// the TUI highlights a session. Zero disables highlighting.
+	CPUThreshold float64 `toml:"cpu_threshold"`
+
+	// RefreshInterval is how often the TUI re-lists sessions in the
+	// background, e.g. "5s". Zero disables auto-refresh.
+	RefreshInterval time.Duration `toml:"refresh_interval"`
+
+	// KillGrace is how long a kill waits after sending SIGINT, and again
+	// after SIGTERM, before escalating to the backend's kill and finally
+	// SIGKILL, e.g. "3s". Zero skips the signals.
+	KillGrace time.Duration `toml:"kill_grace"`
+
+	// KillConcurrency is how many sessions are killed at once.
+	KillConcurrency int `toml:"kill_concurrency"`
+
+	// Protect are name globs, e.g. "prod-*", for sessions that zsm refuses
+	// to kill unless the user types the session's name.
+	Protect []string `toml:"protect"`
+
+	// GroupDelimiter splits session names when grouping by name prefix,
+	// e.g. "/" groups "proj/api" and "proj/web" under "proj".
+	GroupDelimiter string `toml:"group_delimiter"`
+
+	// Sort is the TUI's initial sort mode: name, clients, pid, memory,
+	// uptime or cpu. SortDesc starts it in descending order.
+	Sort     string `toml:"sort"`
+	SortDesc bool   `toml:"sort_desc"`
+
+	// Columns are the list columns shown after the session name, in
+	// order (see ColumnNames).
+	Columns []string `toml:"columns"`
+
+	// LogHeight is how many lines the activity log shows.
+	LogHeight int `toml:"log_height"`
+
+	// FollowInterval is how often a followed session's output is polled.
+	FollowInterval time.Duration `toml:"follow_interval"`
+
+	// Colors maps palette entries (see ColorRoles) to an ANSI color number
+	// or "#rrggbb". Entries missing from the file keep their default.
+	Colors map[string]string `toml:"colors"`
+
+	// Keys binds TUI actions (see KeyActions) to lists of keys. Actions
+	// missing from the file keep their default keys, minus any the file
+	// binds to other actions.
+	Keys map[string][]string `toml:"keys"`
+}
+
+// MinRefreshInterval is the shortest accepted non-zero RefreshInterval.
+const MinRefreshInterval = 500 * time.Millisecond
+
+// MinFollowInterval is the shortest accepted FollowInterval.
+const MinFollowInterval = 100 * time.Millisecond
+
+// MaxLogHeight is the tallest accepted LogHeight.
+const MaxLogHeight = 50
+
+// Default returns the configuration used for keys missing from config.toml.
+func Default() Config {
+	return Config{
+		CPUThreshold:    80,
+		RefreshInterval: 5 * time.Second,
+		KillGrace:       3 * time.Second,
+		KillConcurrency: 4,
+		GroupDelimiter:  "/",
+		Sort:            "name",
+		Columns:         slices.Clone(ColumnNames),
+		LogHeight:       4,
+		FollowInterval:  time.Second,
+		Colors:          defaultColors(),
+		Keys:            defaultKeys(),
+	}
+}
+
+// Dir returns zsm's config directory: $XDG_CONFIG_HOME/zsm, falling back
+// to ~/.config/zsm.
+func Dir() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, _ := os.UserHomeDir()
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "zsm")
+}
+
+// Path returns the location of config.toml.
+func Path() string {
+	return filepath.Join(Dir(), "config.toml")
+}
+
+// StateDir returns where zsm keeps what it records about sessions:
+// $XDG_STATE_HOME/zsm, falling back to ~/.local/state/zsm.
+func StateDir() string {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, _ := os.UserHomeDir()
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "zsm")
+}
+
+// ProtectedPath returns the file listing the sessions the user marked as
+// protected from the TUI.
+func ProtectedPath() string {
+	return filepath.Join(StateDir(), "protected")
+}
+
+// MetaPath returns the file holding session aliases, tags and notes.
+func MetaPath() string {
+	return filepath.Join(StateDir(), "meta.json")
+}
+
+// Load reads config.toml. A missing file yields the Default config.
+func Load() (Config, error) {
+	return LoadFile(Path())
+}
+
+// LoadFile reads and validates the config at path. Keys missing from the
+// file, or a missing file, take their Default values.
+func LoadFile(path string) (Config, error) {
+	cfg := Default()
+	md, err := toml.DecodeFile(path, &cfg)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			keys[i] = k.String()
+		}
+		return Config{}, fmt.Errorf("%s: unknown key(s): %s", path, strings.Join(keys, ", "))
+	}
+	var rebound []string
+	for action := range cfg.Keys {
+		if md.IsDefined("keys", action) {
+			rebound = append(rebound, action)
+		}
+	}
+	unbindOverridden(cfg.Keys, rebound)
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c Config) validate() error {
+	for _, h := range c.Hosts {
+		if strings.TrimSpace(h) == "" || strings.ContainsAny(h, " \t") {
+			return fmt.Errorf("hosts: invalid host %q", h)
+		}
+	}
+	if c.CPUThreshold < 0 {
+		return fmt.Errorf("cpu_threshold: must not be negative, got %g", c.CPUThreshold)
+	}
+	if err := ValidateRefreshInterval(c.RefreshInterval); err != nil {
+		return fmt.Errorf("refresh_interval: %w", err)
+	}
+	if c.KillGrace < 0 {
+		return fmt.Errorf("kill_grace: must not be negative, got %s", c.KillGrace)
+	}
+	if c.KillConcurrency < 1 {
+		return fmt.Errorf("kill_concurrency: must be at least 1, got %d", c.KillConcurrency)
+	}
+	if c.GroupDelimiter == "" {
+		return errors.New("group_delimiter: must not be empty")
+	}
+	for _, p := range c.Protect {
+		if strings.TrimSpace(p) == "" {
+			return errors.New("protect: empty pattern")
+		}
+	}
+	if !slices.Contains(sortModes, c.Sort) {
+		return fmt.Errorf("sort: unknown sort mode %q (want %s)", c.Sort, strings.Join(sortModes, ", "))
+	}
+	if err := validateColumns(c.Columns); err != nil {
+		return err
+	}
+	if c.LogHeight < 1 || c.LogHeight > MaxLogHeight {
+		return fmt.Errorf("log_height: must be between 1 and %d, got %d", MaxLogHeight, c.LogHeight)
+	}
+	if c.FollowInterval < MinFollowInterval {
+		return fmt.Errorf("follow_interval: must be at least %s, got %s", MinFollowInterval, c.FollowInterval)
+	}
+	if err := validateColors(c.Colors); err != nil {
+		return err
+	}
+	return validateKeys(c.Keys)
+}
+
+// ValidateRefreshInterval checks an auto-refresh interval from the config
+// file or the command line.
+func ValidateRefreshInterval(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("must not be negative, got %s", d)
+	}
+	if d > 0 && d < MinRefreshInterval {
+		return fmt.Errorf("must be 0 (off) or at least %s, got %s", MinRefreshInterval, d)
+	}
+	return nil
+}
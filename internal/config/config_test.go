@@ -0,0 +1,215 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileMissingIsEmpty(t *testing.T) {
+	cfg, err := LoadFile(filepath.Join(t.TempDir(), "nope.toml"))
+	if err != nil {
+		t.Fatalf("LoadFile error: %v", err)
+	}
+	if len(cfg.Hosts) != 0 {
+		t.Fatalf("expected zero config, got %+v", cfg)
+	}
+}
+
+func TestLoadFileHosts(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `hosts = ["build1", "me@build2"]`))
+	if err != nil {
+		t.Fatalf("LoadFile error: %v", err)
+	}
+	if len(cfg.Hosts) != 2 || cfg.Hosts[1] != "me@build2" {
+		t.Fatalf("unexpected hosts: %v", cfg.Hosts)
+	}
+}
+
+func TestLoadFileRejectsUnknownKeys(t *testing.T) {
+	_, err := LoadFile(writeConfig(t, `hostz = ["build1"]`))
+	if err == nil || !strings.Contains(err.Error(), "hostz") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestLoadFileCPUThreshold(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `hosts = ["build1"]`))
+	if err != nil {
+		t.Fatalf("LoadFile error: %v", err)
+	}
+	if cfg.CPUThreshold != Default().CPUThreshold {
+		t.Fatalf("CPUThreshold = %g, want default %g", cfg.CPUThreshold, Default().CPUThreshold)
+	}
+	cfg, err = LoadFile(writeConfig(t, `cpu_threshold = 150`))
+	if err != nil {
+		t.Fatalf("LoadFile error: %v", err)
+	}
+	if cfg.CPUThreshold != 150 {
+		t.Fatalf("CPUThreshold = %g, want 150", cfg.CPUThreshold)
+	}
+	if _, err := LoadFile(writeConfig(t, `cpu_threshold = -1`)); err == nil {
+		t.Fatal("expected negative cpu_threshold error")
+	}
+}
+
+func TestLoadFileRefreshInterval(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `refresh_interval = "2s"`))
+	if err != nil {
+		t.Fatalf("LoadFile error: %v", err)
+	}
+	if cfg.RefreshInterval != 2*time.Second {
+		t.Fatalf("RefreshInterval = %s, want 2s", cfg.RefreshInterval)
+	}
+	cfg, err = LoadFile(writeConfig(t, `refresh_interval = "0s"`))
+	if err != nil || cfg.RefreshInterval != 0 {
+		t.Fatalf("want auto-refresh disabled, got %s, %v", cfg.RefreshInterval, err)
+	}
+	for _, bad := range []string{`refresh_interval = "10ms"`, `refresh_interval = "-1s"`, `refresh_interval = "soon"`} {
+		if _, err := LoadFile(writeConfig(t, bad)); err == nil {
+			t.Errorf("LoadFile(%s) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestLoadFileRejectsBadHost(t *testing.T) {
+	if _, err := LoadFile(writeConfig(t, `hosts = ["build 1"]`)); err == nil {
+		t.Fatal("expected invalid host error")
+	}
+}
+
+func TestLoadFileKillGrace(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `kill_grace = "10s"`))
+	if err != nil || cfg.KillGrace != 10*time.Second {
+		t.Fatalf("KillGrace = %s, %v; want 10s", cfg.KillGrace, err)
+	}
+	if _, err := LoadFile(writeConfig(t, `kill_grace = "-1s"`)); err == nil {
+		t.Fatal("expected negative kill_grace error")
+	}
+}
+
+func TestLoadFileKillConcurrency(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `kill_concurrency = 8`))
+	if err != nil || cfg.KillConcurrency != 8 {
+		t.Fatalf("KillConcurrency = %d, %v; want 8", cfg.KillConcurrency, err)
+	}
+	if _, err := LoadFile(writeConfig(t, `kill_concurrency = 0`)); err == nil {
+		t.Fatal("expected kill_concurrency error")
+	}
+}
+
+func TestLoadFileProtect(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `protect = ["prod-*", "migrate"]`))
+	if err != nil || len(cfg.Protect) != 2 || cfg.Protect[0] != "prod-*" {
+		t.Fatalf("Protect = %v, %v", cfg.Protect, err)
+	}
+	if _, err := LoadFile(writeConfig(t, `protect = [""]`)); err == nil {
+		t.Fatal("expected empty pattern error")
+	}
+}
+
+func TestLoadFileGroupDelimiter(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `group_delimiter = "."`))
+	if err != nil || cfg.GroupDelimiter != "." {
+		t.Fatalf("GroupDelimiter = %q, %v; want .", cfg.GroupDelimiter, err)
+	}
+	if _, err := LoadFile(writeConfig(t, `group_delimiter = ""`)); err == nil {
+		t.Fatal("expected empty group_delimiter error")
+	}
+}
+
+func TestLoadFileDisplayDefaults(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `
+sort = "memory"
+sort_desc = true
+columns = ["clients", "memory"]
+log_height = 8
+follow_interval = "250ms"
+`))
+	if err != nil {
+		t.Fatalf("LoadFile error: %v", err)
+	}
+	if cfg.Sort != "memory" || !cfg.SortDesc || strings.Join(cfg.Columns, ",") != "clients,memory" ||
+		cfg.LogHeight != 8 || cfg.FollowInterval != 250*time.Millisecond {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	for bad, want := range map[string]string{
+		`sort = "size"`:                `sort: unknown sort mode "size"`,
+		`columns = ["pid", "name"]`:    `columns: unknown column "name"`,
+		`columns = ["pid", "pid"]`:     `columns: "pid" is listed twice`,
+		`log_height = 0`:               "log_height: must be between 1 and 50, got 0",
+		`follow_interval = "10ms"`:     "follow_interval: must be at least 100ms",
+		"[colors]\ntext = \"blue\"":    `colors.text: invalid color "blue"`,
+		"[colors]\ntext = \"300\"":     `colors.text: invalid color "300"`,
+		"[colors]\nbackground = \"0\"": `colors: unknown color "background"`,
+	} {
+		if _, err := LoadFile(writeConfig(t, bad)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got error %v, want %q", bad, err, want)
+		}
+	}
+	cfg, err = LoadFile(writeConfig(t, "[colors]\ntext = \"#1c1c1c\"\nselected = \"161\""))
+	if err != nil || cfg.Colors["text"] != "#1c1c1c" || cfg.Colors["selected"] != "161" || cfg.Colors["title"] != "99" {
+		t.Fatalf("colors should merge with the defaults, got %v, %v", cfg.Colors, err)
+	}
+}
+
+func TestLoadFileKeys(t *testing.T) {
+	// Colemak-style navigation: n and e take over from new and export.
+	cfg, err := LoadFile(writeConfig(t, `
+[keys]
+down = ["down", "n"]
+up = ["up", "e"]
+new = ["ctrl+n"]
+`))
+	if err != nil {
+		t.Fatalf("LoadFile error: %v", err)
+	}
+	if got := strings.Join(cfg.Keys["down"], ","); got != "down,n" {
+		t.Fatalf("down = %s", got)
+	}
+	if len(cfg.Keys["export"]) != 0 || cfg.Keys["new"][0] != "ctrl+n" || cfg.Keys["kill"][0] != "k" {
+		t.Fatalf("rebinding should only unbind the taken keys: %v", cfg.Keys)
+	}
+	for bad, want := range map[string]string{
+		"[keys]\nzoom = [\"z\"]":                 `keys: unknown action "zoom"`,
+		"[keys]\nkill = [\"x\"]\nsort = [\"x\"]": `keys: "x" is bound to both kill and sort`,
+		"[keys]\nkill = [\"\"]":                  `keys.kill: invalid key ""`,
+		"[keys]\nquit = []":                      "keys.quit: needs at least one key",
+	} {
+		if _, err := LoadFile(writeConfig(t, bad)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want %q", bad, err, want)
+		}
+	}
+}
+
+func TestWriteRoundTrips(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, Default()); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFile(writeConfig(t, b.String()))
+	if err != nil {
+		t.Fatalf("LoadFile(Write(Default())): %v\n%s", err, b.String())
+	}
+	if !reflect.DeepEqual(cfg, withEmptyLists(Default())) {
+		t.Fatalf("round trip changed the config:\n got %+v\nwant %+v", cfg, Default())
+	}
+}
+
+// withEmptyLists sets the nil lists that Write prints as [] to empty ones.
+func withEmptyLists(c Config) Config {
+	c.Hosts, c.Protect = []string{}, []string{}
+	return c
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// KeyActions are the TUI actions that can be bound in the keys table, in
+// the order the help bar lists them.
+var KeyActions = []string{
+	"scroll_left", "scroll_right", "up", "down", "select", "select_all", "attach",
+	"new", "host", "kill", "protect", "tags", "alias", "note", "copy", "export",
+	"send", "sort", "group", "refresh", "history", "follow", "grep", "filter",
+	"log_up", "log_down", "quit",
+}
+
+// defaultKeys returns the default bindings. Keys are named the way
+// Bubble Tea reports them: the character typed ("G", "/"), or a key name
+// such as "enter", "space" or "up", prefixed by modifiers as in "ctrl+a".
+func defaultKeys() map[string][]string {
+	return map[string][]string{
+		"scroll_left":  {"left"},
+		"scroll_right": {"right"},
+		"up":           {"up"},
+		"down":         {"down"},
+		"select":       {"space"},
+		"select_all":   {"ctrl+a"},
+		"attach":       {"enter"},
+		"new":          {"n"},
+		"host":         {"h"},
+		"kill":         {"k"},
+		"protect":      {"p"},
+		"tags":         {"t"},
+		"alias":        {"a"},
+		"note":         {"N"},
+		"copy":         {"c"},
+		"export":       {"e"},
+		"send":         {"i"},
+		"sort":         {"s"},
+		"group":        {"G"},
+		"refresh":      {"r"},
+		"history":      {"v"},
+		"follow":       {"f"},
+		"grep":         {"g"},
+		"filter":       {"/"},
+		"log_up":       {"["},
+		"log_down":     {"]"},
+		"quit":         {"q", "ctrl+c"},
+	}
+}
+
+// ColorRoles are the entries of the colors table.
+var ColorRoles = append([]string{
+	"text", "selected", "title", "border", "help", "help_key", "status",
+	"error", "dim", "dir", "match", "sort", "active", "inactive", "pid",
+	"memory", "cpu", "cpu_hot", "uptime", "lock", "note", "tag_text",
+}, TagChipRoles...)
+
+// TagChipRoles are the colors entries tag chips are drawn on; each tag
+// always gets the same one.
+var TagChipRoles = []string{
+	"tag_bg_1", "tag_bg_2", "tag_bg_3", "tag_bg_4", "tag_bg_5",
+	"tag_bg_6", "tag_bg_7", "tag_bg_8", "tag_bg_9", "tag_bg_10",
+}
+
+// defaultColors returns the default palette, tuned for dark terminals.
+func defaultColors() map[string]string {
+	return map[string]string{
+		"text":      "252",
+		"selected":  "212",
+		"title":     "99",
+		"border":    "240",
+		"help":      "241",
+		"help_key":  "252",
+		"status":    "76",
+		"error":     "196",
+		"dim":       "241",
+		"dir":       "245",
+		"match":     "228",
+		"sort":      "75",
+		"active":    "76",
+		"inactive":  "240",
+		"pid":       "245",
+		"memory":    "180",
+		"cpu":       "151",
+		"cpu_hot":   "209",
+		"uptime":    "109",
+		"lock":      "214",
+		"note":      "180",
+		"tag_text":  "255",
+		"tag_bg_1":  "24",
+		"tag_bg_2":  "29",
+		"tag_bg_3":  "53",
+		"tag_bg_4":  "58",
+		"tag_bg_5":  "60",
+		"tag_bg_6":  "66",
+		"tag_bg_7":  "94",
+		"tag_bg_8":  "95",
+		"tag_bg_9":  "130",
+		"tag_bg_10": "133",
+	}
+}
+
+// ColumnNames are the list columns that can be shown after the session
+// name. The host column only appears when remote hosts are configured.
+var ColumnNames = []string{"pid", "memory", "cpu", "uptime", "clients", "host"}
+
+// sortModes are the names zmx.ParseSortMode accepts.
+var sortModes = []string{"name", "clients", "pid", "memory", "uptime", "cpu"}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func validateColor(c string) error {
+	if hexColor.MatchString(c) {
+		return nil
+	}
+	if n, err := strconv.Atoi(c); err == nil && n >= 0 && n <= 255 {
+		return nil
+	}
+	return fmt.Errorf("invalid color %q (want an ANSI color number from 0 to 255 or #rrggbb)", c)
+}
+
+func validateColors(colors map[string]string) error {
+	for _, role := range sortedKeys(colors) {
+		if !slices.Contains(ColorRoles, role) {
+			return fmt.Errorf("colors: unknown color %q (want one of %s)", role, strings.Join(ColorRoles, ", "))
+		}
+		if err := validateColor(colors[role]); err != nil {
+			return fmt.Errorf("colors.%s: %w", role, err)
+		}
+	}
+	return nil
+}
+
+func validateColumns(columns []string) error {
+	for i, c := range columns {
+		if !slices.Contains(ColumnNames, c) {
+			return fmt.Errorf("columns: unknown column %q (want %s)", c, strings.Join(ColumnNames, ", "))
+		}
+		if slices.Contains(columns[:i], c) {
+			return fmt.Errorf("columns: %q is listed twice", c)
+		}
+	}
+	return nil
+}
+
+func validateKeys(keys map[string][]string) error {
+	boundTo := make(map[string]string)
+	for _, action := range sortedKeys(keys) {
+		if !slices.Contains(KeyActions, action) {
+			return fmt.Errorf("keys: unknown action %q", action)
+		}
+		for _, key := range keys[action] {
+			if key == "" || strings.ContainsAny(key, " \t") {
+				return fmt.Errorf("keys.%s: invalid key %q", action, key)
+			}
+			if other, ok := boundTo[key]; ok && other != action {
+				return fmt.Errorf("keys: %q is bound to both %s and %s", key, other, action)
+			}
+			boundTo[key] = action
+		}
+	}
+	if len(keys["quit"]) == 0 {
+		return fmt.Errorf("keys.quit: needs at least one key")
+	}
+	return nil
+}
+
+// unbindOverridden removes the keys bound by actions in user from the
+// default bindings of the other actions, so that rebinding a key does not
+// also require unbinding it from its default action.
+func unbindOverridden(keys map[string][]string, user []string) {
+	for _, action := range user {
+		for other, bound := range keys {
+			if slices.Contains(user, other) {
+				continue
+			}
+			keys[other] = slices.DeleteFunc(bound, func(key string) bool {
+				return slices.Contains(keys[action], key)
+			})
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Write writes c to w as a commented config.toml, which LoadFile reads
+// back as c.
+func Write(w io.Writer, c Config) error {
+	var b strings.Builder
+	entry := func(comment, key string, value any) {
+		fmt.Fprintf(&b, "\n# %s\n%s = %s\n", comment, key, tomlValue(value))
+	}
+	b.WriteString("# zsm configuration. Every key is optional.\n")
+	entry("ssh destinations whose zmx sessions are listed alongside the local ones.", "hosts", nonNil(c.Hosts))
+	entry("Highlight sessions using more than this percentage of a core; 0 disables.", "cpu_threshold", c.CPUThreshold)
+	entry("How often the TUI re-lists sessions; \"0s\" disables auto-refresh.", "refresh_interval", c.RefreshInterval)
+	entry("How often a followed session's output is polled.", "follow_interval", c.FollowInterval)
+	entry("Wait after SIGINT, and again after SIGTERM, before escalating a kill.", "kill_grace", c.KillGrace)
+	entry("Sessions killed at once.", "kill_concurrency", c.KillConcurrency)
+	entry("Name globs of sessions that are only killed after typing their name.", "protect", nonNil(c.Protect))
+	entry("Splits names when grouping by prefix.", "group_delimiter", c.GroupDelimiter)
+	entry("Initial sort: "+strings.Join(sortModes, ", ")+".", "sort", c.Sort)
+	entry("Start sorted in descending order.", "sort_desc", c.SortDesc)
+	entry("List columns after the name, in order: "+strings.Join(ColumnNames, ", ")+".", "columns", nonNil(c.Columns))
+	entry("Lines of the activity log.", "log_height", c.LogHeight)
+
+	b.WriteString("\n# ANSI color numbers (0-255) or \"#rrggbb\". Tag chips are drawn in tag_text\n")
+	b.WriteString("# on one of the tag_bg_* backgrounds.\n[colors]\n")
+	for _, role := range ColorRoles {
+		fmt.Fprintf(&b, "%s = %s\n", role, tomlValue(c.Colors[role]))
+	}
+
+	b.WriteString("\n# Keys for each action of the session list, e.g. \"x\", \"G\", \"enter\", \"space\",\n")
+	b.WriteString("# \"up\" or \"ctrl+a\". The help bar shows the first. Binding a key here\n")
+	b.WriteString("# removes it from the default keys of other actions. History and grep\n")
+	b.WriteString("# results use quit, follow, filter and grep too; their arrows, n/N, esc and\n")
+	b.WriteString("# the y/n of the kill prompt are fixed.\n[keys]\n")
+	for _, action := range KeyActions {
+		fmt.Fprintf(&b, "%s = %s\n", action, tomlValue(nonNil(c.Keys[action])))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tomlValue formats v as a TOML value.
+func tomlValue(v any) string {
+	var b bytes.Buffer
+	_ = toml.NewEncoder(&b).Encode(map[string]any{"v": v}) // cannot fail for the types in Config
+	return strings.TrimSpace(strings.TrimPrefix(b.String(), "v = "))
+}
+
+// nonNil makes nil slices encode as [] rather than be left out.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -1,12 +1,105 @@
 package tui
 
-import tea "charm.land/bubbletea/v2"
+import (
+	"strings"
 
-func isQuit(msg tea.KeyPressMsg) bool {
-	if msg.Code == 'c' && msg.Mod.Contains(tea.ModCtrl) {
-		return true
+	tea "charm.land/bubbletea/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Actions of the session list that can be rebound (see config.KeyActions).
+const (
+	actScrollLeft  = "scroll_left"
+	actScrollRight = "scroll_right"
+	actUp          = "up"
+	actDown        = "down"
+	actSelect      = "select"
+	actSelectAll   = "select_all"
+	actAttach      = "attach"
+	actNew         = "new"
+	actHost        = "host"
+	actKill        = "kill"
+	actProtect     = "protect"
+	actTags        = "tags"
+	actAlias       = "alias"
+	actNote        = "note"
+	actCopy        = "copy"
+	actExport      = "export"
+	actSend        = "send"
+	actSort        = "sort"
+	actGroup       = "group"
+	actRefresh     = "refresh"
+	actHistory     = "history"
+	actFollow      = "follow"
+	actGrep        = "grep"
+	actFilter      = "filter"
+	actLogUp       = "log_up"
+	actLogDown     = "log_down"
+	actQuit        = "quit"
+)
+
+// keymap binds keys, named as tea.KeyPressMsg.String reports them, to
+// actions.
+type keymap struct {
+	actions  map[string]string   // key → action
+	bindings map[string][]string // action → keys
+}
+
+func newKeymap(bindings map[string][]string) keymap {
+	k := keymap{actions: make(map[string]string), bindings: bindings}
+	for action, keys := range bindings {
+		for _, key := range keys {
+			k.actions[key] = action
+		}
+	}
+	return k
+}
+
+// action returns the action msg is bound to, or "".
+func (k keymap) action(msg tea.KeyPressMsg) string {
+	return k.actions[msg.String()]
+}
+
+func (k keymap) is(msg tea.KeyPressMsg, action string) bool {
+	return k.action(msg) == action
+}
+
+// help returns how the help bar shows the keys of actions: the first key
+// of each, run together when they are single characters such as "↑↓" and
+// otherwise separated by "/". It returns "" if any of them is unbound.
+func (k keymap) help(actions ...string) string {
+	labels := make([]string, len(actions))
+	short := true
+	for i, action := range actions {
+		keys := k.bindings[action]
+		if len(keys) == 0 {
+			return ""
+		}
+		labels[i] = keyLabel(keys[0])
+		short = short && runewidth.StringWidth(labels[i]) == 1
+	}
+	if short {
+		return strings.Join(labels, "")
+	}
+	return strings.Join(labels, "/")
+}
+
+// keyLabel abbreviates a key name for the help bar.
+func keyLabel(key string) string {
+	switch key {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	}
+	if rest, ok := strings.CutPrefix(key, "ctrl+"); ok {
+		return "^" + rest
 	}
-	return msg.Text == "q"
+	return key
 }
 
 func isRune(msg tea.KeyPressMsg, r string) bool {
@@ -3,19 +3,21 @@ package tui
 import (
 	"cmp"
 	"fmt"
+	"maps"
 	"slices"
-	"strconv"
 	"strings"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/mattn/go-runewidth"
+	"github.com/mdsakalu/zmx-session-manager/internal/config"
 	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
 )
 
 const (
 	listMaxOuterWidth = 56
-	logContentHeight  = 4
+	minNameWidth      = 10
+	lockWidth         = 3 // " 🔒" after a protected session's name
 )
 
 type state int
@@ -25,35 +27,26 @@ const (
 	stateConfirmKill
 	stateKilling
 	stateFilter
+	stateNewSession
+	stateHistory
+	stateGrep
+	stateExport
+	stateSend
+	stateEditMeta
 )
 
-type sortMode int
+type sortMode = zmx.SortMode
 
 const (
-	sortByName sortMode = iota
-	sortByClients
-	sortByPID
-	sortByMemory
-	sortByUptime
-	sortModeCount
+	sortByName    = zmx.SortByName
+	sortByClients = zmx.SortByClients
+	sortByPID     = zmx.SortByPID
+	sortByMemory  = zmx.SortByMemory
+	sortByUptime  = zmx.SortByUptime
+	sortByCPU     = zmx.SortByCPU
+	sortModeCount = zmx.SortModeCount
 )
 
-func (s sortMode) label() string {
-	switch s {
-	case sortByName:
-		return "name"
-	case sortByClients:
-		return "clients"
-	case sortByPID:
-		return "pid"
-	case sortByMemory:
-		return "memory"
-	case sortByUptime:
-		return "uptime"
-	}
-	return ""
-}
-
 // Messages
 
 type sessionsMsg struct {
@@ -64,27 +57,25 @@ type sessionsMsg struct {
 type Session = zmx.Session
 
 type previewMsg struct {
-	name    string
-	content string
+	name          string
+	content       string
+	width, height int  // pane size the preview was rendered for
+	cached        bool // served from the preview cache
 }
 
 type statusClearMsg struct{}
 
-type killOneResultMsg struct {
-	name string
-	err  error
-}
-
-type waitCheckMsg struct {
-	names   []string
-	attempt int
-}
-
 type processInfoMsg struct {
 	info map[string]zmx.ProcessInfo
 }
 
-type allGoneMsg struct{}
+type refreshTickMsg struct{}
+
+type attachDoneMsg struct {
+	name    string
+	started time.Time
+	err     error
+}
 
 // Commands
 
@@ -99,40 +90,17 @@ func fetchProcessInfoCmd(sessions []Session) tea.Cmd {
 	}
 }
 
-func fetchPreviewCmd(name string, lines int) tea.Cmd {
-	return func() tea.Msg {
-		return previewMsg{name: name, content: zmx.FetchPreview(name, lines)}
-	}
-}
-
-func killOneCmd(name string) tea.Cmd {
-	return func() tea.Msg {
-		err := zmx.KillSession(name)
-		return killOneResultMsg{name: name, err: err}
-	}
+func attachCmd(s Session, dir string, command []string) tea.Cmd {
+	started := time.Now()
+	return tea.ExecProcess(zmx.AttachCommand(s, dir, command), func(err error) tea.Msg {
+		return attachDoneMsg{name: s.Key(), started: started, err: err}
+	})
 }
 
-func waitForGoneCmd(names []string, attempt int) tea.Cmd {
-	return func() tea.Msg {
-		if attempt >= 20 {
-			return allGoneMsg{}
-		}
-		time.Sleep(200 * time.Millisecond)
-		sessions, err := zmx.FetchSessions()
-		if err != nil {
-			return allGoneMsg{}
-		}
-		alive := make(map[string]bool, len(sessions))
-		for _, s := range sessions {
-			alive[s.Name] = true
-		}
-		for _, name := range names {
-			if alive[name] {
-				return waitCheckMsg{names: names, attempt: attempt + 1}
-			}
-		}
-		return allGoneMsg{}
-	}
+func refreshTickCmd(d time.Duration) tea.Cmd {
+	return tea.Tick(d, func(time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
 }
 
 func clearStatusAfter(d time.Duration) tea.Cmd {
@@ -150,29 +118,81 @@ type Model struct {
 	selected   map[string]bool
 
 	filterText   string
+	filterQuery  *zmx.Query       // last query parsed from filterText without error
+	filterErr    error            // why filterText does not parse
+	fuzzy        bool             // match filterText fuzzily and rank by score
+	fuzzyMatches map[string][]int // session key → matched rune offsets
+	hostFilter   int              // 0 = all hosts, 1 = local, n = zmx.RemoteHosts()[n-2]
 	sortMode     sortMode
 	sortAsc      bool
-	attachTarget string // non-empty → exec zmx attach after quit
+	attachTarget *Session // non-nil → exec attach after quit
+	attachDir    string   // start directory when attaching creates a session
+	attachCmd    []string
+
+	stay bool // attach as a child process and resume afterwards
+
+	grouper   zmx.Grouper
+	collapsed map[string]bool // keys of collapsed groups
+
+	cpuSampler   zmx.CPUSampler
+	cpuThreshold float64 // highlight sessions above this CPU%; 0 disables
+
+	refreshInterval time.Duration // background refresh period; 0 disables
+	refreshing      bool          // a background refresh is in flight
+
+	// Picker mode: Enter records the chosen names instead of attaching
+	pickMode bool
+	picked   []string
+	loaded   bool
 
 	preview        string
+	previewKey     string // session the preview shows
+	previews       *previewCache
 	previewScrollX int
 	state          state
 	status         string
 
+	follow         bool // re-poll the previewed session every followInterval
+	followInterval time.Duration
+	followSeq      int
+	followNew      int // new output since the user last saw the bottom
+
+	form          newSessionForm
+	history       historyView
+	grep          grepView
+	exportForm    exportForm
+	send          sendPrompt
+	metaEdit      metaEditor
+	pendingCursor string // session to move the cursor to on the next refresh
+
 	// Kill tracking
-	killQueue     []string
-	killNow       string
-	killDoneNames []string
+	killGrace       time.Duration // wait after each signal before escalating
+	killConcurrency int           // sessions killed at once
+	killQueue       []Session
+	killActive      map[string]zmx.KillStage // sessions being killed → current stage
+	killTotal       int
+	killDoneNames   []string
+	killSurvived    []string
+	killUntouched   []string // left in the queue when the kill was cancelled
+	killConfirm     string   // name typed to confirm killing a protected session
+
+	protection *zmx.Protection
+	meta       *zmx.MetaStore
+
+	keys    keymap
+	columns []string // list columns after the name (see config.ColumnNames)
 
 	// Activity log
 	logLines  []string
 	logOffset int
+	logHeight int
 
 	width  int
 	height int
 	err    error
 
 	visibleCache      []Session
+	visibleRows       []listRow // visibleCache with group headers; the cursor indexes these
 	visibleCacheDirty bool
 	visibleMetrics    listMetrics
 	allMetrics        listMetrics
@@ -183,34 +203,177 @@ type listMetrics struct {
 	nameW   int
 	pidW    int
 	memW    int
+	cpuW    int
 	uptimeW int
 	clientW int
+	hostW   int
+}
+
+// columnWidth returns the width of one of config.ColumnNames.
+func (lm listMetrics) columnWidth(column string) int {
+	switch column {
+	case "pid":
+		return lm.pidW
+	case "memory":
+		return lm.memW
+	case "cpu":
+		return lm.cpuW
+	case "uptime":
+		return lm.uptimeW
+	case "clients":
+		return lm.clientW
+	case "host":
+		return lm.hostW
+	}
+	return 0
+}
+
+// columnsWidth returns the width of columns, each preceded by a space.
+func (lm listMetrics) columnsWidth(columns []string) int {
+	w := 0
+	for _, c := range columns {
+		w += 1 + lm.columnWidth(c)
+	}
+	return w
 }
 
 func initialModel() Model {
+	defaults := config.Default()
 	return Model{
 		selected:          make(map[string]bool),
+		collapsed:         make(map[string]bool),
+		previews:          newPreviewCache(),
 		sortAsc:           true,
+		keys:              newKeymap(defaults.Keys),
+		columns:           defaults.Columns,
+		logHeight:         defaults.LogHeight,
+		followInterval:    defaults.FollowInterval,
 		visibleCacheDirty: true,
 		allMetricsDirty:   true,
 	}
 }
 
-func NewModel() Model {
-	return initialModel()
+// Options configures a Model created with NewModel.
+type Options struct {
+	// Pick makes Enter record the chosen session names (see Picked) and
+	// quit, instead of requesting an attach.
+	Pick bool
+	// Stay runs `zmx attach` as a child process and returns to the list
+	// when the user detaches, instead of quitting.
+	Stay bool
+	// CPUThreshold highlights sessions using more than this percentage of
+	// a core. Zero disables highlighting.
+	CPUThreshold float64
+	// RefreshInterval re-lists sessions, process info and the preview in
+	// the background this often. Zero disables auto-refresh.
+	RefreshInterval time.Duration
+	// KillGrace is how long a kill waits after signalling a session's
+	// processes before escalating. Zero skips straight to the backend's
+	// kill.
+	KillGrace time.Duration
+	// KillConcurrency is how many sessions are killed at once; values
+	// below 1 mean one at a time.
+	KillConcurrency int
+	// GroupDelimiter splits session names for grouping by name prefix.
+	GroupDelimiter string
+	// Protection marks sessions that are left out of kills. The user can
+	// only kill one by typing its name, and toggles marks with p. Nil
+	// keeps marks in memory only.
+	Protection *zmx.Protection
+	// Meta stores the aliases, tags and notes the user records. Nil keeps
+	// them in memory only.
+	Meta *zmx.MetaStore
+
+	// SortMode and SortDesc set the initial order of the list.
+	SortMode zmx.SortMode
+	SortDesc bool
+	// Keys binds actions (see config.KeyActions) to keys, Colors overrides
+	// entries of the palette (see config.ColorRoles) and Columns sets the
+	// list columns after the name. Nil uses the defaults of
+	// config.Default, as do LogHeight and FollowInterval when zero.
+	Keys           map[string][]string
+	Colors         map[string]string
+	Columns        []string
+	LogHeight      int
+	FollowInterval time.Duration
 }
 
+func NewModel(opts Options) Model {
+	m := initialModel()
+	m.pickMode = opts.Pick
+	m.stay = opts.Stay
+	m.cpuThreshold = opts.CPUThreshold
+	m.refreshInterval = opts.RefreshInterval
+	m.killGrace = opts.KillGrace
+	m.killConcurrency = opts.KillConcurrency
+	m.protection = opts.Protection
+	m.grouper.Delimiter = opts.GroupDelimiter
+	if m.protection == nil {
+		m.protection, _ = zmx.NewProtection(nil, "")
+	}
+	m.meta = opts.Meta
+	if m.meta == nil {
+		m.meta, _ = zmx.LoadMetaStore("")
+	}
+	m.sortMode = opts.SortMode
+	m.sortAsc = !opts.SortDesc
+
+	if opts.Keys != nil {
+		m.keys = newKeymap(opts.Keys)
+	}
+	if opts.Columns != nil {
+		m.columns = opts.Columns
+	}
+	m.logHeight = cmp.Or(opts.LogHeight, m.logHeight)
+	m.followInterval = cmp.Or(opts.FollowInterval, m.followInterval)
+	colors := config.Default().Colors
+	maps.Copy(colors, opts.Colors)
+	applyColors(colors)
+	return m
+}
+
+// AttachTarget returns the key (see zmx.Session.Key) of the session to
+// attach to after quitting, or "" if none was chosen.
 func (m Model) AttachTarget() string {
-	return m.attachTarget
+	if m.attachTarget == nil {
+		return ""
+	}
+	return m.attachTarget.Key()
+}
+
+// AttachDir returns the directory to run the attach from, set when the user
+// created a new session from the form.
+func (m Model) AttachDir() string {
+	return m.attachDir
+}
+
+// AttachArgs returns the argv to exec for AttachTarget, including the
+// command to start when the user created a new session.
+func (m Model) AttachArgs() []string {
+	if m.attachTarget == nil {
+		return nil
+	}
+	return zmx.AttachArgs(*m.attachTarget, m.attachCmd)
 }
 
-// visibleSessions returns sessions matching the current filter, sorted by sortMode.
+// Picked returns the session names chosen in picker mode, in list order.
+func (m Model) Picked() []string {
+	return m.picked
+}
+
+// SessionCount returns how many sessions were listed when the model quit.
+func (m Model) SessionCount() int {
+	return len(m.sessions)
+}
+
+// visibleSessions returns sessions matching the current filter, sorted by
+// sortMode within their groups.
 func (m *Model) visibleSessions() []Session {
 	if !m.visibleCacheDirty {
 		return m.visibleCache
 	}
-	m.visibleCache = m.computeVisibleSessions()
-	m.visibleMetrics = computeListMetrics(m.visibleCache)
+	m.visibleCache, m.visibleRows = m.groupRows(m.computeVisibleSessions())
+	m.visibleMetrics = computeListMetrics(m.visibleCache, m.listName)
 	m.visibleCacheDirty = false
 	return m.visibleCache
 }
@@ -218,6 +381,8 @@ func (m *Model) visibleSessions() []Session {
 func (m *Model) markSessionsChanged() {
 	m.visibleCacheDirty = true
 	m.allMetricsDirty = true
+	// Directories may have become (or stopped being) repositories.
+	m.grouper = zmx.Grouper{Mode: m.grouper.Mode, Delimiter: m.grouper.Delimiter}
 }
 
 func (m *Model) markVisibleChanged() {
@@ -226,7 +391,7 @@ func (m *Model) markVisibleChanged() {
 
 func (m *Model) allSessionMetrics() listMetrics {
 	if m.allMetricsDirty {
-		m.allMetrics = computeListMetrics(m.sessions)
+		m.allMetrics = computeListMetrics(m.sessions, m.listName)
 		m.allMetricsDirty = false
 	}
 	return m.allMetrics
@@ -234,72 +399,85 @@ func (m *Model) allSessionMetrics() listMetrics {
 
 func (m *Model) computeVisibleSessions() []Session {
 	var filtered []Session
-	if m.filterText == "" {
-		filtered = make([]Session, len(m.sessions))
-		copy(filtered, m.sessions)
+	m.fuzzyMatches = nil
+	if m.fuzzyActive() {
+		m.fuzzyMatches = make(map[string][]int)
+		for _, r := range zmx.FuzzyFilter(m.sessions, m.filterText) {
+			filtered = append(filtered, r.Session)
+			m.fuzzyMatches[r.Session.Key()] = r.Positions
+		}
 	} else {
-		lower := strings.ToLower(m.filterText)
-		for _, s := range m.sessions {
-			if strings.Contains(strings.ToLower(s.Name), lower) ||
-				strings.Contains(strings.ToLower(s.StartedIn), lower) {
-				filtered = append(filtered, s)
-			}
+		filtered = zmx.FilterSessions(m.sessions, m.filterQuery)
+	}
+	if m.hostFilter > 0 {
+		host := ""
+		if m.hostFilter > 1 {
+			host = zmx.RemoteHosts()[m.hostFilter-2]
 		}
+		filtered = slices.DeleteFunc(filtered, func(s Session) bool {
+			return s.Host != host
+		})
 	}
+	if !m.fuzzyActive() {
+		zmx.SortSessions(filtered, m.sortMode, m.sortAsc)
+	}
+	return filtered
+}
+
+// fuzzyActive reports whether the list is ranked by fuzzy match score
+// rather than sorted by sortMode.
+func (m *Model) fuzzyActive() bool {
+	return m.fuzzy && strings.TrimSpace(m.filterText) != ""
+}
 
-	dir := 1
-	if !m.sortAsc {
-		dir = -1
+// setFilterText sets the filter query. While the text does not parse, as
+// when a term is half typed, the list keeps showing the last valid query's
+// matches and the help line shows the error.
+func (m *Model) setFilterText(text string) {
+	m.filterText = text
+	m.markVisibleChanged()
+	if m.fuzzy {
+		m.filterErr = nil
+		return
 	}
-	switch m.sortMode {
-	case sortByName:
-		slices.SortFunc(filtered, func(a, b Session) int {
-			return dir * cmp.Compare(a.Name, b.Name)
-		})
-	case sortByClients:
-		slices.SortFunc(filtered, func(a, b Session) int {
-			if a.Clients != b.Clients {
-				return dir * (a.Clients - b.Clients)
-			}
-			return cmp.Compare(a.Name, b.Name)
-		})
-	case sortByPID:
-		slices.SortFunc(filtered, func(a, b Session) int {
-			ai, _ := strconv.Atoi(a.PID)
-			bi, _ := strconv.Atoi(b.PID)
-			if ai != bi {
-				return dir * (ai - bi)
-			}
-			return cmp.Compare(a.Name, b.Name)
-		})
-	case sortByMemory:
-		slices.SortFunc(filtered, func(a, b Session) int {
-			if a.Memory != b.Memory {
-				return dir * cmp.Compare(a.Memory, b.Memory)
-			}
-			return cmp.Compare(a.Name, b.Name)
-		})
-	case sortByUptime:
-		slices.SortFunc(filtered, func(a, b Session) int {
-			if a.Uptime != b.Uptime {
-				return dir * (a.Uptime - b.Uptime)
-			}
-			return cmp.Compare(a.Name, b.Name)
-		})
+	q, err := zmx.ParseQuery(text)
+	m.filterErr = err
+	if err == nil {
+		m.filterQuery = q
 	}
+}
 
-	return filtered
+// cycleHostFilter steps the host filter through all hosts, the local
+// machine, then each remote host.
+func (m *Model) cycleHostFilter() {
+	m.hostFilter = (m.hostFilter + 1) % (len(zmx.RemoteHosts()) + 2)
+	m.markVisibleChanged()
+	m.cursor = 0
+	m.listOffset = 0
+}
+
+// hostFilterLabel names the active host filter, or "" when showing all hosts.
+func (m *Model) hostFilterLabel() string {
+	switch {
+	case m.hostFilter == 0:
+		return ""
+	case m.hostFilter == 1:
+		return "local"
+	}
+	return zmx.RemoteHosts()[m.hostFilter-2]
 }
 
-func computeListMetrics(sessions []Session) listMetrics {
+func computeListMetrics(sessions []Session, name func(Session) string) listMetrics {
 	metrics := listMetrics{
 		pidW:    1,
 		memW:    1,
+		cpuW:    1,
 		uptimeW: 1,
 		clientW: 2,
+		hostW:   runewidth.StringWidth(localHostLabel),
 	}
 	for _, s := range sessions {
-		if w := runewidth.StringWidth(s.Name); w > metrics.nameW {
+		if w := runewidth.StringWidth(name(s)) + tagChipsWidth(s.Tags); w > metrics.nameW {
 			metrics.nameW = w
 		}
 		if w := runewidth.StringWidth(s.PID); w > metrics.pidW {
@@ -312,6 +490,13 @@ func computeListMetrics(sessions []Session) listMetrics {
 		if w := runewidth.StringWidth(memLabel); w > metrics.memW {
 			metrics.memW = w
 		}
+		cpuLabel := "-"
+		if s.Uptime > 0 {
+			cpuLabel = zmx.FormatCPU(s.CPU)
+		}
+		if w := runewidth.StringWidth(cpuLabel); w > metrics.cpuW {
+			metrics.cpuW = w
+		}
 		uptimeLabel := "-"
 		if s.Uptime > 0 {
 			uptimeLabel = zmx.FormatUptime(s.Uptime)
@@ -323,6 +508,9 @@ func computeListMetrics(sessions []Session) listMetrics {
 		if w := runewidth.StringWidth(clientLabel); w > metrics.clientW {
 			metrics.clientW = w
 		}
+		if w := runewidth.StringWidth(s.Host); w > metrics.hostW {
+			metrics.hostW = w
+		}
 	}
 	return metrics
 }
@@ -330,18 +518,85 @@ func computeListMetrics(sessions []Session) listMetrics {
 func (m *Model) addLog(line string) {
 	ts := logDimStyle.Render(time.Now().Format("15:04:05"))
 	m.logLines = append(m.logLines, ts+" "+line)
-	maxOff := len(m.logLines) - logContentHeight
+	maxOff := len(m.logLines) - m.logHeight
 	if maxOff < 0 {
 		maxOff = 0
 	}
 	m.logOffset = maxOff
 }
 
+// moveCursorTo places the cursor on the session with the given key if it
+// is visible, and reports whether it is.
+func (m *Model) moveCursorTo(key string) bool {
+	for i, row := range m.listRows() {
+		if row.group == nil && row.session.Key() == key {
+			if m.cursorKey() != key {
+				m.previewScrollX = 0
+			}
+			m.cursor = i
+			m.ensureVisible()
+			return true
+		}
+	}
+	return false
+}
+
+// cursorKey returns the key of the session under the cursor, or "".
+func (m *Model) cursorKey() string {
+	if s, ok := m.cursorSession(); ok {
+		return s.Key()
+	}
+	return ""
+}
+
+// logSessionChanges logs sessions that appeared or disappeared between two
+// listings. Unreachable-host placeholders are skipped; they show in the list.
+func (m *Model) logSessionChanges(old, next []Session) {
+	before := make(map[string]bool, len(old))
+	for _, s := range old {
+		before[s.Key()] = true
+	}
+	after := make(map[string]bool, len(next))
+	for _, s := range next {
+		after[s.Key()] = true
+		if !before[s.Key()] && !s.Degraded() {
+			m.addLog(statusStyle.Render("  + " + s.Key()))
+		}
+	}
+	for _, s := range old {
+		if !after[s.Key()] && !s.Degraded() {
+			m.addLog(logDimStyle.Render("  - " + s.Key()))
+		}
+	}
+}
+
+// carryProcessInfo copies process data onto a fresh listing from the
+// previous one, so columns don't blank out (or re-sort) while the new
+// process info is fetched. Only sessions with an unchanged PID qualify.
+func carryProcessInfo(next, old []Session) {
+	byKey := make(map[string]Session, len(old))
+	for _, s := range old {
+		byKey[s.Key()] = s
+	}
+	for i, s := range next {
+		prev, ok := byKey[s.Key()]
+		if !ok || prev.PID != s.PID {
+			continue
+		}
+		next[i].Memory = prev.Memory
+		next[i].Uptime = prev.Uptime
+		next[i].StartedAt = prev.StartedAt
+		next[i].Threads = prev.Threads
+		next[i].State = prev.State
+		next[i].CPU = prev.CPU
+	}
+}
+
 // clampCursor ensures cursor and listOffset are valid for the visible list.
 func (m *Model) clampCursor() {
-	visible := m.visibleSessions()
-	if m.cursor >= len(visible) {
-		m.cursor = max(0, len(visible)-1)
+	rows := m.listRows()
+	if m.cursor >= len(rows) {
+		m.cursor = max(0, len(rows)-1)
 	}
 	if m.listOffset > m.cursor {
 		m.listOffset = m.cursor
@@ -349,6 +604,9 @@ func (m *Model) clampCursor() {
 }
 
 func (m Model) Init() tea.Cmd {
+	if m.refreshInterval > 0 {
+		return tea.Batch(fetchSessionsCmd, refreshTickCmd(m.refreshInterval))
+	}
 	return fetchSessionsCmd
 }
 
@@ -358,31 +616,62 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		visible := m.visibleSessions()
-		if m.state != stateKilling && m.cursor < len(visible) {
+		if m.state == stateHistory {
+			return m, tea.Batch(m.previewCmd(), m.loadHistory())
+		}
+		if m.state != stateKilling && m.cursor < len(m.listRows()) {
 			return m, m.previewCmd()
 		}
 
 	case sessionsMsg:
+		m.refreshing = false
 		if msg.err != nil {
-			m.err = msg.err
-			return m, nil
+			if len(msg.sessions) == 0 {
+				if m.loaded {
+					// Keep showing the last good listing; a later refresh
+					// may succeed.
+					m.addLog(confirmStyle.Render("  ✗ " + firstLine(msg.err)))
+					return m, nil
+				}
+				m.err = msg.err
+				return m, nil
+			}
+			m.addLog(confirmStyle.Render("  ✗ " + firstLine(msg.err)))
+		}
+		cursorKey := m.cursorKey()
+		if m.loaded {
+			m.logSessionChanges(m.sessions, msg.sessions)
 		}
+		m.meta.Apply(msg.sessions)
+		carryProcessInfo(msg.sessions, m.sessions)
 		m.sessions = msg.sessions
 		m.markSessionsChanged()
+		if msg.err == nil && !slices.ContainsFunc(m.sessions, Session.Degraded) {
+			m.pruneMeta()
+			m.pruneProtection()
+		}
+		if m.pickMode && !m.loaded && len(m.sessions) == 0 {
+			return m, tea.Quit
+		}
+		m.loaded = true
 		live := make(map[string]bool, len(m.sessions))
 		for _, s := range m.sessions {
-			live[s.Name] = true
+			live[s.Key()] = true
 		}
 		for name := range m.selected {
 			if !live[name] {
 				delete(m.selected, name)
 			}
 		}
-		m.clampCursor()
+		if m.pendingCursor != "" {
+			cursorKey = m.pendingCursor
+			m.pendingCursor = ""
+		}
+		if !m.moveCursorTo(cursorKey) {
+			m.clampCursor()
+		}
 		cmds := []tea.Cmd{fetchProcessInfoCmd(m.sessions)}
-		visible := m.visibleSessions()
-		if len(visible) > 0 && m.cursor < len(visible) {
+		if m.cursor < len(m.listRows()) {
 			cmds = append(cmds, m.previewCmd())
 		} else {
 			m.preview = ""
@@ -390,105 +679,197 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(cmds...)
 
 	case processInfoMsg:
-		updated := false
-		for i := range m.sessions {
-			if info, ok := msg.info[m.sessions[i].Name]; ok {
-				m.sessions[i].Memory = info.Memory
-				m.sessions[i].Uptime = info.Uptime
-				updated = true
-			}
-		}
-		if updated {
+		m.cpuSampler.Sample(msg.info)
+		if zmx.ApplyProcessInfo(m.sessions, msg.info) {
 			m.markSessionsChanged()
 		}
 
 	case previewMsg:
-		visible := m.visibleSessions()
-		if m.cursor < len(visible) && visible[m.cursor].Name == msg.name {
-			m.preview = msg.content
+		m.applyPreview(msg)
+
+	case followTickMsg:
+		if !m.follow || msg.seq != m.followSeq {
+			return m, nil
 		}
+		if m.state == stateKilling {
+			return m, followTickCmd(m.followInterval, msg.seq)
+		}
+		return m, tea.Batch(m.pollFollowed(), followTickCmd(m.followInterval, msg.seq))
 
-	case killOneResultMsg:
-		if msg.err != nil {
-			m.addLog(confirmStyle.Render("  ✗ " + msg.name))
-		} else {
-			m.addLog(statusStyle.Render("  ✓ " + msg.name))
-			m.killDoneNames = append(m.killDoneNames, msg.name)
+	case previewDueMsg:
+		if msg.seq == m.previews.seq {
+			return m, m.fetchDuePreviews()
 		}
-		m.killNow = ""
-		if len(m.killQueue) > 0 {
-			next := m.killQueue[0]
-			m.killQueue = m.killQueue[1:]
-			m.killNow = next
-			m.addLog(helpStyle.Render("  ⋯ " + next))
-			return m, killOneCmd(next)
+
+	case historyMsg:
+		if m.state == stateHistory && m.history.key == msg.name {
+			m.applyHistory(msg)
 		}
-		if len(m.killDoneNames) > 0 {
-			m.addLog(logDimStyle.Render("  Waiting for cleanup..."))
-			return m, waitForGoneCmd(m.killDoneNames, 0)
+
+	case grepMsg:
+		if m.state == stateGrep && m.grep.running && m.grep.query == msg.query {
+			m.applyGrep(msg)
 		}
-		return m, m.finishKill()
 
-	case waitCheckMsg:
-		return m, waitForGoneCmd(msg.names, msg.attempt)
+	case sendDoneMsg:
+		return m, m.applySend(msg)
 
-	case allGoneMsg:
-		return m, m.finishKill()
+	case sendSettledMsg:
+		if m.state == stateHistory {
+			m.history.polling = true
+			return m, tea.Batch(m.previewCmd(), m.loadHistory())
+		}
+		return m, m.previewCmd()
+
+	case exportDoneMsg:
+		m.applyExport(msg)
+
+	case killStageMsg:
+		if m.state == stateKilling {
+			return m, m.applyKillStage(msg)
+		}
+
+	case sessionCreatedMsg:
+		if msg.err != nil {
+			m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ Create failed: %v", firstLine(msg.err))))
+			return m, nil
+		}
+		m.pendingCursor = msg.name
+		return m, fetchSessionsCmd
+
+	case attachDoneMsg:
+		if msg.err != nil {
+			m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ Attach %s failed: %v", msg.name, msg.err)))
+		} else {
+			held := int(time.Since(msg.started) / time.Second)
+			m.addLog(statusStyle.Render(fmt.Sprintf("  Detached from %s after %s", msg.name, zmx.FormatUptime(held))))
+		}
+		m.pendingCursor = msg.name
+		return m, fetchSessionsCmd
+
+	case refreshTickMsg:
+		next := refreshTickCmd(m.refreshInterval)
+		if m.refreshing || m.state == stateKilling {
+			return m, next
+		}
+		m.refreshing = true
+		return m, tea.Batch(fetchSessionsCmd, next)
 
 	case statusClearMsg:
 		m.status = ""
 
 	case tea.KeyPressMsg:
 		if m.state == stateKilling {
-			if isQuit(msg) {
+			if m.keys.is(msg, actQuit) {
 				return m, tea.Quit
 			}
+			if msg.Code == tea.KeyEscape {
+				return m, m.cancelKill()
+			}
 			m.handleLogScroll(msg)
 			return m, nil
 		}
 		if m.state == stateFilter {
 			return m.handleFilterKey(msg)
 		}
+		if m.state == stateNewSession {
+			return m.handleFormKey(msg)
+		}
+		if m.state == stateHistory {
+			return m.handleHistoryKey(msg)
+		}
+		if m.state == stateGrep {
+			return m.handleGrepKey(msg)
+		}
+		if m.state == stateExport {
+			return m.handleExportKey(msg)
+		}
+		if m.state == stateSend {
+			return m.handleSendKey(msg)
+		}
+		if m.state == stateEditMeta {
+			return m.handleMetaKey(msg)
+		}
 		return m.handleKey(msg)
 	}
 
 	return m, nil
 }
 
-func (m *Model) finishKill() tea.Cmd {
-	killed := len(m.killDoneNames)
-	m.addLog(statusStyle.Render(fmt.Sprintf("  Done. Killed %d session(s).", killed)))
-	m.state = stateNormal
-	m.selected = make(map[string]bool)
-	m.filterText = ""
-	m.markVisibleChanged()
-	m.cursor = 0
-	m.listOffset = 0
-	m.killQueue = nil
-	m.killDoneNames = nil
-	m.killNow = ""
-	return tea.Batch(fetchSessionsCmd, clearStatusAfter(3*time.Second))
+// attach either hands the session to the caller to exec after quitting, or
+// in stay mode runs the attach as a child and resumes when it exits.
+func (m *Model) attach(s Session, dir string, command []string) tea.Cmd {
+	if m.stay {
+		return attachCmd(s, dir, command)
+	}
+	m.attachTarget = &s
+	m.attachDir = dir
+	m.attachCmd = command
+	return tea.Quit
 }
 
-func (m *Model) previewCmd() tea.Cmd {
-	visible := m.visibleSessions()
-	if m.cursor >= len(visible) {
+// killTargets returns the targets (see targets) that are not protected.
+func (m *Model) killTargets() []string {
+	return slices.DeleteFunc(m.targets(), m.isProtected)
+}
+
+// targets returns the keys of the selected sessions or, if nothing is
+// selected, of the cursor session or every session in the cursor group.
+// Placeholders for unreachable hosts are never targets.
+func (m *Model) targets() []string {
+	var keys []string
+	if len(m.selected) > 0 {
+		keys = m.selectedNames()
+	} else if row, ok := m.cursorRow(); !ok {
 		return nil
+	} else if row.group != nil {
+		keys = row.group.keys()
+	} else {
+		keys = []string{row.session.Key()}
 	}
-	return fetchPreviewCmd(visible[m.cursor].Name, m.mainContentHeight(1))
+	return slices.DeleteFunc(keys, m.isDegraded)
 }
 
-func (m *Model) killTargets() []string {
-	if len(m.selected) > 0 {
-		names := make([]string, 0, len(m.selected))
-		for name := range m.selected {
-			names = append(names, name)
+// isDegraded reports whether key is the placeholder of an unreachable host.
+func (m *Model) isDegraded(key string) bool {
+	return slices.ContainsFunc(m.sessions, func(s Session) bool {
+		return s.Degraded() && s.Key() == key
+	})
+}
+
+// selectedNames returns the selected session keys in list order, followed
+// by any selections hidden by the current filter.
+func (m *Model) selectedNames() []string {
+	names := make([]string, 0, len(m.selected))
+	seen := make(map[string]bool, len(m.selected))
+	for _, s := range m.visibleSessions() {
+		if m.selected[s.Key()] {
+			names = append(names, s.Key())
+			seen[s.Key()] = true
+		}
+	}
+	var hidden []string
+	for name := range m.selected {
+		if !seen[name] {
+			hidden = append(hidden, name)
 		}
-		return names
 	}
-	visible := m.visibleSessions()
-	if m.cursor < len(visible) {
-		return []string{visible[m.cursor].Name}
+	slices.Sort(hidden)
+	return append(names, hidden...)
+}
+
+// sessionsByKey returns the current sessions for keys, in the same order,
+// skipping any that are no longer listed.
+func (m *Model) sessionsByKey(keys []string) []Session {
+	byKey := make(map[string]Session, len(m.sessions))
+	for _, s := range m.sessions {
+		byKey[s.Key()] = s
+	}
+	var out []Session
+	for _, key := range keys {
+		if s, ok := byKey[key]; ok {
+			out = append(out, s)
+		}
 	}
-	return nil
+	return out
 }
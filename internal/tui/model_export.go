@@ -0,0 +1,185 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+const (
+	exportFieldFormat = iota
+	exportFieldDir
+	exportFieldCount
+)
+
+// exportForm holds the state of the export form.
+type exportForm struct {
+	targets []string // session keys to export
+	format  zmx.ExportFormat
+	dir     string
+	focus   int
+	err     string
+}
+
+// exportResult reports the export of one session.
+type exportResult struct {
+	name string
+	path string
+	err  error
+}
+
+type exportDoneMsg struct {
+	results []exportResult
+}
+
+func exportCmd(sessions []Session, dir string, f zmx.ExportFormat) tea.Cmd {
+	return func() tea.Msg {
+		opts := zmx.ExportOptions{Width: zmx.ExportWidth, Height: zmx.ExportHeight}
+		results := make([]exportResult, 0, len(sessions))
+		paths := zmx.ExportPaths(dir, sessions, f)
+		for i, s := range sessions {
+			path := paths[i]
+			results = append(results, exportResult{name: s.Key(), path: path, err: zmx.ExportToFile(path, s, f, opts)})
+		}
+		return exportDoneMsg{results: results}
+	}
+}
+
+// openExportForm switches to the export form for the selected sessions, or
+// the cursor session, defaulting the directory to the working directory.
+func (m *Model) openExportForm() {
+	var targets []string
+	for _, s := range m.sessionsByKey(m.targets()) {
+		if !s.Degraded() {
+			targets = append(targets, s.Key())
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	dir, _ := os.Getwd()
+	m.exportForm = exportForm{targets: targets, dir: dir}
+	m.state = stateExport
+}
+
+func (m Model) handleExportKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	if msg.Code == 'c' && msg.Mod.Contains(tea.ModCtrl) {
+		return m, tea.Quit
+	}
+	f := &m.exportForm
+
+	switch {
+	case msg.Code == tea.KeyEscape:
+		m.state = stateNormal
+		return m, nil
+
+	case msg.Code == tea.KeyTab, msg.Code == tea.KeyUp, msg.Code == tea.KeyDown:
+		f.focus = (f.focus + 1) % exportFieldCount
+
+	case f.focus == exportFieldFormat && (msg.Code == tea.KeyRight || msg.Code == tea.KeySpace):
+		f.format = (f.format + 1) % zmx.ExportFormatCount
+
+	case f.focus == exportFieldFormat && msg.Code == tea.KeyLeft:
+		f.format = (f.format + zmx.ExportFormatCount - 1) % zmx.ExportFormatCount
+
+	case msg.Code == tea.KeyBackspace:
+		if f.focus == exportFieldDir {
+			if r := []rune(f.dir); len(r) > 0 {
+				f.dir = string(r[:len(r)-1])
+			}
+		}
+
+	case msg.Code == tea.KeyEnter:
+		dir, err := exportDir(f.dir)
+		if err != nil {
+			f.err = err.Error()
+			return m, nil
+		}
+		m.state = stateNormal
+		m.addLog(titleStyle.Render(fmt.Sprintf("Exporting %d session(s) as %s...", len(f.targets), f.format)))
+		return m, exportCmd(m.sessionsByKey(f.targets), dir, f.format)
+
+	default:
+		if f.focus == exportFieldDir && msg.Text != "" {
+			f.dir += msg.Text
+		}
+	}
+	f.err = ""
+	return m, nil
+}
+
+// exportDir expands ~ in dir, makes it absolute and creates it if needed.
+func exportDir(dir string) (string, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, _ := os.UserHomeDir()
+		dir = home + dir[1:]
+	}
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	dir, _ = filepath.Abs(dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("cannot create %s: %v", dir, err)
+	}
+	return dir, nil
+}
+
+func (m *Model) applyExport(msg exportDoneMsg) {
+	failed := 0
+	for _, r := range msg.results {
+		if r.err != nil {
+			failed++
+			m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ %s: %s", r.name, firstLine(r.err))))
+			continue
+		}
+		m.addLog(statusStyle.Render(fmt.Sprintf("  ✓ %s → %s", r.name, r.path)))
+	}
+	if failed > 0 {
+		m.addLog(confirmStyle.Render(fmt.Sprintf("  %d of %d export(s) failed.", failed, len(msg.results))))
+	}
+}
+
+func (m Model) renderExportForm() string {
+	f := m.exportForm
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("  Export the full history of " + targetLabel(f.targets) + "."))
+	b.WriteString("\n\n")
+
+	formats := make([]string, 0, zmx.ExportFormatCount)
+	for ef := zmx.ExportFormat(0); ef < zmx.ExportFormatCount; ef++ {
+		if ef == f.format {
+			formats = append(formats, helpKeyStyle.Render("["+ef.String()+"]"))
+		} else {
+			formats = append(formats, helpStyle.Render(" "+ef.String()+" "))
+		}
+	}
+	dir := f.dir
+	marker := [exportFieldCount]string{"  ", "  "}
+	marker[f.focus] = selectedStyle.Render("▸ ")
+	if f.focus == exportFieldDir {
+		dir += "█"
+	}
+	b.WriteString(fmt.Sprintf("%s%s %s\n", marker[exportFieldFormat], helpStyle.Render(padRight("Format:", 11)), strings.Join(formats, "")))
+	b.WriteString(fmt.Sprintf("%s%s %s\n", marker[exportFieldDir], helpStyle.Render(padRight("Directory:", 11)), helpKeyStyle.Render(dir)))
+	b.WriteString("\n")
+	if f.err != "" {
+		b.WriteString(confirmStyle.Render("  " + f.err))
+	} else {
+		example := zmx.ExportFileName(Session{Name: "name"}, f.format)
+		b.WriteString(logDimStyle.Render("  Files are named after the session, e.g. " + example + "."))
+	}
+	return b.String()
+}
+
+func targetLabel(targets []string) string {
+	if len(targets) == 1 {
+		return targets[0]
+	}
+	return fmt.Sprintf("%d sessions", len(targets))
+}
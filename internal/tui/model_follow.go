@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// followTickMsg drives follow mode; seq ties it to one toggle so that a
+// tick from an earlier toggle does not start a second polling loop.
+type followTickMsg struct {
+	seq int
+}
+
+func followTickCmd(d time.Duration, seq int) tea.Cmd {
+	return tea.Tick(d, func(time.Time) tea.Msg { return followTickMsg{seq: seq} })
+}
+
+// toggleFollow turns follow mode on or off for the preview pane.
+func (m *Model) toggleFollow() tea.Cmd {
+	m.follow = !m.follow
+	m.followSeq++
+	m.followNew = 0
+	if !m.follow {
+		m.addLog(logDimStyle.Render("  Stopped following"))
+		return nil
+	}
+	m.addLog(statusStyle.Render("  Following " + m.followedKey()))
+	return tea.Batch(m.pollFollowed(), followTickCmd(m.followInterval, m.followSeq))
+}
+
+// followedKey returns the session follow mode is polling: the one in
+// history mode, or the cursor session.
+func (m *Model) followedKey() string {
+	if m.state == stateHistory {
+		return m.history.key
+	}
+	return m.cursorKey()
+}
+
+// pollFollowed re-fetches the followed session, bypassing the preview
+// cache.
+func (m *Model) pollFollowed() tea.Cmd {
+	if m.state == stateHistory {
+		if m.history.loading {
+			return nil
+		}
+		m.history.polling = true
+		return m.loadHistory()
+	}
+	targets := m.previewTargets()
+	if len(targets) == 0 || targets[0].Degraded() {
+		return nil
+	}
+	s := targets[0]
+	c := m.previews
+	if _, busy := c.inflight[s.Key()]; busy {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.inflight[s.Key()] = cancel
+	return fetchPreviewCmd(ctx, s, m.previewInnerWidth(), m.mainContentHeight(1))
+}
+
+// noteFollowedPreview records whether a preview of the followed session
+// differs from the one on screen, for the new-output indicator.
+func (m *Model) noteFollowedPreview(msg previewMsg) {
+	if !m.follow || msg.name != m.cursorKey() {
+		return
+	}
+	m.followNew = 0
+	if msg.name == m.previewKey && msg.content != m.preview {
+		m.followNew = 1
+	}
+}
+
+// followLabel is appended to the preview pane title while following.
+func (m *Model) followLabel() string {
+	switch {
+	case !m.follow:
+		return ""
+	case m.state == stateHistory && m.followNew > 0:
+		return fmt.Sprintf("↓ %d new ", m.followNew)
+	case m.state != stateHistory && m.followNew > 0:
+		return "● new output "
+	}
+	return "● follow "
+}
+
+// alignHistory works out how lines moved between two loads of the same
+// history: index i in old is index i+shift in next, and added lines were
+// appended after the old last line. It matches up to the last three old
+// lines; ok is false if they are not found, e.g. because the bottom of
+// the screen was redrawn.
+func alignHistory(old, next []string) (shift, added int, ok bool) {
+	n := min(3, len(old))
+	if n == 0 {
+		return 0, len(next), true
+	}
+	tail := old[len(old)-n:]
+	for end := len(next); end >= n; end-- {
+		if slices.Equal(next[end-n:end], tail) {
+			return end - len(old), len(next) - end, true
+		}
+	}
+	return 0, 0, false
+}
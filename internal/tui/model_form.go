@@ -0,0 +1,153 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+const (
+	formFieldName = iota
+	formFieldDir
+	formFieldCmd
+	formFieldCount
+)
+
+var formFieldLabels = [formFieldCount]string{"Name", "Directory", "Command"}
+
+// newSessionForm holds the state of the "new session" form.
+type newSessionForm struct {
+	fields [formFieldCount]string
+	focus  int
+	err    string
+}
+
+type sessionCreatedMsg struct {
+	name string
+	err  error
+}
+
+func createSessionCmd(name, dir string, command []string) tea.Cmd {
+	return func() tea.Msg {
+		return sessionCreatedMsg{name: name, err: zmx.CreateSession(name, dir, command)}
+	}
+}
+
+// openNewSessionForm switches to the form, defaulting the directory to the
+// cursor session's directory or the current working directory.
+func (m *Model) openNewSessionForm() {
+	dir, _ := os.Getwd()
+	if s, ok := m.cursorSession(); ok && s.StartedIn != "" {
+		dir = s.StartedIn
+	}
+	m.form = newSessionForm{}
+	m.form.fields[formFieldDir] = dir
+	m.state = stateNewSession
+}
+
+func (m Model) handleFormKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	if msg.Code == 'c' && msg.Mod.Contains(tea.ModCtrl) {
+		return m, tea.Quit
+	}
+	f := &m.form
+
+	switch {
+	case msg.Code == tea.KeyEscape:
+		m.state = stateNormal
+		return m, nil
+
+	case msg.Code == tea.KeyTab && msg.Mod.Contains(tea.ModShift), msg.Code == tea.KeyUp:
+		f.focus = (f.focus + formFieldCount - 1) % formFieldCount
+
+	case msg.Code == tea.KeyTab, msg.Code == tea.KeyDown:
+		f.focus = (f.focus + 1) % formFieldCount
+
+	case msg.Code == tea.KeyBackspace:
+		r := []rune(f.fields[f.focus])
+		if len(r) > 0 {
+			f.fields[f.focus] = string(r[:len(r)-1])
+		}
+
+	case msg.Code == tea.KeyEnter, msg.Code == 'd' && msg.Mod.Contains(tea.ModCtrl):
+		name, dir, command, err := m.validateForm()
+		if err != nil {
+			f.err = err.Error()
+			return m, nil
+		}
+		m.state = stateNormal
+		if msg.Code == tea.KeyEnter {
+			return m, m.attach(zmx.NewSession(name), dir, command)
+		}
+		m.addLog(helpStyle.Render("  ⋯ creating " + name))
+		return m, createSessionCmd(name, dir, command)
+
+	default:
+		if msg.Text != "" {
+			f.fields[f.focus] += msg.Text
+		}
+	}
+	f.err = ""
+	return m, nil
+}
+
+// validateForm checks the form and returns the session name, absolute start
+// directory and command arguments.
+func (m *Model) validateForm() (name, dir string, command []string, err error) {
+	name = strings.TrimSpace(m.form.fields[formFieldName])
+	if name == "" {
+		return "", "", nil, fmt.Errorf("name is required")
+	}
+	if strings.ContainsAny(name, " \t") {
+		return "", "", nil, fmt.Errorf("name must not contain spaces")
+	}
+	key := zmx.NewSession(name).Key()
+	for _, s := range m.sessions {
+		if s.Key() == key {
+			return "", "", nil, fmt.Errorf("session %q already exists", name)
+		}
+	}
+
+	dir = strings.TrimSpace(m.form.fields[formFieldDir])
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, _ := os.UserHomeDir()
+		dir = home + dir[1:]
+	}
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	dir, _ = filepath.Abs(dir)
+	if fi, statErr := os.Stat(dir); statErr != nil || !fi.IsDir() {
+		return "", "", nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	command, err = zmx.SplitCommand(m.form.fields[formFieldCmd])
+	if err != nil {
+		return "", "", nil, fmt.Errorf("command: %w", err)
+	}
+	return name, dir, command, nil
+}
+
+func (m Model) renderForm() string {
+	var b strings.Builder
+	b.WriteString("\n")
+	for i, label := range formFieldLabels {
+		value := m.form.fields[i]
+		marker := "  "
+		if i == m.form.focus {
+			marker = selectedStyle.Render("▸ ")
+			value += "█"
+		}
+		b.WriteString(fmt.Sprintf("%s%s %s\n", marker, helpStyle.Render(padRight(label+":", 11)), helpKeyStyle.Render(value)))
+	}
+	b.WriteString("\n")
+	if m.form.err != "" {
+		b.WriteString(confirmStyle.Render("  " + m.form.err))
+	} else {
+		b.WriteString(logDimStyle.Render("  Command is optional; leave it empty for a shell."))
+	}
+	return b.String()
+}
@@ -0,0 +1,314 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+// grepContext is the number of lines shown around each grep match.
+const grepContext = 1
+
+// grepView holds the state of the cross-session grep mode. Matching is a
+// case-insensitive substring search, like history search, so that opening
+// a hit in history mode finds the same lines.
+type grepView struct {
+	typing   bool // editing the query
+	query    string
+	running  bool
+	cancel   context.CancelFunc // stops the running search
+	done     bool               // results hold a finished search
+	searched string             // the query results were found for
+	results  []zmx.GrepResult
+	hits     []grepHit // every match across results, in display order
+	hit      int       // index into hits of the selected match
+}
+
+// grepHit locates one match within grepView.results.
+type grepHit struct {
+	result, match int
+}
+
+type grepMsg struct {
+	query   string
+	results []zmx.GrepResult
+}
+
+func grepCmd(ctx context.Context, sessions []Session, query string, width, height int) tea.Cmd {
+	return func() tea.Msg {
+		q := strings.ToLower(query)
+		results := zmx.GrepContext(ctx, sessions, func(line string) bool {
+			return strings.Contains(strings.ToLower(line), q)
+		}, zmx.GrepOptions{Width: width, Height: height, Lines: historyWindow, Context: grepContext})
+		if ctx.Err() != nil {
+			return nil // cancelled with Esc
+		}
+		return grepMsg{query: query, results: results}
+	}
+}
+
+// openGrep switches to grep mode with an empty query.
+func (m *Model) openGrep() {
+	m.grep = grepView{typing: true}
+	m.state = stateGrep
+}
+
+// closeGrep leaves grep mode, stopping a running search.
+func (m *Model) closeGrep() {
+	m.cancelGrep()
+	m.grep = grepView{}
+	m.state = stateNormal
+}
+
+// cancelGrep stops a running search, if any.
+func (m *Model) cancelGrep() {
+	g := &m.grep
+	if g.cancel != nil {
+		g.cancel()
+		g.cancel = nil
+	}
+	g.running = false
+}
+
+// runGrep searches the history of every visible session for the query.
+func (m *Model) runGrep() tea.Cmd {
+	var sessions []Session
+	for _, s := range m.visibleSessions() {
+		if !s.Degraded() {
+			sessions = append(sessions, s)
+		}
+	}
+	m.cancelGrep()
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &m.grep
+	g.typing = false
+	g.running = true
+	g.cancel = cancel
+	g.done = false
+	g.searched = ""
+	g.results, g.hits, g.hit = nil, nil, 0
+	return grepCmd(ctx, sessions, g.query, m.previewInnerWidth(), m.mainContentHeight(1))
+}
+
+func (m *Model) applyGrep(msg grepMsg) {
+	m.cancelGrep()
+	g := &m.grep
+	g.done = true
+	g.searched = msg.query
+	g.results = msg.results
+	g.hits, g.hit = nil, 0
+	found := 0
+	for i, r := range msg.results {
+		if r.Err != nil {
+			m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ grep %s: %s", r.Session.Key(), firstLine(r.Err))))
+			continue
+		}
+		found++
+		for j := range r.Matches {
+			g.hits = append(g.hits, grepHit{i, j})
+		}
+	}
+	m.addLog(statusStyle.Render(fmt.Sprintf("  grep %q: %d match(es) in %d session(s)", msg.query, len(g.hits), found)))
+}
+
+// openGrepHit moves the cursor to the session of the selected match and
+// opens its history scrolled to the matching line.
+func (m *Model) openGrepHit() tea.Cmd {
+	g := &m.grep
+	if g.hit >= len(g.hits) {
+		return nil
+	}
+	h := g.hits[g.hit]
+	r := g.results[h.result]
+	key := r.Session.Key()
+	if !m.moveCursorTo(key) {
+		m.addLog(confirmStyle.Render("  ✗ " + key + " is no longer listed"))
+		return nil
+	}
+	m.history = historyView{
+		key:      key,
+		window:   historyWindow,
+		query:    g.searched,
+		fromGrep: true,
+		jump:     true,
+		jumpLine: r.Matches[h.match].Line,
+	}
+	m.state = stateHistory
+	return tea.Batch(m.previewCmd(), m.loadHistory())
+}
+
+func (m Model) handleGrepKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	if msg.Code == 'c' && msg.Mod.Contains(tea.ModCtrl) {
+		return m, tea.Quit
+	}
+	g := &m.grep
+
+	if g.typing {
+		switch msg.Code {
+		case tea.KeyEscape:
+			// Abandoning an edit goes back to the results it would have
+			// replaced, or out of grep mode if there are none.
+			if !g.done {
+				m.closeGrep()
+				return m, nil
+			}
+			g.query = g.searched
+			g.typing = false
+		case tea.KeyEnter:
+			if g.query != "" {
+				return m, m.runGrep()
+			}
+		case tea.KeyBackspace:
+			if r := []rune(g.query); len(r) > 0 {
+				g.query = string(r[:len(r)-1])
+			}
+		default:
+			if msg.Text != "" {
+				g.query += msg.Text
+			}
+		}
+		return m, nil
+	}
+
+	switch msg.Code {
+	case tea.KeyEscape:
+		if g.running {
+			// Stop the search and go back to editing its query.
+			m.cancelGrep()
+			g.typing = true
+			m.addLog(logDimStyle.Render(fmt.Sprintf("  grep %q cancelled", g.query)))
+			return m, nil
+		}
+		m.closeGrep()
+		return m, nil
+	case tea.KeyUp:
+		g.hit = max(g.hit-1, 0)
+		return m, nil
+	case tea.KeyDown:
+		g.hit = min(g.hit+1, max(len(g.hits)-1, 0))
+		return m, nil
+	case tea.KeyEnter:
+		return m, m.openGrepHit()
+	}
+
+	switch m.keys.action(msg) {
+	case actQuit:
+		m.closeGrep()
+	case actGrep, actFilter:
+		if !g.running {
+			g.typing = true
+		}
+	}
+	return m, nil
+}
+
+// grepRow is one rendered line of grep results; hit is the index into
+// grepView.hits for matching lines and -1 otherwise.
+type grepRow struct {
+	text string
+	hit  int
+}
+
+// grepRows lays out the results: a header per session followed by its
+// matches and their context, with "--" between groups that are not
+// adjacent.
+func (m *Model) grepRows() []grepRow {
+	g := &m.grep
+	width := m.previewInnerWidth()
+	var rows []grepRow
+	hit := 0
+	for _, r := range g.results {
+		if r.Err != nil {
+			continue
+		}
+		rows = append(rows, grepRow{titleStyle.Render(r.Session.Key()) + logDimStyle.Render(fmt.Sprintf(" (%d)", len(r.Matches))), -1})
+		lines := make(map[int]string)
+		hits := make(map[int]int)
+		for _, mt := range r.Matches {
+			for k, text := range mt.Before {
+				lines[mt.Line-len(mt.Before)+k] = text
+			}
+			for k, text := range mt.After {
+				lines[mt.Line+1+k] = text
+			}
+			lines[mt.Line] = mt.Text
+			hits[mt.Line] = hit
+			hit++
+		}
+		order := slices.Sorted(maps.Keys(lines))
+		for k, n := range order {
+			if k > 0 && n > order[k-1]+1 {
+				rows = append(rows, grepRow{logDimStyle.Render("  --"), -1})
+			}
+			num := fmt.Sprintf("%5d ", n+1)
+			text := truncate(lines[n], max(width-8, 1))
+			h, ok := hits[n]
+			if !ok {
+				rows = append(rows, grepRow{"  " + logDimStyle.Render(num+text), -1})
+				continue
+			}
+			indicator, base := "  ", lipgloss.NewStyle()
+			if h == g.hit {
+				indicator, base = selectedStyle.Render("▸ "), selectedStyle
+			}
+			rows = append(rows, grepRow{indicator + logDimStyle.Render(num) + highlightMatch(text, g.searched, base, filterMatchStyle), h})
+		}
+	}
+	return rows
+}
+
+// renderGrep renders the results, scrolled so the selected match is
+// visible.
+func (m *Model) renderGrep(height int) string {
+	g := &m.grep
+	switch {
+	case g.running:
+		return helpStyle.Render(fmt.Sprintf("Searching for %q...", g.query))
+	case !g.done:
+		return helpStyle.Render("Type text to search for in every session's history.")
+	case len(g.hits) == 0:
+		return normalStyle.Render(fmt.Sprintf("  No matches for %q.", g.searched))
+	}
+	rows := m.grepRows()
+	selected := 0
+	for i, r := range rows {
+		if r.hit == g.hit {
+			selected = i
+			break
+		}
+	}
+	top := 0
+	if selected >= height {
+		top = min(selected-height/3, len(rows)-height)
+	}
+	end := min(top+height, len(rows))
+	out := make([]string, 0, end-top)
+	for _, r := range rows[top:end] {
+		out = append(out, r.text)
+	}
+	return strings.Join(out, "\n")
+}
+
+// grepTitle summarises the results for the pane title.
+func (m *Model) grepTitle() string {
+	g := &m.grep
+	if g.running || !g.done {
+		return ""
+	}
+	sessions := 0
+	for _, r := range g.results {
+		if r.Err == nil {
+			sessions++
+		}
+	}
+	if len(g.hits) == 0 {
+		return fmt.Sprintf(" %q no matches ", g.searched)
+	}
+	return fmt.Sprintf(" %q %d/%d in %d session(s) ", g.searched, g.hit+1, len(g.hits), sessions)
+}
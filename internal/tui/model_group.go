@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+// sessionGroup is a set of visible sessions sharing a group key (see
+// zmx.Grouper).
+type sessionGroup struct {
+	key      string
+	sessions []Session
+}
+
+// listRow is one line of the session list: a group header when group is
+// set, otherwise a session.
+type listRow struct {
+	group   *sessionGroup
+	session Session
+}
+
+func (g *sessionGroup) label(mode zmx.GroupMode) string {
+	if g.key != "" {
+		return g.key
+	}
+	switch mode {
+	case zmx.GroupByDir:
+		return "(no directory)"
+	case zmx.GroupByRepo:
+		return "(no repository)"
+	case zmx.GroupByPrefix:
+		return "(no prefix)"
+	case zmx.GroupByTag:
+		return "(no tags)"
+	}
+	return "(none)"
+}
+
+// totals returns the group's total memory and attached clients.
+func (g *sessionGroup) totals() (memory uint64, clients int) {
+	for _, s := range g.sessions {
+		memory += s.Memory
+		clients += s.Clients
+	}
+	return memory, clients
+}
+
+func (g *sessionGroup) keys() []string {
+	keys := make([]string, len(g.sessions))
+	for i, s := range g.sessions {
+		keys[i] = s.Key()
+	}
+	return keys
+}
+
+// groupRows orders sessions by group and returns them along with the list
+// rows: each group's header, followed by its sessions unless collapsed.
+// Without a group mode every session is a row of its own. Groups are
+// sorted by key, with the ungrouped last; while the fuzzy filter ranks the
+// list, they keep the order of their best match instead.
+func (m *Model) groupRows(sessions []Session) ([]Session, []listRow) {
+	if m.grouper.Mode == zmx.GroupNone {
+		rows := make([]listRow, len(sessions))
+		for i, s := range sessions {
+			rows[i] = listRow{session: s}
+		}
+		return sessions, rows
+	}
+	var groups []*sessionGroup
+	byKey := make(map[string]*sessionGroup)
+	for _, s := range sessions {
+		key := m.grouper.Key(s)
+		g, ok := byKey[key]
+		if !ok {
+			g = &sessionGroup{key: key}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.sessions = append(g.sessions, s)
+	}
+	if !m.fuzzyActive() {
+		slices.SortStableFunc(groups, func(a, b *sessionGroup) int {
+			if (a.key == "") != (b.key == "") {
+				if a.key == "" {
+					return 1
+				}
+				return -1
+			}
+			return cmp.Compare(a.key, b.key)
+		})
+	}
+	ordered := make([]Session, 0, len(sessions))
+	rows := make([]listRow, 0, len(sessions)+len(groups))
+	for _, g := range groups {
+		ordered = append(ordered, g.sessions...)
+		rows = append(rows, listRow{group: g})
+		if !m.collapsed[g.key] {
+			for _, s := range g.sessions {
+				rows = append(rows, listRow{session: s})
+			}
+		}
+	}
+	return ordered, rows
+}
+
+// listRows returns the rows of the session list; the cursor indexes them.
+func (m *Model) listRows() []listRow {
+	m.visibleSessions()
+	return m.visibleRows
+}
+
+// cursorRow returns the row under the cursor.
+func (m *Model) cursorRow() (listRow, bool) {
+	rows := m.listRows()
+	if m.cursor < len(rows) {
+		return rows[m.cursor], true
+	}
+	return listRow{}, false
+}
+
+// cursorSession returns the session under the cursor, if the cursor is not
+// on a group header.
+func (m *Model) cursorSession() (Session, bool) {
+	row, ok := m.cursorRow()
+	if !ok || row.group != nil {
+		return Session{}, false
+	}
+	return row.session, true
+}
+
+// cycleGroupMode steps through the group modes, keeping the cursor on the
+// same session.
+func (m *Model) cycleGroupMode() {
+	key := m.cursorKey()
+	m.grouper.Mode = (m.grouper.Mode + 1) % zmx.GroupModeCount
+	m.markVisibleChanged()
+	if !m.moveCursorTo(key) {
+		m.clampCursor()
+	}
+}
+
+// toggleCollapsed collapses or expands the group under the cursor.
+func (m *Model) toggleCollapsed(g *sessionGroup) {
+	if m.collapsed[g.key] {
+		delete(m.collapsed, g.key)
+	} else {
+		m.collapsed[g.key] = true
+	}
+	m.markVisibleChanged()
+	m.clampCursor()
+}
+
+// renderGroupHeader renders a group's row: its label, session count, total
+// memory and attached clients.
+func (m *Model) renderGroupHeader(g *sessionGroup, indicator string, width int) string {
+	arrow := "▾ "
+	if m.collapsed[g.key] {
+		arrow = "▸ "
+	}
+	memory, clients := g.totals()
+	summary := fmt.Sprintf(" %d · %s · ●%d", len(g.sessions), zmx.FormatBytes(memory), clients)
+	labelWidth := max(width-2-len([]rune(summary)), 1)
+	label := padRight(truncate(arrow+g.label(m.grouper.Mode), labelWidth), labelWidth)
+	return indicator + titleStyle.Render(label) + logDimStyle.Render(summary)
+}
+
+// renderGroupPreview lists a group's sessions in the preview pane while
+// the cursor is on its header.
+func (m *Model) renderGroupPreview(g *sessionGroup) string {
+	var b strings.Builder
+	for i, s := range g.sessions {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		line := fmt.Sprintf("  %s  %s  ●%d", s.Key(), zmx.FormatBytes(s.Memory), s.Clients)
+		if m.selected[s.Key()] {
+			b.WriteString(selectedStyle.Render(line))
+		} else {
+			b.WriteString(normalStyle.Render(line))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,350 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+// History mode loads historyWindow lines at first and grows the window by
+// historyGrowth each time the user scrolls to the top, up to historyMaxWindow.
+const (
+	historyWindow    = 1000
+	historyGrowth    = 4
+	historyMaxWindow = 64000
+)
+
+// historyView holds the state of the preview pane's history mode.
+type historyView struct {
+	key     string   // session being browsed
+	lines   []string // rendered rows, oldest first
+	plain   []string // lines without escape sequences, for searching
+	window  int      // lines requested
+	more    bool     // older lines exist beyond window
+	loading bool
+	err     string
+	top     int // index of the first visible line
+
+	searching bool // typing a query
+	query     string
+	matches   []int // indices of lines containing query
+	match     int   // index into matches of the current match
+
+	polling bool // the pending load is a follow mode poll
+
+	fromGrep bool // opened from grep mode; leaving returns there
+	jump     bool // select the match nearest jumpLine once loaded
+	jumpLine int
+}
+
+type historyMsg struct {
+	name   string
+	window int
+	lines  []string
+	more   bool
+	err    error
+}
+
+func fetchHistoryCmd(s Session, width, height, window int) tea.Cmd {
+	return func() tea.Msg {
+		lines, more, err := zmx.FetchHistory(s, width, height, window)
+		return historyMsg{name: s.Key(), window: window, lines: lines, more: more, err: err}
+	}
+}
+
+// openHistory switches the preview pane to history mode for the cursor
+// session.
+func (m *Model) openHistory() tea.Cmd {
+	s, ok := m.cursorSession()
+	if !ok || s.Degraded() {
+		return nil
+	}
+	m.history = historyView{key: s.Key(), window: historyWindow}
+	m.state = stateHistory
+	m.followNew = 0
+	return m.loadHistory()
+}
+
+func (m *Model) loadHistory() tea.Cmd {
+	s := m.sessionsByKey([]string{m.history.key})
+	if len(s) == 0 {
+		return nil
+	}
+	m.history.loading = true
+	return fetchHistoryCmd(s[0], m.previewInnerWidth(), m.mainContentHeight(1), m.history.window)
+}
+
+// applyHistory installs freshly loaded lines, keeping the view anchored to
+// the same line counted from the bottom.
+func (m *Model) applyHistory(msg historyMsg) {
+	h := &m.history
+	h.loading = false
+	if msg.err != nil {
+		h.err = msg.err.Error()
+		return
+	}
+	fromBottom := len(h.lines) - h.top
+	first := h.lines == nil
+	pinned := h.top >= m.historyMaxTop()
+	polled := h.polling
+	h.polling = false
+	plain := make([]string, len(msg.lines))
+	for i, l := range msg.lines {
+		plain[i] = ansi.Strip(l)
+	}
+	shift, added, aligned := alignHistory(h.plain, plain)
+	h.lines = msg.lines
+	h.plain = plain
+	h.window = msg.window
+	h.more = msg.more
+	h.err = ""
+	switch {
+	case first || polled && pinned:
+		// Follow mode keeps the view pinned to the bottom.
+		h.top = m.historyMaxTop()
+		m.followNew = 0
+	case polled && aligned:
+		// New output arrived below a view the user scrolled away from:
+		// keep showing the same lines and count what is new.
+		h.top += shift
+		m.followNew += added
+	default:
+		h.top = len(h.lines) - fromBottom
+	}
+	h.top = min(max(h.top, 0), m.historyMaxTop())
+	m.findMatches()
+	if h.jump && len(h.matches) > 0 {
+		h.jump = false
+		for i, line := range h.matches {
+			if abs(line-h.jumpLine) < abs(h.matches[h.match]-h.jumpLine) {
+				h.match = i
+			}
+		}
+		m.jumpToMatch()
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// closeHistory leaves history mode, returning to grep results if history
+// was opened from them.
+func (m *Model) closeHistory() {
+	m.state = stateNormal
+	if m.history.fromGrep {
+		m.state = stateGrep
+	}
+	m.history = historyView{}
+	m.followNew = 0
+}
+
+func (m *Model) historyPageHeight() int {
+	return m.mainContentHeight(1)
+}
+
+func (m *Model) historyMaxTop() int {
+	return max(len(m.history.lines)-m.historyPageHeight(), 0)
+}
+
+// scrollHistory moves the view by delta lines, loading a larger window when
+// scrolling past the top of what is loaded.
+func (m *Model) scrollHistory(delta int) tea.Cmd {
+	h := &m.history
+	h.top = min(max(h.top+delta, 0), m.historyMaxTop())
+	if h.top == m.historyMaxTop() {
+		m.followNew = 0
+	}
+	if h.top == 0 && delta < 0 && h.more && !h.loading && h.window < historyMaxWindow {
+		h.window = min(h.window*historyGrowth, historyMaxWindow)
+		return m.loadHistory()
+	}
+	return nil
+}
+
+// findMatches recomputes the lines matching the query and selects the last
+// match at or above the bottom of the view.
+func (m *Model) findMatches() {
+	h := &m.history
+	h.matches = nil
+	h.match = 0
+	if h.query == "" {
+		return
+	}
+	q := strings.ToLower(h.query)
+	for i, l := range h.plain {
+		if strings.Contains(strings.ToLower(l), q) {
+			h.matches = append(h.matches, i)
+		}
+	}
+	bottom := h.top + m.historyPageHeight() - 1
+	for i, line := range h.matches {
+		if line <= bottom {
+			h.match = i
+		}
+	}
+}
+
+// jumpToMatch scrolls so the current match is visible, a third of the way
+// down the pane.
+func (m *Model) jumpToMatch() {
+	h := &m.history
+	if len(h.matches) == 0 {
+		return
+	}
+	line := h.matches[h.match]
+	page := m.historyPageHeight()
+	if line < h.top || line >= h.top+page {
+		h.top = min(max(line-page/3, 0), m.historyMaxTop())
+	}
+}
+
+func (m Model) handleHistoryKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	if msg.Code == 'c' && msg.Mod.Contains(tea.ModCtrl) {
+		return m, tea.Quit
+	}
+	h := &m.history
+
+	if h.searching {
+		switch msg.Code {
+		case tea.KeyEscape:
+			h.searching = false
+			h.query = ""
+			m.findMatches()
+		case tea.KeyEnter:
+			h.searching = false
+			m.findMatches()
+			m.jumpToMatch()
+		case tea.KeyBackspace:
+			if r := []rune(h.query); len(r) > 0 {
+				h.query = string(r[:len(r)-1])
+			}
+		default:
+			if msg.Text != "" {
+				h.query += msg.Text
+			}
+		}
+		return m, nil
+	}
+
+	page := m.historyPageHeight()
+	switch msg.Code {
+	case tea.KeyEscape:
+		if h.query != "" && !h.fromGrep {
+			h.query = ""
+			m.findMatches()
+			return m, nil
+		}
+		m.closeHistory()
+		return m, nil
+	case tea.KeyUp:
+		return m, m.scrollHistory(-1)
+	case tea.KeyDown:
+		return m, m.scrollHistory(1)
+	case tea.KeyPgUp:
+		return m, m.scrollHistory(-page)
+	case tea.KeyPgDown:
+		return m, m.scrollHistory(page)
+	case tea.KeyHome:
+		return m, m.scrollHistory(-len(h.lines))
+	case tea.KeyEnd:
+		return m, m.scrollHistory(len(h.lines))
+	case tea.KeyLeft:
+		m.previewScrollX = max(m.previewScrollX-4, 0)
+		return m, nil
+	case tea.KeyRight:
+		limit := max(previewMaxWidth(strings.Join(h.lines, "\n"))-m.previewInnerWidth(), 0)
+		m.previewScrollX = min(m.previewScrollX+4, limit)
+		return m, nil
+	}
+
+	switch m.keys.action(msg) {
+	case actQuit:
+		m.closeHistory()
+		return m, nil
+	case actFollow:
+		return m, m.toggleFollow()
+	case actFilter:
+		h.searching = true
+		h.query = ""
+		return m, nil
+	}
+
+	switch msg.Text {
+	case "n", "N":
+		if len(h.matches) > 0 {
+			step := 1
+			if msg.Text == "N" {
+				step = -1
+			}
+			h.match = (h.match + step + len(h.matches)) % len(h.matches)
+			m.jumpToMatch()
+		}
+	}
+	return m, nil
+}
+
+// renderHistory renders the visible history lines. Lines containing a
+// match are shown without their own colors so the match highlight stands
+// out; the current match uses the selection style.
+func (m *Model) renderHistory(height int) string {
+	h := &m.history
+	switch {
+	case h.err != "":
+		return fmt.Sprintf("(history unavailable: %s)", h.err)
+	case h.lines == nil:
+		return helpStyle.Render("Loading history...")
+	}
+	current := -1
+	if len(h.matches) > 0 {
+		current = h.matches[h.match]
+	}
+	isMatch := make(map[int]bool, len(h.matches))
+	for _, i := range h.matches {
+		isMatch[i] = true
+	}
+
+	end := min(h.top+height, len(h.lines))
+	rows := make([]string, 0, end-h.top)
+	for i := h.top; i < end; i++ {
+		line := h.lines[i]
+		if isMatch[i] && h.query != "" {
+			base := lipgloss.NewStyle()
+			if i == current {
+				base = selectedStyle
+			}
+			line = highlightMatch(h.plain[i], h.query, base, filterMatchStyle)
+		}
+		rows = append(rows, line)
+	}
+	return zmx.ScrollPreview(strings.Join(rows, "\n"), m.previewScrollX, m.previewInnerWidth())
+}
+
+// historyTitle describes the position in the history for the pane title.
+func (m *Model) historyTitle() string {
+	h := &m.history
+	if len(h.lines) == 0 {
+		return " history "
+	}
+	end := min(h.top+m.historyPageHeight(), len(h.lines))
+	more := ""
+	if h.more {
+		more = "+"
+	}
+	title := fmt.Sprintf(" history %d-%d/%d%s ", h.top+1, end, len(h.lines), more)
+	if h.query != "" && !h.searching {
+		if len(h.matches) == 0 {
+			title += fmt.Sprintf("/%s no matches ", h.query)
+		} else {
+			title += fmt.Sprintf("/%s %d/%d ", h.query, h.match+1, len(h.matches))
+		}
+	}
+	return title
+}
@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
@@ -13,7 +14,7 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		return m.handleConfirmKey(msg)
 	}
 
-	if isQuit(msg) {
+	if m.keys.is(msg, actQuit) {
 		return m, tea.Quit
 	}
 
@@ -21,23 +22,19 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 
 	// Escape or Backspace clears active filter in normal mode
 	if (msg.Code == tea.KeyEscape || msg.Code == tea.KeyBackspace) && m.filterText != "" {
-		m.filterText = ""
-		m.markVisibleChanged()
+		m.setFilterText("")
 		m.cursor = 0
 		m.listOffset = 0
 		return m, m.previewCmd()
 	}
 
-	visible := m.visibleSessions()
+	row, onRow := m.cursorRow()
 
-	// Ctrl+A toggles select all
-	if msg.Code == 'a' && msg.Mod.Contains(tea.ModCtrl) {
-		m.toggleSelectAll(visible)
-		return m, nil
-	}
+	switch m.keys.action(msg) {
+	case actSelectAll:
+		m.toggleSelectAll(m.visibleSessions())
 
-	switch msg.Code {
-	case tea.KeyUp:
+	case actUp:
 		if m.cursor > 0 {
 			m.cursor--
 			m.previewScrollX = 0
@@ -45,15 +42,15 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 			return m, m.previewCmd()
 		}
 
-	case tea.KeyDown:
-		if m.cursor < len(visible)-1 {
+	case actDown:
+		if m.cursor < len(m.listRows())-1 {
 			m.cursor++
 			m.previewScrollX = 0
 			m.ensureVisible()
 			return m, m.previewCmd()
 		}
 
-	case tea.KeyLeft:
+	case actScrollLeft:
 		if m.previewScrollX > 0 {
 			m.previewScrollX -= 4
 			if m.previewScrollX < 0 {
@@ -61,7 +58,7 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-	case tea.KeyRight:
+	case actScrollRight:
 		maxW := previewMaxWidth(m.preview)
 		limit := maxW - m.previewInnerWidth()
 		if limit < 0 {
@@ -73,60 +70,93 @@ func (m Model) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 			m.previewScrollX = limit
 		}
 
-	case tea.KeySpace:
-		if m.cursor < len(visible) {
-			name := visible[m.cursor].Name
-			if m.selected[name] {
-				delete(m.selected, name)
-			} else {
-				m.selected[name] = true
-			}
+	case actSelect:
+		switch {
+		case !onRow:
+		case row.group != nil:
+			m.toggleSelectAll(row.group.sessions)
+		case m.selected[row.session.Key()]:
+			delete(m.selected, row.session.Key())
+		default:
+			m.selected[row.session.Key()] = true
 		}
 
-	case tea.KeyEnter:
-		if m.cursor < len(visible) {
-			m.attachTarget = visible[m.cursor].Name
-			return m, tea.Quit
+	case actAttach:
+		if onRow && row.group != nil {
+			m.toggleCollapsed(row.group)
+			return m, m.previewCmd()
+		}
+		if m.pickMode {
+			m.picked = m.targets()
+			if len(m.picked) > 0 {
+				return m, tea.Quit
+			}
+		} else if onRow && !row.session.Degraded() {
+			return m, m.attach(row.session, "", nil)
 		}
 
-	default:
-		if msg.Text != "" {
-			switch msg.Text {
-			case "k":
-				targets := m.killTargets()
-				if len(targets) > 0 {
-					m.state = stateConfirmKill
-				}
-			case "c":
-				if m.cursor < len(visible) {
-					name := visible[m.cursor].Name
-					text := fmt.Sprintf("zmx attach %s", name)
-					if err := zmx.CopyToClipboard(text); err != nil {
-						m.status = fmt.Sprintf("Copy failed: %v", err)
-						m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ Copy failed: %v", err)))
-					} else {
-						m.status = "Copied!"
-						m.addLog(statusStyle.Render(fmt.Sprintf("  Copied: %s", text)))
-					}
-					return m, clearStatusAfter(2 * time.Second)
-				}
-			case "r":
-				return m, fetchSessionsCmd
-			case "/":
-				m.state = stateFilter
-			case "s":
-				if m.sortAsc {
-					m.sortAsc = false
-				} else {
-					m.sortAsc = true
-					m.sortMode = (m.sortMode + 1) % sortModeCount
-				}
-				m.markVisibleChanged()
-				m.cursor = 0
-				m.listOffset = 0
-				return m, m.previewCmd()
+	case actKill:
+		if len(m.targets()) > 0 {
+			m.state = stateConfirmKill
+			m.killConfirm = ""
+		}
+	case actProtect:
+		m.toggleProtection()
+	case actTags:
+		m.openMetaEditor(metaFieldTags)
+	case actAlias:
+		m.openMetaEditor(metaFieldAlias)
+	case actNote:
+		m.openMetaEditor(metaFieldNote)
+	case actCopy:
+		if s, ok := m.cursorSession(); ok && !s.Degraded() {
+			text := strings.Join(zmx.AttachArgs(s, nil), " ")
+			if err := zmx.CopyToClipboard(text); err != nil {
+				m.status = fmt.Sprintf("Copy failed: %v", err)
+				m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ Copy failed: %v", err)))
+			} else {
+				m.status = "Copied!"
+				m.addLog(statusStyle.Render(fmt.Sprintf("  Copied: %s", text)))
 			}
+			return m, clearStatusAfter(2 * time.Second)
 		}
+	case actNew:
+		if !m.pickMode {
+			m.openNewSessionForm()
+		}
+	case actHost:
+		if len(zmx.RemoteHosts()) > 0 {
+			m.cycleHostFilter()
+			return m, m.previewCmd()
+		}
+	case actRefresh:
+		return m, fetchSessionsCmd
+	case actGroup:
+		m.cycleGroupMode()
+		return m, m.previewCmd()
+	case actHistory:
+		return m, m.openHistory()
+	case actGrep:
+		m.openGrep()
+	case actExport:
+		m.openExportForm()
+	case actFollow:
+		return m, m.toggleFollow()
+	case actSend:
+		m.openSendPrompt()
+	case actFilter:
+		m.state = stateFilter
+	case actSort:
+		if m.sortAsc {
+			m.sortAsc = false
+		} else {
+			m.sortAsc = true
+			m.sortMode = (m.sortMode + 1) % sortModeCount
+		}
+		key := m.cursorKey()
+		m.markVisibleChanged()
+		m.moveCursorTo(key)
+		return m, nil
 	}
 
 	return m, nil
@@ -138,18 +168,18 @@ func (m *Model) toggleSelectAll(visible []Session) {
 	}
 	allSelected := true
 	for _, s := range visible {
-		if !m.selected[s.Name] {
+		if !m.selected[s.Key()] {
 			allSelected = false
 			break
 		}
 	}
 	if allSelected {
 		for _, s := range visible {
-			delete(m.selected, s.Name)
+			delete(m.selected, s.Key())
 		}
 	} else {
 		for _, s := range visible {
-			m.selected[s.Name] = true
+			m.selected[s.Key()] = true
 		}
 	}
 }
@@ -159,7 +189,7 @@ func (m *Model) pruneSelections() {
 	visible := m.visibleSessions()
 	allowed := make(map[string]bool, len(visible))
 	for _, s := range visible {
-		allowed[s.Name] = true
+		allowed[s.Key()] = true
 	}
 	for name := range m.selected {
 		if !allowed[name] {
@@ -169,28 +199,38 @@ func (m *Model) pruneSelections() {
 }
 
 func (m Model) handleFilterKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
-	if isQuit(msg) {
+	if m.keys.is(msg, actQuit) {
 		return m, tea.Quit
 	}
 
 	switch msg.Code {
 	case tea.KeyEscape:
-		m.filterText = ""
-		m.markVisibleChanged()
+		m.setFilterText("")
 		m.state = stateNormal
 		m.cursor = 0
 		m.listOffset = 0
 		return m, m.previewCmd()
 
 	case tea.KeyEnter:
+		if m.filterErr != nil {
+			// Stay in filter mode so that the error stays visible.
+			return m, nil
+		}
 		m.state = stateNormal
 		m.clampCursor()
 		return m, m.previewCmd()
 
+	case tea.KeyTab:
+		m.fuzzy = !m.fuzzy
+		m.setFilterText(m.filterText)
+		m.pruneSelections()
+		m.cursor = 0
+		m.listOffset = 0
+		return m, m.previewCmd()
+
 	case tea.KeyBackspace:
 		if len(m.filterText) > 0 {
-			m.filterText = m.filterText[:len(m.filterText)-1]
-			m.markVisibleChanged()
+			m.setFilterText(m.filterText[:len(m.filterText)-1])
 			m.pruneSelections()
 			m.cursor = 0
 			m.listOffset = 0
@@ -208,8 +248,7 @@ func (m Model) handleFilterKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.KeyDown:
-		visible := m.visibleSessions()
-		if m.cursor < len(visible)-1 {
+		if m.cursor < len(m.listRows())-1 {
 			m.cursor++
 			m.ensureVisible()
 			return m, m.previewCmd()
@@ -217,8 +256,7 @@ func (m Model) handleFilterKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 
 	default:
 		if msg.Text != "" {
-			m.filterText += msg.Text
-			m.markVisibleChanged()
+			m.setFilterText(m.filterText + msg.Text)
 			m.pruneSelections()
 			m.cursor = 0
 			m.listOffset = 0
@@ -229,7 +267,13 @@ func (m Model) handleFilterKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleConfirmKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
-	if isQuit(msg) {
+	if key := m.typedKillTarget(); key != "" {
+		if msg.Code == 'c' && msg.Mod.Contains(tea.ModCtrl) {
+			return m, tea.Quit
+		}
+		return m.handleTypedKillKey(msg, key)
+	}
+	if m.keys.is(msg, actQuit) {
 		return m, tea.Quit
 	}
 	if msg.Code == tea.KeyEscape || msg.Code == tea.KeyBackspace {
@@ -237,18 +281,12 @@ func (m Model) handleConfirmKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 	if isRune(msg, "y") {
-		targets := m.killTargets()
-		total := len(targets)
-		m.state = stateKilling
-		m.killDoneNames = nil
-
-		m.addLog(titleStyle.Render(fmt.Sprintf("Killing %d session(s)...", total)))
-
-		first := targets[0]
-		m.killQueue = targets[1:]
-		m.killNow = first
-		m.addLog(helpStyle.Render("  ⋯ " + first))
-		return m, killOneCmd(first)
+		targets := m.sessionsByKey(m.killTargets())
+		if len(targets) == 0 {
+			m.state = stateNormal
+			return m, nil
+		}
+		return m, m.startKill(targets)
 	}
 	if isRune(msg, "n") {
 		m.state = stateNormal
@@ -257,17 +295,18 @@ func (m Model) handleConfirmKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) handleLogScroll(msg tea.KeyPressMsg) {
-	if !isRune(msg, "[") && !isRune(msg, "]") {
+	action := m.keys.action(msg)
+	if action != actLogUp && action != actLogDown {
 		return
 	}
-	maxOffset := len(m.logLines) - logContentHeight
+	maxOffset := len(m.logLines) - m.logHeight
 	if maxOffset < 0 {
 		maxOffset = 0
 	}
-	if isRune(msg, "[") && m.logOffset > 0 {
+	if action == actLogUp && m.logOffset > 0 {
 		m.logOffset--
 	}
-	if isRune(msg, "]") && m.logOffset < maxOffset {
+	if action == actLogDown && m.logOffset < maxOffset {
 		m.logOffset++
 	}
 }
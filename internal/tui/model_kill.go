@@ -0,0 +1,160 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+// killStageMsg reports one stage of a graceful kill (see zmx.KillStage).
+type killStageMsg struct {
+	session Session
+	stage   zmx.KillStage
+	gone    bool
+	err     error
+}
+
+func killStageCmd(s Session, stage zmx.KillStage, grace time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		gone, err := zmx.KillStep(s, stage, grace)
+		return killStageMsg{session: s, stage: stage, gone: gone, err: err}
+	}
+}
+
+// startKill kills targets through a pool of killConcurrency workers,
+// escalating through the kill stages for each until it is gone.
+func (m *Model) startKill(targets []Session) tea.Cmd {
+	m.state = stateKilling
+	m.killDoneNames = nil
+	m.killSurvived = nil
+	m.killUntouched = nil
+	m.killQueue = targets
+	m.killActive = make(map[string]zmx.KillStage)
+	m.killTotal = len(targets)
+	m.addLog(titleStyle.Render(fmt.Sprintf("Killing %d session(s)...", len(targets))))
+	var cmds []tea.Cmd
+	for range min(max(m.killConcurrency, 1), len(targets)) {
+		cmds = append(cmds, m.nextKill())
+	}
+	return tea.Batch(cmds...)
+}
+
+// nextKill starts on the next queued session. Once the queue is empty and
+// no kill is in flight, it finishes.
+func (m *Model) nextKill() tea.Cmd {
+	if len(m.killQueue) == 0 {
+		if len(m.killActive) == 0 {
+			return m.finishKill()
+		}
+		return nil
+	}
+	next := m.killQueue[0]
+	m.killQueue = m.killQueue[1:]
+	m.killActive[next.Key()] = 0
+	m.addLog(helpStyle.Render("  ⋯ " + next.Key()))
+	return killStageCmd(next, 0, m.killGrace)
+}
+
+// cancelKill stops dequeuing sessions. Kills in flight run to completion.
+func (m *Model) cancelKill() tea.Cmd {
+	if len(m.killQueue) > 0 {
+		for _, s := range m.killQueue {
+			m.killUntouched = append(m.killUntouched, s.Key())
+		}
+		m.killQueue = nil
+		m.addLog(confirmStyle.Render(fmt.Sprintf("  Cancelled; left %d session(s) untouched: %s", len(m.killUntouched), strings.Join(m.killUntouched, ", "))))
+	}
+	if len(m.killActive) == 0 {
+		return m.finishKill()
+	}
+	m.addLog(logDimStyle.Render(fmt.Sprintf("  Waiting for %d kill(s) in progress...", len(m.killActive))))
+	return nil
+}
+
+// applyKillStage logs a session once a stage has removed it, or once it
+// has survived every stage, and otherwise escalates to the next stage.
+func (m *Model) applyKillStage(msg killStageMsg) tea.Cmd {
+	key := msg.session.Key()
+	label := msg.stage.Label(msg.session)
+	switch {
+	case msg.gone:
+		m.addLog(statusStyle.Render(fmt.Sprintf("  ✓ %s (%s)", key, label)))
+		m.killDoneNames = append(m.killDoneNames, key)
+	case msg.stage+1 < zmx.KillStageCount:
+		if msg.err != nil {
+			m.addLog(logDimStyle.Render(fmt.Sprintf("    %s: %s", label, firstLine(msg.err))))
+		}
+		m.killActive[key] = msg.stage + 1
+		return killStageCmd(msg.session, msg.stage+1, m.killGrace)
+	default:
+		reason := "survived every stage"
+		if msg.err != nil {
+			reason = firstLine(msg.err)
+		}
+		m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ %s: %s", key, reason)))
+		m.killSurvived = append(m.killSurvived, key)
+	}
+	delete(m.killActive, key)
+	return m.nextKill()
+}
+
+// killTitle is the log pane title while killing: a progress bar and, with
+// a single kill in flight, its current stage.
+func (m *Model) killTitle() string {
+	done := len(m.killDoneNames) + len(m.killSurvived)
+	title := fmt.Sprintf(" Killing %s %d/%d ", progressBar(done, m.killTotal, 20), done, m.killTotal)
+	if len(m.killActive) == 1 {
+		for key, stage := range m.killActive {
+			if sessions := m.sessionsByKey([]string{key}); len(sessions) > 0 {
+				title += fmt.Sprintf("· %s (%s) ", key, stage.Label(sessions[0]))
+			}
+		}
+	}
+	return title
+}
+
+// progressBar renders done out of total as a bar of width cells.
+func progressBar(done, total, width int) string {
+	filled := width
+	if total > 0 {
+		filled = done * width / total
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+func (m *Model) finishKill() tea.Cmd {
+	killed := len(m.killDoneNames)
+	m.addLog(statusStyle.Render(fmt.Sprintf("  Done. Killed %d session(s).", killed)))
+	if len(m.killSurvived) > 0 {
+		m.addLog(confirmStyle.Render(fmt.Sprintf("  %d session(s) survived: %s", len(m.killSurvived), strings.Join(m.killSurvived, ", "))))
+	}
+	m.state = stateNormal
+	// Keep survivors and untouched sessions selected so that the kill can
+	// be retried or resumed.
+	m.selected = make(map[string]bool)
+	for _, name := range slices.Concat(m.killSurvived, m.killUntouched) {
+		m.selected[name] = true
+	}
+	m.setFilterText("")
+	// Drop the killed sessions now rather than logging them again as
+	// departures on the next listing. The cursor keeps its position.
+	gone := make(map[string]bool, killed)
+	for _, name := range m.killDoneNames {
+		gone[name] = true
+	}
+	m.sessions = slices.DeleteFunc(m.sessions, func(s Session) bool {
+		return gone[s.Key()]
+	})
+	m.markSessionsChanged()
+	m.clampCursor()
+	m.killQueue = nil
+	m.killDoneNames = nil
+	m.killSurvived = nil
+	m.killUntouched = nil
+	m.killActive = nil
+	return tea.Batch(fetchSessionsCmd, clearStatusAfter(3*time.Second))
+}
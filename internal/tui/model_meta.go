@@ -0,0 +1,238 @@
+package tui
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"slices"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+const (
+	metaFieldAlias = iota
+	metaFieldTags
+	metaFieldNote
+	metaFieldCount
+)
+
+var metaFieldLabels = [metaFieldCount]string{"Alias", "Tags", "Note"}
+
+// noteHeaderMaxLines caps how much of the preview pane a note may take.
+const noteHeaderMaxLines = 5
+
+// metaEditor holds the state of the alias, tags and note editor.
+type metaEditor struct {
+	keys   []string // sessions being edited; only tags can be edited for several
+	common []string // tags all of them had when the editor opened
+	fields [metaFieldCount]string
+	focus  int
+	err    string
+}
+
+func (e *metaEditor) multi() bool {
+	return len(e.keys) > 1
+}
+
+// openMetaEditor edits the metadata of the selected sessions, or the cursor
+// session, starting at field. Aliases and notes belong to one session, so
+// with several selected only their tags are edited: tags removed from the
+// field are removed from all of them, and tags added are added to all.
+func (m *Model) openMetaEditor(field int) {
+	var sessions []Session
+	for _, s := range m.sessionsByKey(m.targets()) {
+		if !s.Degraded() {
+			sessions = append(sessions, s)
+		}
+	}
+	if len(sessions) == 0 {
+		return
+	}
+	e := metaEditor{focus: field}
+	e.common = slices.Clone(sessions[0].Tags)
+	for _, s := range sessions {
+		e.keys = append(e.keys, s.Key())
+		e.common = slices.DeleteFunc(e.common, func(tag string) bool {
+			return !slices.Contains(s.Tags, tag)
+		})
+	}
+	e.fields[metaFieldTags] = strings.Join(e.common, " ")
+	if e.multi() {
+		e.focus = metaFieldTags
+	} else {
+		e.fields[metaFieldAlias] = sessions[0].Alias
+		e.fields[metaFieldNote] = sessions[0].Note
+	}
+	m.metaEdit = e
+	m.state = stateEditMeta
+}
+
+func (m Model) handleMetaKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	if msg.Code == 'c' && msg.Mod.Contains(tea.ModCtrl) {
+		return m, tea.Quit
+	}
+	e := &m.metaEdit
+
+	switch {
+	case msg.Code == tea.KeyEscape:
+		m.state = stateNormal
+		return m, nil
+
+	case msg.Code == tea.KeyTab && msg.Mod.Contains(tea.ModShift):
+		if !e.multi() {
+			e.focus = (e.focus + metaFieldCount - 1) % metaFieldCount
+		}
+
+	case msg.Code == tea.KeyTab:
+		if !e.multi() {
+			e.focus = (e.focus + 1) % metaFieldCount
+		}
+
+	case msg.Code == tea.KeyBackspace:
+		r := []rune(e.fields[e.focus])
+		if len(r) > 0 {
+			e.fields[e.focus] = string(r[:len(r)-1])
+		}
+
+	case msg.Code == tea.KeyEnter && e.focus == metaFieldNote:
+		e.fields[e.focus] += "\n"
+
+	case msg.Code == tea.KeyEnter, msg.Code == 's' && msg.Mod.Contains(tea.ModCtrl):
+		if err := m.saveMeta(); err != nil {
+			e.err = err.Error()
+			return m, nil
+		}
+		m.state = stateNormal
+		return m, m.previewCmd()
+
+	default:
+		if msg.Text != "" {
+			e.fields[e.focus] += msg.Text
+		}
+	}
+	e.err = ""
+	return m, nil
+}
+
+// saveMeta stores the edited metadata and shows it in the list.
+func (m *Model) saveMeta() error {
+	e := m.metaEdit
+	tags := zmx.ParseTags(e.fields[metaFieldTags])
+	for _, s := range m.sessionsByKey(e.keys) {
+		meta := m.meta.Get(s.Key())
+		kept := slices.DeleteFunc(slices.Clone(meta.Tags), func(tag string) bool {
+			return slices.Contains(e.common, tag)
+		})
+		meta.Tags = append(kept, tags...)
+		if !e.multi() {
+			meta.Alias = e.fields[metaFieldAlias]
+			meta.Note = e.fields[metaFieldNote]
+		}
+		if err := m.meta.Set(s, meta); err != nil {
+			return fmt.Errorf("saving metadata: %w", err)
+		}
+	}
+	m.meta.Apply(m.sessions)
+	m.markSessionsChanged()
+	m.addLog(statusStyle.Render("  ✓ Updated " + targetLabel(e.keys)))
+	return nil
+}
+
+// pruneMeta forgets the metadata of sessions that no longer exist. It must
+// only be called with a complete listing.
+func (m *Model) pruneMeta() {
+	removed, err := m.meta.Prune(m.sessions)
+	if err != nil {
+		m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ Saving metadata: %v", err)))
+		return
+	}
+	if len(removed) > 0 {
+		m.addLog(logDimStyle.Render("  Forgot the notes and tags of " + strings.Join(removed, ", ")))
+	}
+}
+
+func (m Model) renderMetaEditor() string {
+	e := m.metaEdit
+	var b strings.Builder
+	b.WriteString("\n")
+	for i, label := range metaFieldLabels {
+		if e.multi() && i != metaFieldTags {
+			continue
+		}
+		marker := "  "
+		value := e.fields[i]
+		if i == e.focus {
+			marker = selectedStyle.Render("▸ ")
+			value += "█"
+		}
+		lines := strings.Split(value, "\n")
+		b.WriteString(fmt.Sprintf("%s%s %s\n", marker, helpStyle.Render(padRight(label+":", 7)), helpKeyStyle.Render(lines[0])))
+		for _, line := range lines[1:] {
+			b.WriteString(strings.Repeat(" ", 10) + helpKeyStyle.Render(line) + "\n")
+		}
+	}
+	b.WriteString("\n")
+	switch {
+	case e.err != "":
+		b.WriteString(confirmStyle.Render("  " + e.err))
+	case e.multi():
+		b.WriteString(logDimStyle.Render(fmt.Sprintf("  Tags shared by %d sessions; separate them with spaces.", len(e.keys))))
+	default:
+		b.WriteString(logDimStyle.Render("  Separate tags with spaces. Enter in the note starts a new line."))
+	}
+	return b.String()
+}
+
+// tagChipColors are the backgrounds tag chips are drawn with, set by
+// applyColors; each tag always gets the same one.
+var tagChipColors []color.Color
+
+func tagChipStyle(tag string) lipgloss.Style {
+	h := fnv.New32a()
+	h.Write([]byte(tag))
+	return tagChipBaseStyle.Background(tagChipColors[h.Sum32()%uint32(len(tagChipColors))])
+}
+
+// tagChips renders as many of tags as fit in width, each preceded by a
+// space, and returns them along with their width.
+func tagChips(tags []string, width int) (string, int) {
+	var b strings.Builder
+	w := 0
+	for _, tag := range tags {
+		cw := 1 + runewidth.StringWidth(tag) + 2
+		if w+cw > width {
+			break
+		}
+		b.WriteString(" " + tagChipStyle(tag).Render(" "+tag+" "))
+		w += cw
+	}
+	return b.String(), w
+}
+
+// tagChipsWidth is the width of all of tags' chips.
+func tagChipsWidth(tags []string) int {
+	w := 0
+	for _, tag := range tags {
+		w += 1 + runewidth.StringWidth(tag) + 2
+	}
+	return w
+}
+
+// renderNoteHeader renders the start of a session's note above its preview,
+// followed by a rule.
+func renderNoteHeader(note string, width int) string {
+	lines := strings.Split(note, "\n")
+	if len(lines) > noteHeaderMaxLines {
+		lines = append(lines[:noteHeaderMaxLines-1], "…")
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(noteStyle.Render(truncate(" "+line, width)) + "\n")
+	}
+	b.WriteString(borderCharStyle.Render(strings.Repeat("─", max(width, 0))))
+	return b.String()
+}
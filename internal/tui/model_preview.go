@@ -0,0 +1,180 @@
+package tui
+
+import (
+	"context"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+// Preview fetching is debounced by previewDebounce so that holding an arrow
+// key does not start a `zmx history` per row. Fetched previews are cached
+// for previewCacheTTL, keeping at most previewCacheSize sessions.
+const (
+	previewDebounce  = 80 * time.Millisecond
+	previewCacheTTL  = 3 * time.Second
+	previewCacheSize = 32
+)
+
+// previewEntry is a cached preview, valid only for the pane size it was
+// rendered at.
+type previewEntry struct {
+	content       string
+	width, height int
+	fetched       time.Time
+	used          uint64 // previewCache.clock at the last hit, for LRU eviction
+}
+
+// previewCache tracks cached previews and in-flight fetches. It is shared
+// by all copies of a Model and only touched from Update.
+type previewCache struct {
+	entries  map[string]*previewEntry
+	inflight map[string]context.CancelFunc
+	clock    uint64
+	seq      int // bumped on each request; a debounce tick fires only if unchanged
+}
+
+func newPreviewCache() *previewCache {
+	return &previewCache{
+		entries:  make(map[string]*previewEntry),
+		inflight: make(map[string]context.CancelFunc),
+	}
+}
+
+// get returns the cached preview for key if it is fresh and was rendered
+// at width×height.
+func (c *previewCache) get(key string, width, height int, now time.Time) (string, bool) {
+	e, ok := c.entries[key]
+	if !ok || e.width != width || e.height != height || now.Sub(e.fetched) > previewCacheTTL {
+		return "", false
+	}
+	c.clock++
+	e.used = c.clock
+	return e.content, true
+}
+
+// put caches a preview, evicting the least recently used entry when full.
+func (c *previewCache) put(key, content string, width, height int, now time.Time) {
+	c.clock++
+	c.entries[key] = &previewEntry{content: content, width: width, height: height, fetched: now, used: c.clock}
+	if len(c.entries) <= previewCacheSize {
+		return
+	}
+	oldest := ""
+	for k, e := range c.entries {
+		if oldest == "" || e.used < c.entries[oldest].used {
+			oldest = k
+		}
+	}
+	delete(c.entries, oldest)
+}
+
+// cancelExcept cancels in-flight fetches for sessions not in keep.
+func (c *previewCache) cancelExcept(keep map[string]bool) {
+	for k, cancel := range c.inflight {
+		if !keep[k] {
+			cancel()
+			delete(c.inflight, k)
+		}
+	}
+}
+
+// previewDueMsg fires when the debounce period after a preview request has
+// passed.
+type previewDueMsg struct {
+	seq int
+}
+
+// previewTargets returns the cursor session followed by its neighbours,
+// which are prefetched. It returns nothing while the cursor is on a group
+// header.
+func (m *Model) previewTargets() []Session {
+	s, ok := m.cursorSession()
+	if !ok {
+		return nil
+	}
+	rows := m.listRows()
+	targets := []Session{s}
+	for _, i := range []int{m.cursor + 1, m.cursor - 1} {
+		if i >= 0 && i < len(rows) && rows[i].group == nil && !rows[i].session.Degraded() {
+			targets = append(targets, rows[i].session)
+		}
+	}
+	return targets
+}
+
+// previewCmd shows the cursor session's preview: from the cache when
+// fresh, otherwise by fetching it (and prefetching its neighbours) once
+// the cursor has rested for previewDebounce. Fetches for sessions that are
+// no longer wanted are cancelled.
+func (m *Model) previewCmd() tea.Cmd {
+	targets := m.previewTargets()
+	if len(targets) == 0 {
+		return nil
+	}
+	c := m.previews
+	c.seq++
+	keep := make(map[string]bool, len(targets))
+	for _, s := range targets {
+		keep[s.Key()] = true
+	}
+	c.cancelExcept(keep)
+
+	seq := c.seq
+	due := tea.Tick(previewDebounce, func(time.Time) tea.Msg { return previewDueMsg{seq: seq} })
+	key := targets[0].Key()
+	if content, ok := c.get(key, m.previewInnerWidth(), m.mainContentHeight(1), time.Now()); ok {
+		return tea.Batch(func() tea.Msg { return previewMsg{name: key, content: content, cached: true} }, due)
+	}
+	return due
+}
+
+// fetchDuePreviews starts fetches for the cursor session and its
+// neighbours unless they are cached or already being fetched.
+func (m *Model) fetchDuePreviews() tea.Cmd {
+	c := m.previews
+	width, height := m.previewInnerWidth(), m.mainContentHeight(1)
+	now := time.Now()
+	var cmds []tea.Cmd
+	for _, s := range m.previewTargets() {
+		key := s.Key()
+		if _, busy := c.inflight[key]; busy {
+			continue
+		}
+		if _, ok := c.get(key, width, height, now); ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		c.inflight[key] = cancel
+		cmds = append(cmds, fetchPreviewCmd(ctx, s, width, height))
+	}
+	return tea.Batch(cmds...)
+}
+
+func fetchPreviewCmd(ctx context.Context, s Session, width, height int) tea.Cmd {
+	return func() tea.Msg {
+		content := zmx.FetchPreviewContext(ctx, s, width, height)
+		if ctx.Err() != nil {
+			return nil // cancelled: the cursor moved on
+		}
+		return previewMsg{name: s.Key(), content: content, width: width, height: height}
+	}
+}
+
+// applyPreview caches a fetched preview and shows it if its session is
+// still under the cursor.
+func (m *Model) applyPreview(msg previewMsg) {
+	if !msg.cached {
+		if cancel, ok := m.previews.inflight[msg.name]; ok {
+			cancel()
+			delete(m.previews.inflight, msg.name)
+		}
+		m.previews.put(msg.name, msg.content, msg.width, msg.height, time.Now())
+	}
+	if m.cursorKey() == msg.name {
+		m.noteFollowedPreview(msg)
+		m.preview = msg.content
+		m.previewKey = msg.name
+	}
+}
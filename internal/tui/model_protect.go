@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// isProtected reports whether the session with the given key is protected
+// from kills.
+func (m *Model) isProtected(key string) bool {
+	sessions := m.sessionsByKey([]string{key})
+	return len(sessions) > 0 && m.protection.Protected(sessions[0])
+}
+
+// protectedTargets returns the targets (see targets) that kills skip.
+func (m *Model) protectedTargets() []string {
+	return slices.DeleteFunc(m.targets(), func(key string) bool {
+		return !m.isProtected(key)
+	})
+}
+
+// typedKillTarget returns the protected session that the confirm prompt
+// asks the user to type the name of: the only target, when it is
+// protected. Otherwise it returns "".
+func (m *Model) typedKillTarget() string {
+	if targets := m.targets(); len(targets) == 1 && m.isProtected(targets[0]) {
+		return targets[0]
+	}
+	return ""
+}
+
+// toggleProtection protects the targets, or unprotects them if they are all
+// protected already. Sessions matching a protect pattern stay protected.
+func (m *Model) toggleProtection() {
+	targets := m.sessionsByKey(m.targets())
+	if len(targets) == 0 {
+		return
+	}
+	on := slices.ContainsFunc(targets, func(s Session) bool {
+		return !m.protection.Protected(s)
+	})
+	var keys, pinned []string
+	for _, s := range targets {
+		if !on && m.protection.Pinned(s) {
+			pinned = append(pinned, s.Key())
+			continue
+		}
+		keys = append(keys, s.Key())
+	}
+	if err := m.protection.SetMarked(keys, on); err != nil {
+		m.addLog(confirmStyle.Render("  ✗ Saving protected sessions failed: " + firstLine(err)))
+	}
+	if len(keys) > 0 {
+		verb := "Unprotected"
+		if on {
+			verb = "Protected"
+		}
+		m.addLog(statusStyle.Render(fmt.Sprintf("  %s %s", verb, strings.Join(keys, ", "))))
+	}
+	if len(pinned) > 0 {
+		m.addLog(logDimStyle.Render(fmt.Sprintf("  %s stay protected by a protect pattern in the config file", strings.Join(pinned, ", "))))
+	}
+}
+
+// pruneProtection forgets the marks of sessions that no longer exist. Like
+// pruneMeta, it must only be called with a complete listing.
+func (m *Model) pruneProtection() {
+	removed, err := m.protection.Prune(m.sessions)
+	if err != nil {
+		m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ Saving protected sessions: %v", err)))
+		return
+	}
+	if len(removed) > 0 {
+		m.addLog(logDimStyle.Render("  Forgot the protection of " + strings.Join(removed, ", ")))
+	}
+}
+
+// handleTypedKillKey edits the name typed to confirm killing a protected
+// session, and starts the kill once it matches the session's name (without
+// its backend or host).
+func (m Model) handleTypedKillKey(msg tea.KeyPressMsg, key string) (tea.Model, tea.Cmd) {
+	switch msg.Code {
+	case tea.KeyEscape:
+		m.state = stateNormal
+		m.killConfirm = ""
+	case tea.KeyBackspace:
+		if r := []rune(m.killConfirm); len(r) > 0 {
+			m.killConfirm = string(r[:len(r)-1])
+		}
+	case tea.KeyEnter:
+		targets := m.sessionsByKey([]string{key})
+		if len(targets) == 0 {
+			m.state = stateNormal
+			m.killConfirm = ""
+			return m, nil
+		}
+		if m.killConfirm != targets[0].Name {
+			m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ Typed name does not match %s; not killed", key)))
+			m.state = stateNormal
+			m.killConfirm = ""
+			return m, nil
+		}
+		m.killConfirm = ""
+		m.addLog(confirmStyle.Render("  Killing protected session " + key))
+		return m, m.startKill(targets)
+	default:
+		m.killConfirm += msg.Text
+	}
+	return m, nil
+}
+
+// renderKillConfirm is the help bar while confirming a kill. It explains
+// which targets are protected and asks for the name of a lone protected
+// target.
+func (m *Model) renderKillConfirm() string {
+	if key := m.typedKillTarget(); key != "" {
+		prompt := fmt.Sprintf(" %s is protected. Type its name to kill it: ", key)
+		if s := m.sessionsByKey([]string{key}); len(s) > 0 && s[0].Name != key {
+			prompt = fmt.Sprintf(" %s is protected. Type its name, %s, to kill it: ", key, s[0].Name)
+		}
+		return confirmStyle.Render(prompt) +
+			helpKeyStyle.Render(m.killConfirm) + helpStyle.Render("█  Enter kill | Esc cancel")
+	}
+	targets := m.killTargets()
+	protected := m.protectedTargets()
+	var prompt string
+	switch len(targets) {
+	case 0:
+		return confirmStyle.Render(fmt.Sprintf(" All %d sessions are protected; kill them one at a time. ", len(protected))) +
+			helpStyle.Render("Esc cancel")
+	case 1:
+		prompt = fmt.Sprintf(" Kill %s? y/n ", targets[0])
+	default:
+		prompt = fmt.Sprintf(" Kill %d sessions? y/n ", len(targets))
+	}
+	if len(protected) > 0 {
+		return confirmStyle.Render(prompt) + helpStyle.Render(fmt.Sprintf(" skipping %d protected: %s", len(protected), strings.Join(protected, ", ")))
+	}
+	return confirmStyle.Render(prompt)
+}
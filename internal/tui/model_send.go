@@ -0,0 +1,150 @@
+package tui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
+)
+
+// sendSettle is how long to wait after sending before refreshing the
+// preview, so that the session has had time to react.
+const sendSettle = 300 * time.Millisecond
+
+// sendPrompt holds the state of the send prompt.
+type sendPrompt struct {
+	targets []string // session keys to send to
+	keys    bool     // input is named keys rather than a line of text
+	text    string
+	err     string
+}
+
+// sendResult reports the delivery to one session.
+type sendResult struct {
+	name string
+	err  error
+}
+
+type sendDoneMsg struct {
+	input   zmx.Input
+	results []sendResult
+}
+
+type sendSettledMsg struct{}
+
+func sendCmd(sessions []Session, in zmx.Input) tea.Cmd {
+	return func() tea.Msg {
+		results := make([]sendResult, len(sessions))
+		var wg sync.WaitGroup
+		for i, s := range sessions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = sendResult{name: s.Key(), err: zmx.SendInput(s, in)}
+			}()
+		}
+		wg.Wait()
+		return sendDoneMsg{input: in, results: results}
+	}
+}
+
+// openSendPrompt prompts for input to send to the selected sessions, or
+// the cursor session.
+func (m *Model) openSendPrompt() {
+	var targets []string
+	for _, s := range m.sessionsByKey(m.targets()) {
+		if !s.Degraded() {
+			targets = append(targets, s.Key())
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	m.send = sendPrompt{targets: targets}
+	m.state = stateSend
+}
+
+func (m Model) handleSendKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	if msg.Code == 'c' && msg.Mod.Contains(tea.ModCtrl) {
+		return m, tea.Quit
+	}
+	p := &m.send
+
+	switch msg.Code {
+	case tea.KeyEscape:
+		m.state = stateNormal
+		return m, nil
+	case tea.KeyTab:
+		if !p.keys && !m.sendsKeys() {
+			p.err = "keys cannot be sent to zmx sessions: zmx run submits all input with Enter"
+			return m, nil
+		}
+		p.keys = !p.keys
+	case tea.KeyBackspace:
+		if r := []rune(p.text); len(r) > 0 {
+			p.text = string(r[:len(r)-1])
+		}
+	case tea.KeyEnter:
+		in := zmx.Input{Text: p.text}
+		if p.keys {
+			keys, err := zmx.ParseKeys(p.text)
+			if err != nil {
+				p.err = err.Error()
+				return m, nil
+			}
+			in = zmx.Input{Keys: keys}
+		}
+		m.state = stateNormal
+		m.addLog(titleStyle.Render(fmt.Sprintf("Sending %s to %s...", in, targetLabel(p.targets))))
+		return m, sendCmd(m.sessionsByKey(p.targets), in)
+	default:
+		if msg.Text != "" {
+			p.text += msg.Text
+		}
+	}
+	p.err = ""
+	return m, nil
+}
+
+// sendsKeys reports whether every send target accepts named keys (see
+// zmx.SendsKeys).
+func (m Model) sendsKeys() bool {
+	for _, s := range m.sessionsByKey(m.send.targets) {
+		if !zmx.SendsKeys(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// applySend logs the result for each session and schedules a preview
+// refresh once the sessions have had time to react.
+func (m *Model) applySend(msg sendDoneMsg) tea.Cmd {
+	for _, r := range msg.results {
+		if r.err != nil {
+			m.addLog(confirmStyle.Render(fmt.Sprintf("  ✗ %s: %s", r.name, firstLine(r.err))))
+			continue
+		}
+		m.addLog(statusStyle.Render("  ✓ " + r.name))
+		delete(m.previews.entries, r.name)
+	}
+	return tea.Tick(sendSettle, func(time.Time) tea.Msg { return sendSettledMsg{} })
+}
+
+func (m Model) renderSendPrompt() string {
+	p := m.send
+	mode, hint := "text", "Enter sends the line | Tab keys"
+	if p.keys {
+		mode, hint = "keys", "e.g. ctrl-c, ctrl-d, enter, esc, up | Tab text"
+	} else if !m.sendsKeys() {
+		hint = "Enter sends the line | zmx sessions take text only"
+	}
+	prompt := helpStyle.Render(fmt.Sprintf(" send %s to %s: ", mode, targetLabel(p.targets))) +
+		helpKeyStyle.Render(p.text) + helpStyle.Render("█  ")
+	if p.err != "" {
+		return prompt + confirmStyle.Render(p.err)
+	}
+	return prompt + helpStyle.Render(hint+" | Esc cancel")
+}
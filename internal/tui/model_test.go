@@ -1,12 +1,17 @@
 package tui
 
 import (
+	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/mdsakalu/zmx-session-manager/internal/config"
+	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
 )
 
 func TestTruncate(t *testing.T) {
@@ -70,6 +75,17 @@ func TestHighlightMatch_ContainsQuery(t *testing.T) {
 	}
 }
 
+func TestHighlightPositionsIsNonContiguous(t *testing.T) {
+	upper := lipgloss.NewStyle().Transform(strings.ToUpper)
+	got := stripStyleCodes(highlightPositions("api-server", []int{0, 1, 2, 4, 6, 7}, lipgloss.NewStyle(), upper))
+	if got != "API-SeRVer" {
+		t.Fatalf("highlightPositions = %q, want API-SeRVer", got)
+	}
+	if got := stripStyleCodes(highlightMatch("my-séance", "SÉ", lipgloss.NewStyle(), upper)); got != "my-SÉance" {
+		t.Fatalf("highlightMatch = %q, want my-SÉance", got)
+	}
+}
+
 func TestPadRight(t *testing.T) {
 	tests := []struct {
 		s     string
@@ -151,14 +167,13 @@ func TestVisibleSessionsInvalidatesAfterFilterAndSortChange(t *testing.T) {
 		t.Fatalf("unexpected initial ordering: %+v", visible)
 	}
 
-	m.filterText = "bet"
-	m.markVisibleChanged()
+	m.setFilterText("bet")
 	visible = m.visibleSessions()
 	if len(visible) != 1 || visible[0].Name != "beta" {
 		t.Fatalf("filter invalidation failed: %+v", visible)
 	}
 
-	m.filterText = ""
+	m.setFilterText("")
 	m.sortAsc = false
 	m.markVisibleChanged()
 	visible = m.visibleSessions()
@@ -167,6 +182,1149 @@ func TestVisibleSessionsInvalidatesAfterFilterAndSortChange(t *testing.T) {
 	}
 }
 
+func TestFilterQueryKeepsLastMatchesWhileInvalid(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20
+	m.sessions = []Session{
+		{Name: "api", Clients: 0, Memory: 2 << 30},
+		{Name: "web", Clients: 1, Memory: 2 << 30},
+		{Name: "tmp", Clients: 0, Memory: 1 << 20},
+	}
+	m.markSessionsChanged()
+	update := func(msg tea.Msg) {
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+	names := func() []string {
+		var out []string
+		for _, s := range m.visibleSessions() {
+			out = append(out, s.Name)
+		}
+		return out
+	}
+
+	update(tea.KeyPressMsg{Code: '/', Text: "/"})
+	for _, r := range "clients:0 (mem:>1G" {
+		update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	if got := names(); !slices.Equal(got, []string{"api", "tmp"}) {
+		t.Fatalf("visible = %v, want the clients:0 matches while the group is open", got)
+	}
+	if help := stripStyleCodes(m.renderHelp()); !strings.Contains(help, `missing ")"`) {
+		t.Fatalf("help = %q, want the parse error", help)
+	}
+	if update(tea.KeyPressMsg{Code: tea.KeyEnter}); m.state != stateFilter {
+		t.Fatal("enter should not accept an invalid filter")
+	}
+	update(tea.KeyPressMsg{Code: ')', Text: ")"})
+	if got := names(); !slices.Equal(got, []string{"api"}) {
+		t.Fatalf("visible = %v, want [api]", got)
+	}
+}
+
+func TestFuzzyFilterRanksAndHighlights(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20
+	m.sessions = []Session{{Name: "apple"}, {Name: "papi-server"}, {Name: "api-server"}}
+	m.markSessionsChanged()
+	update := func(msg tea.Msg) {
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+
+	update(tea.KeyPressMsg{Code: '/', Text: "/"})
+	for _, r := range "apisrv" {
+		update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	if len(m.visibleSessions()) != 0 {
+		t.Fatal("the query filter should not match apisrv")
+	}
+	update(tea.KeyPressMsg{Code: tea.KeyTab})
+	var names []string
+	for _, s := range m.visibleSessions() {
+		names = append(names, s.Name)
+	}
+	if want := []string{"api-server", "papi-server"}; !slices.Equal(names, want) {
+		t.Fatalf("fuzzy ranking = %v, want %v", names, want)
+	}
+	if help := stripStyleCodes(m.renderHelp()); !strings.HasPrefix(help, " ~apisrv█") || !strings.Contains(help, "Tab query") {
+		t.Fatalf("help = %q", help)
+	}
+
+	update(tea.KeyPressMsg{Code: tea.KeyTab})
+	if len(m.visibleSessions()) != 0 || m.fuzzy {
+		t.Fatal("tab should switch back to the query filter")
+	}
+}
+
+func TestPickModeEnterRecordsSelectionInListOrder(t *testing.T) {
+	m := NewModel(Options{Pick: true})
+	m.sessions = []Session{{Name: "gamma"}, {Name: "alpha"}, {Name: "beta"}}
+	m.markSessionsChanged()
+	m.selected["gamma"] = true
+	m.selected["alpha"] = true
+
+	updated, cmd := m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	got := updated.(Model)
+	if want := []string{"alpha", "gamma"}; !slices.Equal(got.Picked(), want) {
+		t.Fatalf("Picked() = %v, want %v", got.Picked(), want)
+	}
+	if got.AttachTarget() != "" {
+		t.Fatalf("pick mode should not request attach, got %q", got.AttachTarget())
+	}
+	if cmd == nil {
+		t.Fatal("expected quit command after picking")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fatal("expected quit command after picking")
+	}
+}
+
+func TestPickModeQuitsWhenNoSessions(t *testing.T) {
+	m := NewModel(Options{Pick: true})
+	_, cmd := m.Update(sessionsMsg{})
+	if cmd == nil {
+		t.Fatal("expected quit command when there is nothing to pick")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fatal("expected quit command when there is nothing to pick")
+	}
+}
+
+func TestHostColumnAndUnreachableHostsAreNotTargets(t *testing.T) {
+	zmx.UseHosts([]string{"build1", "build2"})
+	t.Cleanup(func() { zmx.UseHosts(nil) })
+	m := NewModel(Options{Pick: true})
+	m.width, m.height = 120, 20
+	updated, _ := m.Update(sessionsMsg{sessions: []Session{
+		{Name: "api", PID: "101"},
+		{Name: "web", PID: "202", Host: "build1"},
+		{Host: "build2", Error: "connection timed out"},
+	}})
+	m = updated.(Model)
+
+	rows := map[string][]string{}
+	degraded := -1
+	for i, line := range strings.Split(stripStyleCodes(m.renderList(10)), "\n") {
+		fields := strings.Fields(line)
+		if strings.Contains(line, "unreachable") {
+			degraded = i
+		} else if len(fields) > 0 {
+			rows[fields[0]] = fields
+		}
+	}
+	if got := rows["web"]; len(got) == 0 || got[len(got)-1] != "build1" {
+		t.Fatalf("remote row should show its host in the host column, not the name: %q", got)
+	}
+	if got := rows["api"]; len(got) == 0 || got[len(got)-1] != "local" {
+		t.Fatalf("local row should show local in the host column: %q", got)
+	}
+	if degraded < 0 {
+		t.Fatal("the unreachable host should be listed")
+	}
+
+	m.cursor = degraded
+	for _, r := range "kc" {
+		updated, _ = m.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+		m = updated.(Model)
+	}
+	if m.state != stateNormal || m.status != "" {
+		t.Fatalf("kill and copy should ignore an unreachable host, state=%v status=%q", m.state, m.status)
+	}
+
+	updated, _ = m.Update(tea.KeyPressMsg{Code: 'a', Mod: tea.ModCtrl})
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	m = updated.(Model)
+	if want := []string{"api", "web@build1"}; !slices.Equal(m.Picked(), want) {
+		t.Fatalf("Picked() = %q, want %q", m.Picked(), want)
+	}
+}
+
+func TestNewSessionFormRejectsDuplicateName(t *testing.T) {
+	m := initialModel()
+	m.sessions = []Session{{Name: "api", StartedIn: t.TempDir()}}
+	m.markSessionsChanged()
+	m.openNewSessionForm()
+	m.form.fields[formFieldName] = "api"
+
+	updated, _ := m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	got := updated.(Model)
+	if got.state != stateNewSession || got.form.err == "" {
+		t.Fatalf("duplicate name should keep the form open with an error, state=%v err=%q", got.state, got.form.err)
+	}
+	if got.AttachTarget() != "" {
+		t.Fatalf("duplicate name should not attach, got %q", got.AttachTarget())
+	}
+}
+
+func TestNewSessionFormAttachDefaultsToCursorDir(t *testing.T) {
+	dir := t.TempDir()
+	m := initialModel()
+	m.sessions = []Session{{Name: "api", StartedIn: dir}}
+	m.markSessionsChanged()
+
+	updated, _ := m.Update(tea.KeyPressMsg{Code: 'n', Text: "n"})
+	got := updated.(Model)
+	for _, r := range "web" {
+		updated, _ = got.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+		got = updated.(Model)
+	}
+	updated, _ = got.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	got = updated.(Model)
+	if got.AttachTarget() != "web" || got.AttachDir() != dir {
+		t.Fatalf("attach = %q in %q, want web in %q", got.AttachTarget(), got.AttachDir(), dir)
+	}
+}
+
+func TestAttachDoneRestoresCursorOnRefresh(t *testing.T) {
+	m := NewModel(Options{Stay: true})
+	m.sessions = []Session{{Name: "alpha"}, {Name: "beta"}, {Name: "gamma"}}
+	m.markSessionsChanged()
+
+	updated, _ := m.Update(attachDoneMsg{name: "gamma", started: time.Now().Add(-5 * time.Minute)})
+	got := updated.(Model)
+	if len(got.logLines) != 1 || !strings.Contains(got.logLines[0], "Detached from gamma after 5m") {
+		t.Fatalf("unexpected log: %q", got.logLines)
+	}
+
+	updated, _ = got.Update(sessionsMsg{sessions: []Session{{Name: "alpha"}, {Name: "beta"}, {Name: "gamma"}}})
+	got = updated.(Model)
+	if got.cursor != 2 {
+		t.Fatalf("cursor = %d, want 2 (gamma)", got.cursor)
+	}
+}
+
+func TestProcessInfoSamplesCPUBetweenRefreshes(t *testing.T) {
+	m := NewModel(Options{CPUThreshold: 50})
+	m.sessions = []Session{{Name: "idle"}, {Name: "busy"}}
+	m.markSessionsChanged()
+	m.sortMode = sortByCPU
+	m.sortAsc = false
+
+	start := time.Now()
+	sample := func(busyCPU time.Duration, at time.Time) {
+		updated, _ := m.Update(processInfoMsg{info: map[string]zmx.ProcessInfo{
+			"idle": {Uptime: 60, CPUTime: time.Second, CPU: 2, Sampled: at},
+			"busy": {Uptime: 60, CPUTime: busyCPU, CPU: 1, Sampled: at},
+		}})
+		m = updated.(Model)
+	}
+	sample(time.Second, start)
+	if visible := m.visibleSessions(); visible[0].Name != "idle" {
+		t.Fatalf("first sample should use lifetime averages, got order %v", visible)
+	}
+
+	// busy burns 1.8s of CPU over the next 2s.
+	sample(2800*time.Millisecond, start.Add(2*time.Second))
+	visible := m.visibleSessions()
+	if visible[0].Name != "busy" || visible[0].CPU < 89 || visible[0].CPU > 91 {
+		t.Fatalf("want busy first at ~90%%, got %+v", visible)
+	}
+	m.width, m.height = 120, 30
+	if row := strings.Split(m.renderList(10), "\n")[0]; !strings.Contains(row, "90%") {
+		t.Fatalf("list row missing CPU column: %q", stripStyleCodes(row))
+	}
+}
+
+func TestRefreshKeepsCursorAndSelectionAndLogsChanges(t *testing.T) {
+	m := NewModel(Options{RefreshInterval: time.Second})
+	updated, _ := m.Update(sessionsMsg{sessions: []Session{{Name: "alpha"}, {Name: "beta", PID: "7", Memory: 42}, {Name: "old-build"}}})
+	m = updated.(Model)
+	m.cursor = 1 // beta
+	m.selected["alpha"] = true
+
+	updated, cmd := m.Update(refreshTickMsg{})
+	m = updated.(Model)
+	if cmd == nil || !m.refreshing {
+		t.Fatal("tick should start a refresh")
+	}
+	if _, cmd = m.Update(refreshTickMsg{}); cmd == nil {
+		t.Fatal("tick during a refresh should still reschedule")
+	}
+
+	updated, _ = m.Update(sessionsMsg{sessions: []Session{{Name: "aardvark"}, {Name: "alpha"}, {Name: "api-server"}, {Name: "beta", PID: "7"}}})
+	m = updated.(Model)
+	if m.refreshing {
+		t.Fatal("refreshing should clear when sessions arrive")
+	}
+	if key := m.cursorKey(); key != "beta" {
+		t.Fatalf("cursor on %q, want beta", key)
+	}
+	if !m.selected["alpha"] {
+		t.Fatal("selection lost on refresh")
+	}
+	if got := m.sessionsByKey([]string{"beta"})[0].Memory; got != 42 {
+		t.Fatalf("beta memory = %d, want 42 carried over until process info arrives", got)
+	}
+	var log []string
+	for _, line := range m.logLines {
+		log = append(log, stripStyleCodes(line)[len("15:04:05 "):])
+	}
+	want := []string{"  + aardvark", "  + api-server", "  - old-build"}
+	if !slices.Equal(log, want) {
+		t.Fatalf("log = %q, want %q", log, want)
+	}
+}
+
+func TestRefreshMovesCursorToNeighbourWhenSessionLeaves(t *testing.T) {
+	m := NewModel(Options{})
+	updated, _ := m.Update(sessionsMsg{sessions: []Session{{Name: "alpha"}, {Name: "beta"}, {Name: "gamma"}}})
+	m = updated.(Model)
+	m.cursor = 2
+
+	updated, _ = m.Update(sessionsMsg{sessions: []Session{{Name: "alpha"}, {Name: "beta"}}})
+	m = updated.(Model)
+	if key := m.cursorKey(); key != "beta" {
+		t.Fatalf("cursor on %q, want beta", key)
+	}
+}
+
+func TestSortChangeKeepsCursorSession(t *testing.T) {
+	m := NewModel(Options{})
+	m.sessions = []Session{{Name: "alpha", Clients: 2}, {Name: "beta", Clients: 0}, {Name: "gamma", Clients: 1}}
+	m.markSessionsChanged()
+	m.cursor = 2 // gamma
+
+	updated, _ := m.Update(tea.KeyPressMsg{Code: 's', Text: "s"})
+	m = updated.(Model)
+	if key := m.cursorKey(); key != "gamma" || m.cursor != 0 {
+		t.Fatalf("cursor = %d on %q, want 0 on gamma after sorting descending", m.cursor, key)
+	}
+}
+
+func TestFinishKillKeepsCursorPosition(t *testing.T) {
+	m := NewModel(Options{})
+	m.sessions = []Session{{Name: "alpha"}, {Name: "beta"}, {Name: "gamma"}, {Name: "delta"}}
+	m.markSessionsChanged()
+	m.cursor = 2 // delta (sorted: alpha beta delta gamma)
+	m.state = stateKilling
+	m.killActive = map[string]zmx.KillStage{"delta": zmx.KillBackend}
+	m.loaded = true
+
+	updated, _ := m.Update(killStageMsg{session: Session{Name: "delta"}, stage: zmx.KillBackend, gone: true})
+	m = updated.(Model)
+	if key := m.cursorKey(); key != "gamma" {
+		t.Fatalf("cursor on %q, want gamma", key)
+	}
+	updated, _ = m.Update(sessionsMsg{sessions: []Session{{Name: "alpha"}, {Name: "beta"}, {Name: "gamma"}}})
+	m = updated.(Model)
+	for _, line := range m.logLines {
+		if strings.Contains(line, "- delta") {
+			t.Fatalf("killed session logged as departure: %q", m.logLines)
+		}
+	}
+}
+
+func TestKillEscalatesAndReportsSurvivors(t *testing.T) {
+	m := NewModel(Options{KillGrace: time.Second})
+	m.sessions = []Session{{Name: "api"}, {Name: "db"}}
+	m.markSessionsChanged()
+	m.selected = map[string]bool{"api": true, "db": true}
+	update := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+
+	update(tea.KeyPressMsg{Code: 'k', Text: "k"})
+	if cmd := update(tea.KeyPressMsg{Code: 'y', Text: "y"}); cmd == nil || len(m.killActive) != 1 {
+		t.Fatalf("y should start killing api alone, active=%v", m.killActive)
+	}
+	if cmd := update(killStageMsg{session: Session{Name: "api"}, stage: zmx.KillInterrupt}); cmd == nil || m.killActive["api"] != zmx.KillTerminate {
+		t.Fatalf("a surviving session should escalate, active=%v", m.killActive)
+	}
+	update(killStageMsg{session: Session{Name: "api"}, stage: zmx.KillTerminate, gone: true})
+	if _, ok := m.killActive["db"]; !ok {
+		t.Fatalf("active = %v, want db next", m.killActive)
+	}
+	update(killStageMsg{session: Session{Name: "db"}, stage: zmx.KillForce})
+
+	log := stripStyleCodes(strings.Join(m.logLines, "\n"))
+	for _, want := range []string{"✓ api (SIGTERM)", "✗ db: survived every stage", "Killed 1 session(s)", "1 session(s) survived: db"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log missing %q:\n%s", want, log)
+		}
+	}
+	if m.state != stateNormal || !m.selected["db"] || m.selected["api"] {
+		t.Fatalf("state=%v selected=%v, want the survivor still selected", m.state, m.selected)
+	}
+}
+
+func TestKillRunsInParallelAndCanBeCancelled(t *testing.T) {
+	m := NewModel(Options{KillConcurrency: 2})
+	m.width, m.height = 120, 20
+	names := []string{"s1", "s2", "s3", "s4", "s5"}
+	m.selected = make(map[string]bool)
+	for _, name := range names {
+		m.sessions = append(m.sessions, Session{Name: name})
+		m.selected[name] = true
+	}
+	m.markSessionsChanged()
+	update := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+
+	update(tea.KeyPressMsg{Code: 'k', Text: "k"})
+	update(tea.KeyPressMsg{Code: 'y', Text: "y"})
+	if len(m.killActive) != 2 || len(m.killQueue) != 3 {
+		t.Fatalf("active=%v queued=%d, want 2 in flight and 3 queued", m.killActive, len(m.killQueue))
+	}
+	update(killStageMsg{session: Session{Name: "s1"}, stage: zmx.KillBackend, gone: true})
+	if _, ok := m.killActive["s3"]; !ok || len(m.killActive) != 2 {
+		t.Fatalf("active = %v, want s3 to replace s1", m.killActive)
+	}
+	if title := stripStyleCodes(m.killTitle()); !strings.Contains(title, "████"+strings.Repeat("░", 16)+" 1/5") {
+		t.Fatalf("killTitle = %q, want a progress bar at 1/5", title)
+	}
+
+	update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if m.state != stateKilling || len(m.killQueue) != 0 {
+		t.Fatalf("esc should stop dequeuing but wait for kills in flight, state=%v", m.state)
+	}
+	update(killStageMsg{session: Session{Name: "s2"}, stage: zmx.KillBackend, gone: true})
+	update(killStageMsg{session: Session{Name: "s3"}, stage: zmx.KillBackend, gone: true})
+	if m.state != stateNormal {
+		t.Fatalf("state = %v, want normal once the kills in flight finish", m.state)
+	}
+	log := stripStyleCodes(strings.Join(m.logLines, "\n"))
+	if !strings.Contains(log, "left 2 session(s) untouched: s4, s5") || !strings.Contains(log, "Killed 3 session(s)") {
+		t.Fatalf("log = %s", log)
+	}
+	if len(m.selected) != 2 || !m.selected["s4"] || !m.selected["s5"] {
+		t.Fatalf("selected = %v, want the untouched sessions", m.selected)
+	}
+}
+
+func TestProtectedSessionsNeedTheirNameTyped(t *testing.T) {
+	protection, _ := zmx.NewProtection([]string{"prod-*"}, "")
+	m := NewModel(Options{Protection: protection})
+	m.width, m.height = 120, 20
+	m.sessions = []Session{{Name: "api"}, {Name: "migrate"}, {Name: "prod-tunnel", Backend: "tmux", Host: "build1"}}
+	m.markSessionsChanged()
+	update := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+
+	m.cursor = 1 // migrate
+	update(tea.KeyPressMsg{Code: 'p', Text: "p"})
+	if !m.isProtected("migrate") {
+		t.Fatal("p should protect the cursor session")
+	}
+	if rows := strings.Split(stripStyleCodes(m.renderList(10)), "\n"); strings.Contains(rows[0], "🔒") || !strings.Contains(rows[1], "🔒") {
+		t.Fatalf("only protected sessions should show a lock:\n%s", strings.Join(rows, "\n"))
+	}
+
+	update(tea.KeyPressMsg{Code: 'a', Mod: tea.ModCtrl})
+	update(tea.KeyPressMsg{Code: 'k', Text: "k"})
+	if got := m.killTargets(); !slices.Equal(got, []string{"api"}) {
+		t.Fatalf("killTargets = %v, want only api", got)
+	}
+	if help := stripStyleCodes(m.renderHelp()); !strings.Contains(help, "skipping 2 protected: migrate, tmux:prod-tunnel@build1") {
+		t.Fatalf("confirm prompt = %q", help)
+	}
+	update(tea.KeyPressMsg{Code: tea.KeyEscape})
+
+	// A lone protected target can only be killed by typing its name, which
+	// leaves out the backend and host.
+	m.selected = make(map[string]bool)
+	m.cursor = 2 // tmux:prod-tunnel@build1
+	update(tea.KeyPressMsg{Code: 'k', Text: "k"})
+	if help := stripStyleCodes(m.renderHelp()); !strings.Contains(help, "Type its name, prod-tunnel, to kill it") {
+		t.Fatalf("confirm prompt = %q", help)
+	}
+	for _, r := range "prod-tunnle" {
+		update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	if update(tea.KeyPressMsg{Code: tea.KeyEnter}); m.state != stateNormal {
+		t.Fatalf("a mistyped name should cancel, state=%v", m.state)
+	}
+	update(tea.KeyPressMsg{Code: 'k', Text: "k"})
+	for _, r := range "prod-tunnelé" {
+		update(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	// Backspace removes the whole "é", not one of its bytes.
+	update(tea.KeyPressMsg{Code: tea.KeyBackspace})
+	if update(tea.KeyPressMsg{Code: tea.KeyEnter}); m.state != stateKilling {
+		t.Fatalf("typing the name should start the kill, state=%v", m.state)
+	}
+
+	// Pattern protection cannot be toggled off.
+	m.state = stateNormal
+	update(tea.KeyPressMsg{Code: 'p', Text: "p"})
+	if !m.isProtected("tmux:prod-tunnel@build1") {
+		t.Fatal("prod-tunnel matches a protect pattern and should stay protected")
+	}
+}
+
+func TestEditTagsAliasAndNote(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20
+	updated, _ := m.Update(sessionsMsg{sessions: []Session{{Name: "tmp3"}, {Name: "web"}, {Name: "worker"}}})
+	m = updated.(Model)
+	update := func(msg tea.Msg) {
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+	typeText := func(text string) {
+		for _, r := range text {
+			update(tea.KeyPressMsg{Code: r, Text: string(r)})
+		}
+	}
+
+	update(tea.KeyPressMsg{Code: 'a', Text: "a"})
+	typeText("deploy fix")
+	update(tea.KeyPressMsg{Code: tea.KeyTab})
+	typeText("alice ci")
+	update(tea.KeyPressMsg{Code: tea.KeyTab})
+	typeText("Started by alice.")
+	update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	typeText("Safe to kill.")
+	update(tea.KeyPressMsg{Code: 's', Mod: tea.ModCtrl})
+	if m.state != stateNormal {
+		t.Fatalf("ctrl+s should save and close the editor, state=%v", m.state)
+	}
+	got := m.meta.Get("tmp3")
+	if got.Alias != "deploy fix" || !slices.Equal(got.Tags, []string{"alice", "ci"}) || got.Note != "Started by alice.\nSafe to kill." {
+		t.Fatalf("saved metadata = %+v", got)
+	}
+	if row := strings.Split(stripStyleCodes(m.renderList(10)), "\n")[0]; !strings.Contains(row, "deploy fix  alice   ci ") {
+		t.Fatalf("list row should show the alias and tag chips: %q", row)
+	}
+	if header := stripStyleCodes(renderNoteHeader(got.Note, 40)); !strings.HasPrefix(header, " Started by alice.\n Safe to kill.\n──") {
+		t.Fatalf("note header = %q", header)
+	}
+
+	// With several sessions selected, only the tags they share are edited.
+	m.selected = map[string]bool{"tmp3": true, "web": true}
+	update(tea.KeyPressMsg{Code: 't', Text: "t"})
+	if m.metaEdit.fields[metaFieldTags] != "" {
+		t.Fatalf("no tags are shared yet, field = %q", m.metaEdit.fields[metaFieldTags])
+	}
+	typeText("prod")
+	update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if tags := m.meta.Get("tmp3").Tags; !slices.Equal(tags, []string{"alice", "ci", "prod"}) {
+		t.Fatalf("tmp3 tags = %v", tags)
+	}
+	if tags := m.meta.Get("web").Tags; !slices.Equal(tags, []string{"prod"}) {
+		t.Fatalf("web tags = %v", tags)
+	}
+
+	m.setFilterText("tag:prod")
+	if n := len(m.visibleSessions()); n != 2 {
+		t.Fatalf("tag:prod matched %d sessions, want 2", n)
+	}
+	m.setFilterText("")
+
+	// A complete listing without tmp3 forgets its metadata.
+	update(sessionsMsg{sessions: []Session{{Name: "web"}, {Name: "worker"}}})
+	if got := m.meta.Get("tmp3"); got.Alias != "" || got.Tags != nil {
+		t.Fatalf("metadata of a dead session was kept: %+v", got)
+	}
+	if tags := m.sessions[0].Tags; !slices.Equal(tags, []string{"prod"}) {
+		t.Fatalf("refreshed sessions should carry their tags, got %v", tags)
+	}
+}
+
+func TestGroupHeadersSelectKillAndCollapse(t *testing.T) {
+	m := NewModel(Options{GroupDelimiter: "/"})
+	m.width, m.height = 120, 20
+	m.sessions = []Session{
+		{Name: "proj/web", Memory: 1 << 30, Clients: 1},
+		{Name: "scratch"},
+		{Name: "proj/api", Memory: 1 << 30},
+		{Name: "ops/db"},
+	}
+	m.markSessionsChanged()
+	update := func(msg tea.Msg) {
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+
+	update(tea.KeyPressMsg{Code: 'G', Text: "G"}) // dir
+	update(tea.KeyPressMsg{Code: 'G', Text: "G"}) // repo
+	update(tea.KeyPressMsg{Code: 'G', Text: "G"}) // prefix
+	if m.grouper.Mode != zmx.GroupByPrefix {
+		t.Fatalf("group mode = %v, want prefix", m.grouper.Mode)
+	}
+	list := stripStyleCodes(m.renderList(20))
+	rows := strings.Split(list, "\n")
+	if len(rows) != 7 || !strings.Contains(rows[0], "▾ ops") || !strings.Contains(rows[2], "▾ proj") ||
+		!strings.Contains(rows[2], "2 · 2.0G · ●1") || !strings.Contains(rows[5], "(no prefix)") {
+		t.Fatalf("grouped list:\n%s", list)
+	}
+
+	m.cursor = 2 // proj header
+	update(tea.KeyPressMsg{Code: tea.KeySpace, Text: " "})
+	if len(m.selected) != 2 || !m.selected["proj/api"] || !m.selected["proj/web"] {
+		t.Fatalf("space on a header should select its sessions, selected=%v", m.selected)
+	}
+	update(tea.KeyPressMsg{Code: tea.KeySpace, Text: " "})
+	if len(m.selected) != 0 {
+		t.Fatalf("space again should deselect the group, selected=%v", m.selected)
+	}
+	if got := m.killTargets(); !slices.Equal(got, []string{"proj/api", "proj/web"}) {
+		t.Fatalf("killTargets on a header = %v, want the group", got)
+	}
+
+	update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if rows := m.listRows(); len(rows) != 5 || m.cursorKey() != "" || m.cursor != 2 {
+		t.Fatalf("enter should collapse proj, rows=%d cursor=%d", len(rows), m.cursor)
+	}
+	update(tea.KeyPressMsg{Code: tea.KeyDown})
+	if m.cursorKey() != "" || m.cursor != 3 {
+		t.Fatalf("down should move to the next header, cursor=%d", m.cursor)
+	}
+	update(tea.KeyPressMsg{Code: tea.KeyDown})
+	if m.cursorKey() != "scratch" {
+		t.Fatalf("cursor on %q, want scratch", m.cursorKey())
+	}
+}
+
+func TestHistoryModeScrollsSearchesAndGrows(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20 // 11-line pages
+	m.sessions = []Session{{Name: "api"}}
+	m.markSessionsChanged()
+
+	updated, cmd := m.Update(tea.KeyPressMsg{Code: 'v', Text: "v"})
+	m = updated.(Model)
+	if m.state != stateHistory || cmd == nil {
+		t.Fatalf("v should open history mode and load it, state=%v", m.state)
+	}
+
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	lines[20] = "\x1b[31merror: disk full\x1b[m"
+	lines[70] = "error: retrying"
+	updated, _ = m.Update(historyMsg{name: "api", window: historyWindow, lines: lines, more: true})
+	m = updated.(Model)
+	if m.history.top != 89 {
+		t.Fatalf("history should open at the bottom, top = %d", m.history.top)
+	}
+
+	press := func(keys ...tea.KeyPressMsg) tea.Cmd {
+		var cmd tea.Cmd
+		for _, k := range keys {
+			updated, cmd = m.Update(k)
+			m = updated.(Model)
+		}
+		return cmd
+	}
+	text := func(s string) []tea.KeyPressMsg {
+		var keys []tea.KeyPressMsg
+		for _, r := range s {
+			keys = append(keys, tea.KeyPressMsg{Code: r, Text: string(r)})
+		}
+		return keys
+	}
+
+	press(tea.KeyPressMsg{Code: tea.KeyPgUp})
+	if m.history.top != 78 {
+		t.Fatalf("pgup: top = %d, want 78", m.history.top)
+	}
+
+	press(append(text("/ERROR"), tea.KeyPressMsg{Code: tea.KeyEnter})...)
+	if len(m.history.matches) != 2 || m.history.matches[m.history.match] != 70 {
+		t.Fatalf("search should select the match nearest the view, got %v/%d", m.history.matches, m.history.match)
+	}
+	press(text("n")...)
+	if line := m.history.matches[m.history.match]; line != 20 || m.history.top > 20 || m.history.top+11 <= 20 {
+		t.Fatalf("n should wrap to line 20 and show it, match line %d top %d", line, m.history.top)
+	}
+	if view := stripStyleCodes(m.renderHistory(11)); !strings.Contains(view, "error: disk full") {
+		t.Fatalf("match line not rendered: %q", view)
+	}
+	if !strings.Contains(m.historyTitle(), "/ERROR 1/2") {
+		t.Fatalf("title = %q", m.historyTitle())
+	}
+
+	if cmd := press(tea.KeyPressMsg{Code: tea.KeyHome}); cmd == nil || m.history.window != historyWindow*historyGrowth {
+		t.Fatalf("home at the top with more history should load a larger window, window=%d", m.history.window)
+	}
+	// The larger window keeps the view on the same lines.
+	older := append(make([]string, 50), lines...)
+	updated, _ = m.Update(historyMsg{name: "api", window: m.history.window, lines: older})
+	m = updated.(Model)
+	if m.history.top != 50 || m.history.more {
+		t.Fatalf("after growing: top = %d more = %v, want 50 false", m.history.top, m.history.more)
+	}
+
+	press(tea.KeyPressMsg{Code: tea.KeyEscape}, tea.KeyPressMsg{Code: tea.KeyEscape})
+	if m.state != stateNormal {
+		t.Fatalf("esc should clear the search, then leave history mode; state = %v", m.state)
+	}
+}
+
+func TestGrepOpensMatchInHistory(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20 // 11-line pages
+	m.sessions = []Session{{Name: "api"}, {Name: "web"}}
+	m.markSessionsChanged()
+
+	press := func(keys ...tea.KeyPressMsg) tea.Cmd {
+		var cmd tea.Cmd
+		for _, k := range keys {
+			var updated tea.Model
+			updated, cmd = m.Update(k)
+			m = updated.(Model)
+		}
+		return cmd
+	}
+	press(tea.KeyPressMsg{Code: 'g', Text: "g"})
+	for _, r := range "8080" {
+		press(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	if cmd := press(tea.KeyPressMsg{Code: tea.KeyEnter}); m.state != stateGrep || !m.grep.running || cmd == nil {
+		t.Fatalf("enter should start the search, state=%v running=%v", m.state, m.grep.running)
+	}
+
+	updated, _ := m.Update(grepMsg{query: "8080", results: []zmx.GrepResult{
+		{Session: Session{Name: "api"}, Matches: []zmx.GrepMatch{{Line: 3, Text: "listening on 8080"}}},
+		{Session: Session{Name: "web"}, Matches: []zmx.GrepMatch{{Line: 40, Text: "bind 8080: in use"}}},
+	}})
+	m = updated.(Model)
+	if len(m.grep.hits) != 2 || !strings.Contains(m.grepTitle(), "1/2 in 2 session(s)") {
+		t.Fatalf("hits = %v, title %q", m.grep.hits, m.grepTitle())
+	}
+	if view := stripStyleCodes(m.renderGrep(11)); !strings.Contains(view, "web (1)") || !strings.Contains(view, "41 bind 8080") {
+		t.Fatalf("results not rendered: %q", view)
+	}
+
+	press(tea.KeyPressMsg{Code: tea.KeyDown})
+	if cmd := press(tea.KeyPressMsg{Code: tea.KeyEnter}); cmd == nil || m.state != stateHistory {
+		t.Fatalf("enter should open the match in history, state=%v", m.state)
+	}
+	if m.cursorKey() != "web" || m.history.key != "web" {
+		t.Fatalf("cursor = %q history = %q, want web", m.cursorKey(), m.history.key)
+	}
+
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	lines[40] = "bind 8080: in use"
+	lines[90] = "retry on 8080"
+	updated, _ = m.Update(historyMsg{name: "web", window: historyWindow, lines: lines})
+	m = updated.(Model)
+	if h := m.history; h.matches[h.match] != 40 || h.top > 40 || h.top+11 <= 40 {
+		t.Fatalf("history should show line 40 as the current match, match %v/%d top %d", h.matches, h.match, h.top)
+	}
+
+	press(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if m.state != stateGrep || m.grep.hit != 1 {
+		t.Fatalf("esc should return to the grep results, state=%v hit=%d", m.state, m.grep.hit)
+	}
+
+	// Abandoning an edited query restores the one the results are for.
+	press(tea.KeyPressMsg{Code: 'g', Text: "g"}, tea.KeyPressMsg{Code: 'x', Text: "x"}, tea.KeyPressMsg{Code: tea.KeyEscape})
+	if m.grep.query != "8080" || m.grep.typing || len(m.grep.hits) != 2 {
+		t.Fatalf("esc should restore the searched query, query=%q typing=%v hits=%d", m.grep.query, m.grep.typing, len(m.grep.hits))
+	}
+
+	// Esc stops a running search, whose results are then dropped.
+	press(tea.KeyPressMsg{Code: 'g', Text: "g"}, tea.KeyPressMsg{Code: '1', Text: "1"}, tea.KeyPressMsg{Code: tea.KeyEnter})
+	cancel := m.grep.cancel
+	press(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if m.grep.running || !m.grep.typing || m.grep.query != "80801" || cancel == nil {
+		t.Fatalf("esc should stop the search and edit its query, running=%v typing=%v query=%q", m.grep.running, m.grep.typing, m.grep.query)
+	}
+	updated, _ = m.Update(grepMsg{query: "80801", results: []zmx.GrepResult{{Session: Session{Name: "api"}, Matches: []zmx.GrepMatch{{Line: 1, Text: "80801"}}}}})
+	m = updated.(Model)
+	if m.grep.done {
+		t.Fatal("results of a cancelled search should be ignored")
+	}
+	press(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if m.state != stateNormal {
+		t.Fatalf("esc with no results should leave grep mode, state=%v", m.state)
+	}
+}
+
+func TestExportFormExportsSelectedSessions(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20
+	m.sessions = []Session{{Name: "api"}, {Name: "web"}, {Name: "db"}}
+	m.markSessionsChanged()
+	m.selected = map[string]bool{"api": true, "web": true}
+
+	press := func(keys ...tea.KeyPressMsg) tea.Cmd {
+		var cmd tea.Cmd
+		for _, k := range keys {
+			var updated tea.Model
+			updated, cmd = m.Update(k)
+			m = updated.(Model)
+		}
+		return cmd
+	}
+	press(tea.KeyPressMsg{Code: 'e', Text: "e"})
+	if m.state != stateExport || len(m.exportForm.targets) != 2 {
+		t.Fatalf("e should open the export form for the selection, state=%v targets=%v", m.state, m.exportForm.targets)
+	}
+
+	dir := t.TempDir() + "/out"
+	press(tea.KeyPressMsg{Code: tea.KeyLeft})
+	press(tea.KeyPressMsg{Code: tea.KeyTab})
+	m.exportForm.dir = ""
+	for _, r := range dir {
+		press(tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	if m.exportForm.format != zmx.ExportCast || m.exportForm.dir != dir {
+		t.Fatalf("format = %v dir = %q", m.exportForm.format, m.exportForm.dir)
+	}
+	if cmd := press(tea.KeyPressMsg{Code: tea.KeyEnter}); cmd == nil || m.state != stateNormal {
+		t.Fatalf("enter should start exporting, state=%v", m.state)
+	}
+
+	updated, _ := m.Update(exportDoneMsg{results: []exportResult{
+		{name: "api", path: dir + "/api.cast"},
+		{name: "web", err: fmt.Errorf("timed out")},
+	}})
+	m = updated.(Model)
+	log := stripStyleCodes(strings.Join(m.logLines, "\n"))
+	for _, want := range []string{"✓ api → " + dir + "/api.cast", "✗ web: timed out", "1 of 2 export(s) failed"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log missing %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestPreviewFetchIsDebouncedCancelledAndCached(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20
+	m.sessions = []Session{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+	m.markSessionsChanged()
+	w, h := m.previewInnerWidth(), m.mainContentHeight(1)
+
+	press := func(code rune) tea.Cmd {
+		updated, cmd := m.Update(tea.KeyPressMsg{Code: code})
+		m = updated.(Model)
+		return cmd
+	}
+	press(tea.KeyDown)
+	stale := m.previews.seq
+	press(tea.KeyDown) // cursor on c
+	updated, cmd := m.Update(previewDueMsg{seq: stale})
+	m = updated.(Model)
+	if cmd != nil || len(m.previews.inflight) != 0 {
+		t.Fatalf("a superseded debounce tick should not fetch, inflight=%v", m.previews.inflight)
+	}
+	updated, cmd = m.Update(previewDueMsg{seq: m.previews.seq})
+	m = updated.(Model)
+	if cmd == nil || len(m.previews.inflight) != 3 {
+		t.Fatalf("debounce tick should fetch c and prefetch b and d, inflight=%v", m.previews.inflight)
+	}
+
+	press(tea.KeyUp) // cursor on b: d is no longer wanted
+	if _, ok := m.previews.inflight["d"]; ok {
+		t.Fatal("moving away should cancel the fetch for d")
+	}
+
+	updated, _ = m.Update(previewMsg{name: "b", content: "B", width: w, height: h})
+	m = updated.(Model)
+	if m.preview != "B" {
+		t.Fatalf("preview = %q, want B", m.preview)
+	}
+	press(tea.KeyDown)
+	cmd = press(tea.KeyUp)
+	var cached bool
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg, ok := c().(previewMsg); ok && msg.name == "b" && msg.cached {
+			cached = true
+		}
+	}
+	if !cached {
+		t.Fatal("revisiting b should serve the cached preview at once")
+	}
+}
+
+func TestPreviewCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newPreviewCache()
+	now := time.Now()
+	for i := range previewCacheSize {
+		c.put(fmt.Sprint(i), "x", 10, 5, now)
+	}
+	c.get("0", 10, 5, now) // 1 is now the least recently used
+	c.put("new", "x", 10, 5, now)
+	if _, ok := c.entries["1"]; ok || len(c.entries) != previewCacheSize {
+		t.Fatalf("expected 1 to be evicted, have %d entries", len(c.entries))
+	}
+	if _, ok := c.get("0", 10, 5, now.Add(previewCacheTTL+time.Second)); ok {
+		t.Fatal("entries older than the TTL should miss")
+	}
+	if _, ok := c.get("0", 11, 5, now); ok {
+		t.Fatal("entries rendered at another size should miss")
+	}
+}
+
+func TestFollowModeRepollsPreviewAndFlagsNewOutput(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20
+	m.sessions = []Session{{Name: "build"}}
+	m.markSessionsChanged()
+	update := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+
+	if cmd := update(tea.KeyPressMsg{Code: 'f', Text: "f"}); cmd == nil || !m.follow {
+		t.Fatal("f should start following and poll at once")
+	}
+	if _, ok := m.previews.inflight["build"]; !ok {
+		t.Fatal("following should fetch the preview, bypassing the cache")
+	}
+	update(previewMsg{name: "build", content: "step 1"})
+	if m.followNew != 0 || !strings.Contains(m.followLabel(), "follow") {
+		t.Fatalf("first preview is not new output: followNew=%d label=%q", m.followNew, m.followLabel())
+	}
+
+	if cmd := update(followTickMsg{seq: m.followSeq}); cmd == nil {
+		t.Fatal("follow tick should poll and reschedule")
+	}
+	update(previewMsg{name: "build", content: "step 1\nstep 2"})
+	if m.preview != "step 1\nstep 2" || !strings.Contains(m.followLabel(), "new output") {
+		t.Fatalf("changed preview should be shown and flagged, label=%q", m.followLabel())
+	}
+
+	seq := m.followSeq
+	update(tea.KeyPressMsg{Code: 'f', Text: "f"})
+	if cmd := update(followTickMsg{seq: seq}); m.follow || cmd != nil {
+		t.Fatal("f again should stop following and end the polling loop")
+	}
+}
+
+func TestFollowModeInHistoryPinsBottomUnlessScrolled(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20 // 11-line pages
+	m.sessions = []Session{{Name: "build"}}
+	m.markSessionsChanged()
+	update := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+	lines := func(from, to int) []string {
+		var out []string
+		for i := from; i < to; i++ {
+			out = append(out, fmt.Sprintf("line %d", i))
+		}
+		return out
+	}
+
+	update(tea.KeyPressMsg{Code: 'v', Text: "v"})
+	update(historyMsg{name: "build", window: historyWindow, lines: lines(0, 100)})
+	if cmd := update(tea.KeyPressMsg{Code: 'f', Text: "f"}); cmd == nil || !m.history.loading {
+		t.Fatal("f in history mode should start following and reload the history")
+	}
+	update(historyMsg{name: "build", window: historyWindow, lines: lines(0, 105)})
+	if m.history.top != 94 || m.followNew != 0 {
+		t.Fatalf("pinned view should follow the bottom: top=%d followNew=%d", m.history.top, m.followNew)
+	}
+
+	update(tea.KeyPressMsg{Code: tea.KeyPgUp})
+	if m.history.plain[m.history.top] != "line 83" {
+		t.Fatalf("top line = %q", m.history.plain[m.history.top])
+	}
+	update(followTickMsg{seq: m.followSeq})
+	// The window is full, so old lines fall off the top as new ones arrive.
+	update(historyMsg{name: "build", window: historyWindow, lines: lines(5, 110)})
+	if got := m.history.plain[m.history.top]; got != "line 83" || m.followNew != 5 {
+		t.Fatalf("scrolled view should stay put: top line %q, followNew %d", got, m.followNew)
+	}
+	if !strings.Contains(m.followLabel(), "↓ 5 new") {
+		t.Fatalf("label = %q", m.followLabel())
+	}
+	update(tea.KeyPressMsg{Code: tea.KeyEnd})
+	if m.followNew != 0 {
+		t.Fatal("returning to the bottom should clear the new-output count")
+	}
+}
+
+func TestSendPromptSendsKeysToSelectedSessions(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20
+	m.sessions = []Session{{Name: "w1", Backend: "tmux"}, {Name: "w2", Backend: "tmux"}, {Name: "api"}}
+	m.markSessionsChanged()
+	m.selected = map[string]bool{"tmux:w1": true, "tmux:w2": true}
+	update := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+	typeText := func(s string) {
+		for _, r := range s {
+			update(tea.KeyPressMsg{Code: r, Text: string(r)})
+		}
+	}
+
+	update(tea.KeyPressMsg{Code: 'i', Text: "i"})
+	if m.state != stateSend || len(m.send.targets) != 2 {
+		t.Fatalf("i should prompt for the selection, state=%v targets=%v", m.state, m.send.targets)
+	}
+	update(tea.KeyPressMsg{Code: tea.KeyTab})
+	typeText("ctrl-q-bad")
+	if update(tea.KeyPressMsg{Code: tea.KeyEnter}); m.state != stateSend || m.send.err == "" {
+		t.Fatal("an unknown key should be reported inline")
+	}
+	m.send.text = ""
+	typeText("ctrl-c")
+	if cmd := update(tea.KeyPressMsg{Code: tea.KeyEnter}); cmd == nil || m.state != stateNormal {
+		t.Fatalf("enter should send, state=%v err=%q", m.state, m.send.err)
+	}
+
+	m.previews.put("tmux:w1", "old", 1, 1, time.Now())
+	cmd := update(sendDoneMsg{input: zmx.Input{Keys: []string{"ctrl-c"}}, results: []sendResult{
+		{name: "tmux:w1"},
+		{name: "tmux:w2", err: fmt.Errorf("tmux send-keys w2: exit status 1")},
+	}})
+	log := stripStyleCodes(strings.Join(m.logLines, "\n"))
+	if !strings.Contains(log, "✓ tmux:w1") || !strings.Contains(log, "✗ tmux:w2: tmux send-keys w2") {
+		t.Fatalf("log = %q", log)
+	}
+	if _, ok := m.previews.entries["tmux:w1"]; ok || cmd == nil {
+		t.Fatal("a successful send should drop the cached preview and schedule a refresh")
+	}
+}
+
+func TestSendPromptOffersOnlyTextToZmxSessions(t *testing.T) {
+	m := NewModel(Options{})
+	m.width, m.height = 120, 20
+	m.sessions = []Session{{Name: "w1", Backend: "tmux"}, {Name: "api"}}
+	m.markSessionsChanged()
+	m.selected = map[string]bool{"tmux:w1": true, "api": true}
+	update := func(msg tea.Msg) tea.Cmd {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+
+	update(tea.KeyPressMsg{Code: 'i', Text: "i"})
+	if got := stripStyleCodes(m.renderSendPrompt()); !strings.Contains(got, "zmx sessions take text only") || strings.Contains(got, "Tab keys") {
+		t.Fatalf("prompt = %q, want it to say zmx takes text only", got)
+	}
+	update(tea.KeyPressMsg{Code: tea.KeyTab})
+	if m.send.keys || !strings.Contains(m.send.err, "zmx") {
+		t.Fatalf("tab should refuse keys mode for zmx targets, keys=%v err=%q", m.send.keys, m.send.err)
+	}
+	update(tea.KeyPressMsg{Code: 'l', Text: "l"})
+	update(tea.KeyPressMsg{Code: 's', Text: "s"})
+	if cmd := update(tea.KeyPressMsg{Code: tea.KeyEnter}); cmd == nil || m.state != stateNormal {
+		t.Fatalf("enter should send the line, state=%v err=%q", m.state, m.send.err)
+	}
+}
+
+func TestConfiguredKeysColumnsAndColors(t *testing.T) {
+	keys := config.Default().Keys
+	keys["down"] = []string{"e"}
+	keys["export"] = []string{"x"}
+	keys["kill"] = []string{"ctrl+k"}
+	keys["quit"] = []string{"Q"}
+	m := NewModel(Options{
+		Keys:     keys,
+		Colors:   map[string]string{"selected": "#ff0000", "tag_bg_1": "#00ff00"},
+		Columns:  []string{"clients", "pid"},
+		SortMode: zmx.SortByPID,
+		SortDesc: true,
+	})
+	t.Cleanup(func() { NewModel(Options{}) }) // restore the default palette
+	m.width, m.height = 120, 20
+	updated, _ := m.Update(sessionsMsg{sessions: []Session{{Name: "api", PID: "101", Clients: 2}, {Name: "web", PID: "202"}}})
+	m = updated.(Model)
+
+	if got := m.visibleSessions()[0].Name; got != "web" {
+		t.Fatalf("sorting by descending pid should list web first, got %q", got)
+	}
+	if row := strings.Fields(strings.Split(stripStyleCodes(m.renderList(10)), "\n")[1]); !slices.Equal(row, []string{"api", "●2", "101"}) {
+		t.Fatalf("row should show only clients then pid: %q", row)
+	}
+	if got := selectedStyle.GetForeground(); got != lipgloss.Color("#ff0000") {
+		t.Fatalf("selected color = %v", got)
+	}
+	if got := tagChipColors[0]; got != lipgloss.Color("#00ff00") {
+		t.Fatalf("first tag chip background = %v", got)
+	}
+
+	help := stripStyleCodes(m.renderHelp())
+	for _, want := range []string{"↑e nav", "^k kill", "Q quit"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("help bar should show %q: %q", want, help)
+		}
+	}
+	if strings.Contains(help, "q quit") {
+		t.Errorf("help bar should not show the unbound q: %q", help)
+	}
+
+	updated, _ = m.Update(tea.KeyPressMsg{Code: 'e', Text: "e"})
+	m = updated.(Model)
+	if m.cursor != 1 {
+		t.Fatalf("e should move down, cursor = %d", m.cursor)
+	}
+	if _, cmd := m.Update(tea.KeyPressMsg{Code: 'q', Text: "q"}); cmd != nil {
+		if _, ok := cmd().(tea.QuitMsg); ok {
+			t.Fatal("q is no longer bound and should not quit")
+		}
+	}
+	_, cmd := m.Update(tea.KeyPressMsg{Code: 'Q', Text: "Q"})
+	if cmd == nil {
+		t.Fatal("Q should quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fatal("Q should quit")
+	}
+}
+
+func TestHistoryAndGrepUseConfiguredKeys(t *testing.T) {
+	keys := config.Default().Keys
+	keys["filter"] = []string{"?"}
+	keys["follow"] = []string{"F"}
+	keys["grep"] = []string{"R"}
+	keys["quit"] = []string{"Q"}
+	m := NewModel(Options{Keys: keys})
+	m.width, m.height = 120, 20
+	update := func(r rune) {
+		updated, _ := m.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+		m = updated.(Model)
+	}
+
+	m.state = stateHistory
+	m.history = historyView{key: "api", lines: []string{"one"}, plain: []string{"one"}}
+	help := stripStyleCodes(m.renderHelp())
+	if !strings.Contains(help, "? search") || !strings.Contains(help, "F follow") || strings.Contains(help, "f follow") {
+		t.Fatalf("history help should follow the keymap: %q", help)
+	}
+	if update('/'); m.history.searching {
+		t.Fatal("/ is no longer bound to filter and should not search")
+	}
+	if update('?'); !m.history.searching {
+		t.Fatal("? should start a search")
+	}
+	m.history.searching = false
+	if update('q'); m.state != stateHistory {
+		t.Fatal("q is no longer bound to quit and should not leave history")
+	}
+	if update('Q'); m.state != stateNormal {
+		t.Fatalf("Q should leave history, state=%v", m.state)
+	}
+
+	m.state = stateGrep
+	m.grep = grepView{done: true, query: "err", searched: "err"}
+	if help := stripStyleCodes(m.renderHelp()); !strings.Contains(help, "R new search") {
+		t.Fatalf("grep help should follow the keymap: %q", help)
+	}
+	if update('g'); m.grep.typing {
+		t.Fatal("g is no longer bound to grep and should not start a new search")
+	}
+	if update('R'); !m.grep.typing {
+		t.Fatal("R should start a new search")
+	}
+}
+
 // stripStyleCodes removes ANSI escape sequences for test comparison.
 func stripStyleCodes(s string) string {
 	re := regexp.MustCompile(`\x1b\[[0-9;]*m`)
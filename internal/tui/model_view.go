@@ -2,10 +2,13 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/mattn/go-runewidth"
 	"github.com/mdsakalu/zmx-session-manager/internal/zmx"
 )
@@ -13,7 +16,7 @@ import (
 func previewMaxWidth(raw string) int {
 	maxW := 0
 	for _, line := range strings.Split(raw, "\n") {
-		if w := runewidth.StringWidth(line); w > maxW {
+		if w := ansi.StringWidth(line); w > maxW {
 			maxW = w
 		}
 	}
@@ -24,7 +27,7 @@ func previewMaxWidth(raw string) int {
 
 func (m Model) mainContentHeight(helpLines int) int {
 	// 4 = 2 (list/preview borders) + 2 (log borders)
-	h := m.height - logContentHeight - 4 - helpLines
+	h := m.height - m.logHeight - 4 - helpLines
 	if h < 1 {
 		h = 1
 	}
@@ -32,7 +35,7 @@ func (m Model) mainContentHeight(helpLines int) int {
 }
 
 // listOuterWidth computes the list pane width from session content.
-// Row layout: indicator(2) + name + " " + pid + " " + client + " " + mem + borders(2)
+// Row layout: indicator(2) + name + (" " + column)... + borders(2)
 func (m *Model) listOuterWidth() int {
 	// Minimum: must fit the title elements (display widths, not byte lengths).
 	// Left (non-filtering is always wider): " zmx sessions (NNN) " = 17 + digits
@@ -43,8 +46,12 @@ func (m *Model) listOuterWidth() int {
 	titleMin := (17 + digits) + 11 + 4
 
 	metrics := m.allSessionMetrics()
-	// 2 (indicator) + name + " " + pid + " " + mem + " " + uptime + " " + client + 2 (borders)
-	w := 2 + metrics.nameW + 1 + metrics.pidW + 1 + metrics.memW + 1 + metrics.uptimeW + 1 + metrics.clientW + 2
+	// 2 (indicator) + name + (" " + column)... + 2 (borders)
+	// The name column is never narrower than minNameWidth (see renderList).
+	w := 2 + max(metrics.nameW, minNameWidth) + metrics.columnsWidth(m.listColumns()) + 2
+	if slices.ContainsFunc(m.sessions, m.protection.Protected) {
+		w += lockWidth
+	}
 	if w < titleMin {
 		w = titleMin
 	}
@@ -107,7 +114,16 @@ func (m Model) View() tea.View {
 	if !m.sortAsc {
 		sortArrow = "↓"
 	}
-	listTitleRight := fmt.Sprintf(" %s %s ", sortArrow, m.sortMode.label())
+	listTitleRight := fmt.Sprintf(" %s %s ", sortArrow, m.sortMode.String())
+	if m.fuzzyActive() {
+		listTitleRight = " ↓ score "
+	}
+	if host := m.hostFilterLabel(); host != "" {
+		listTitleRight = " @" + host + listTitleRight
+	}
+	if m.grouper.Mode != zmx.GroupNone {
+		listTitleRight = " by " + m.grouper.Mode.String() + listTitleRight
+	}
 
 	low := m.listOuterWidth()
 	listPane := listBorderStyle.
@@ -125,10 +141,38 @@ func (m Model) View() tea.View {
 	previewContent := clampLines(zmx.ScrollPreview(m.preview, m.previewScrollX, pw), ch)
 	previewTitleLeft := " Preview "
 	previewTitleRight := ""
-	if m.cursor < len(visible) {
-		s := visible[m.cursor]
-		previewTitleLeft = fmt.Sprintf(" %s ", s.Name)
+	if m.state == stateNewSession {
+		previewContent = clampLines(m.renderForm(), ch)
+		previewTitleLeft = " New session "
+	} else if m.state == stateExport {
+		previewContent = clampLines(m.renderExportForm(), ch)
+		previewTitleLeft = " Export "
+	} else if m.state == stateEditMeta {
+		previewContent = clampLines(m.renderMetaEditor(), ch)
+		previewTitleLeft = " " + targetLabel(m.metaEdit.keys) + " "
+	} else if m.state == stateHistory {
+		previewContent = clampLines(m.renderHistory(ch), ch)
+		previewTitleLeft = fmt.Sprintf(" %s %s", m.history.key, m.followLabel())
+		previewTitleRight = m.historyTitle()
+	} else if m.state == stateGrep {
+		previewContent = clampLines(m.renderGrep(ch), ch)
+		previewTitleLeft = " grep "
+		previewTitleRight = m.grepTitle()
+	} else if row, ok := m.cursorRow(); ok && row.group != nil {
+		previewContent = clampLines(m.renderGroupPreview(row.group), ch)
+		previewTitleLeft = fmt.Sprintf(" %s ", row.group.label(m.grouper.Mode))
+		previewTitleRight = fmt.Sprintf(" %d session(s) ", len(row.group.sessions))
+	} else if ok {
+		s := row.session
+		previewTitleLeft = fmt.Sprintf(" %s %s", s.Key(), m.followLabel())
+		if s.Alias != "" {
+			previewTitleLeft = fmt.Sprintf(" %s (%s) %s", s.Alias, s.Key(), m.followLabel())
+		}
 		previewTitleRight = fmt.Sprintf(" 📂 %s ", s.DisplayDir())
+		if s.Note != "" {
+			header := renderNoteHeader(s.Note, pw)
+			previewContent = clampLines(header+"\n"+zmx.ScrollPreview(m.preview, m.previewScrollX, pw), ch)
+		}
 	}
 	pow := m.previewOuterWidth()
 
@@ -145,12 +189,12 @@ func (m Model) View() tea.View {
 
 	logPane := logBorderStyle.
 		Width(m.width).
-		Height(logContentHeight + 2).
+		Height(m.logHeight + 2).
 		Render(logContent)
 
 	logTitle := " Activity Log "
 	if m.state == stateKilling {
-		logTitle = " Killing... "
+		logTitle = m.killTitle()
 	}
 	logPane = replaceTopBorder(logPane, buildTopBorder(logTitle, m.width))
 
@@ -165,7 +209,7 @@ func (m Model) renderLog() string {
 		return logDimStyle.Render("  No activity yet.")
 	}
 
-	end := m.logOffset + logContentHeight
+	end := m.logOffset + m.logHeight
 	if end > len(m.logLines) {
 		end = len(m.logLines)
 	}
@@ -190,22 +234,32 @@ func (m *Model) renderList(maxRows int) string {
 		if m.filterText != "" {
 			return normalStyle.Render("  No matches. Esc to clear filter.")
 		}
-		return normalStyle.Render("  No sessions found. Press r to refresh.")
+		if keys := m.keys.help(actRefresh); keys != "" {
+			return normalStyle.Render("  No sessions found. Press " + keys + " to refresh.")
+		}
+		return normalStyle.Render("  No sessions found.")
 	}
 
 	lw := m.listInnerWidth()
 	metrics := m.visibleMetrics
 	var b strings.Builder
 
+	rows := m.listRows()
 	end := m.listOffset + maxRows
-	if end > len(visible) {
-		end = len(visible)
+	if end > len(rows) {
+		end = len(rows)
 	}
 
 	for i := m.listOffset; i < end; i++ {
-		s := visible[i]
+		row := rows[i]
+		s := row.session
 		isCursor := i == m.cursor
-		isSelected := m.selected[s.Name]
+		isSelected := m.selected[s.Key()]
+		if row.group != nil {
+			isSelected = !slices.ContainsFunc(row.group.sessions, func(s Session) bool {
+				return !m.selected[s.Key()]
+			})
+		}
 
 		var indicator string
 		switch {
@@ -219,49 +273,71 @@ func (m *Model) renderList(maxRows int) string {
 			indicator = "  "
 		}
 
-		var clientInd string
-		if s.Clients > 0 {
-			clientInd = activeClientStyle.Render(padLeft(fmt.Sprintf("●%d", s.Clients), metrics.clientW))
-		} else {
-			clientInd = inactiveClientStyle.Render(padLeft("○0", metrics.clientW))
+		if row.group != nil {
+			b.WriteString(m.renderGroupHeader(row.group, indicator, lw))
+			if i < end-1 {
+				b.WriteString("\n")
+			}
+			continue
 		}
 
-		pidStr := pidStyle.Render(padLeft(s.PID, metrics.pidW))
-
-		memLabel := "-"
-		if s.Memory > 0 {
-			memLabel = zmx.FormatBytes(s.Memory)
+		if s.Degraded() {
+			label := truncate("✗ "+s.Host+" unreachable", lw-2)
+			b.WriteString(indicator + confirmStyle.Render(padRight(label, lw-2)))
+			if i < end-1 {
+				b.WriteString("\n")
+			}
+			continue
 		}
-		memStr := memStyle.Render(padLeft(memLabel, metrics.memW))
 
-		uptimeLabel := "-"
-		if s.Uptime > 0 {
-			uptimeLabel = zmx.FormatUptime(s.Uptime)
+		columns := m.listColumns()
+		var cells strings.Builder
+		for _, column := range columns {
+			cells.WriteString(" " + m.renderCell(s, column, metrics.columnWidth(column)))
 		}
-		uptimeStr := uptimeStyle.Render(padLeft(uptimeLabel, metrics.uptimeW))
 
-		// lw = indicator(2) + name + " " + pid + " " + mem + " " + uptime + " " + client
-		nameWidth := lw - 6 - metrics.pidW - metrics.memW - metrics.uptimeW - metrics.clientW
-		if nameWidth < 10 {
-			nameWidth = 10
+		// lw = indicator(2) + name + (" " + column)...
+		nameWidth := lw - 2 - metrics.columnsWidth(columns)
+		if nameWidth < minNameWidth {
+			nameWidth = minNameWidth
 		}
-		name := truncate(s.Name, nameWidth)
-		paddedName := padRight(name, nameWidth)
+		lock := ""
+		if m.protection.Protected(s) {
+			lock = " " + lockStyle.Render("🔒")
+			nameWidth -= lockWidth
+		}
+		// Tags take what the name leaves, but never more than half.
+		label := m.listName(s)
+		chips, chipsWidth := tagChips(s.Tags, nameWidth-min(runewidth.StringWidth(label), (nameWidth+1)/2))
+		name := truncate(label, nameWidth-chipsWidth)
 
 		style := normalStyle
 		if isCursor || isSelected {
 			style = selectedStyle
+		} else if m.hot(s) {
+			style = cpuHotStyle
 		}
 
 		var styledName string
-		if m.filterText != "" {
-			styledName = highlightMatch(paddedName, m.filterText, style, filterMatchStyle)
+		styledName = style.Render(name)
+		if m.fuzzyActive() {
+			// Match offsets are into the key, which an alias replaces.
+			if s.Alias == "" {
+				positions := visiblePositions(m.fuzzyMatches[s.Key()], name, label)
+				styledName = highlightPositions(name, positions, style, filterMatchStyle)
+			}
 		} else {
-			styledName = style.Render(paddedName)
+			for _, term := range m.filterQuery.Terms() {
+				if term != "" && strings.Contains(strings.ToLower(name), term) {
+					styledName = highlightMatch(name, term, style, filterMatchStyle)
+					break
+				}
+			}
 		}
+		styledName += chips + strings.Repeat(" ", max(nameWidth-chipsWidth-runewidth.StringWidth(name), 0))
 
-		row := fmt.Sprintf("%s%s %s %s %s %s", indicator, styledName, pidStr, memStr, uptimeStr, clientInd)
-		b.WriteString(row)
+		line := indicator + styledName + lock + cells.String()
+		b.WriteString(line)
 		if i < end-1 {
 			b.WriteString("\n")
 		}
@@ -270,43 +346,203 @@ func (m *Model) renderList(maxRows int) string {
 	return b.String()
 }
 
+// localHostLabel is what the host column shows for local sessions.
+const localHostLabel = "local"
+
+// listColumns returns the columns the list shows: the configured ones,
+// without the host column unless remote hosts are configured.
+func (m *Model) listColumns() []string {
+	if len(zmx.RemoteHosts()) > 0 {
+		return m.columns
+	}
+	return slices.DeleteFunc(slices.Clone(m.columns), func(c string) bool { return c == "host" })
+}
+
+// listName returns the name the list shows for s: its alias, or its key
+// without the "@host" suffix when the host column shows the host.
+func (m *Model) listName(s Session) string {
+	if s.Alias == "" && s.Host != "" && slices.Contains(m.listColumns(), "host") {
+		return strings.TrimSuffix(s.Key(), "@"+s.Host)
+	}
+	return s.DisplayName()
+}
+
+// hot reports whether s uses more CPU than the highlight threshold.
+func (m *Model) hot(s Session) bool {
+	return m.cpuThreshold > 0 && s.CPU > m.cpuThreshold
+}
+
+// renderCell renders one of config.ColumnNames for s, right-aligned in
+// width.
+func (m *Model) renderCell(s Session, column string, width int) string {
+	switch column {
+	case "pid":
+		return pidStyle.Render(padLeft(s.PID, width))
+	case "memory":
+		label := "-"
+		if s.Memory > 0 {
+			label = zmx.FormatBytes(s.Memory)
+		}
+		return memStyle.Render(padLeft(label, width))
+	case "cpu":
+		label := "-"
+		if s.Uptime > 0 {
+			label = zmx.FormatCPU(s.CPU)
+		}
+		if m.hot(s) {
+			return cpuHotStyle.Render(padLeft(label, width))
+		}
+		return cpuStyle.Render(padLeft(label, width))
+	case "uptime":
+		label := "-"
+		if s.Uptime > 0 {
+			label = zmx.FormatUptime(s.Uptime)
+		}
+		return uptimeStyle.Render(padLeft(label, width))
+	case "host":
+		if s.Host == "" {
+			return dirStyle.Render(padRight(localHostLabel, width))
+		}
+		return dirStyle.Render(padRight(s.Host, width))
+	case "clients":
+		if s.Clients > 0 {
+			return activeClientStyle.Render(padLeft(fmt.Sprintf("●%d", s.Clients), width))
+		}
+		return inactiveClientStyle.Render(padLeft("○0", width))
+	}
+	return ""
+}
+
 func (m Model) renderHelp() string {
 	if m.state == stateKilling {
-		return helpStyle.Render(" [] scroll log  ") + helpKeyStyle.Render("q") + helpStyle.Render(" quit")
+		line := helpKeyStyle.Render(" esc") + helpStyle.Render(" cancel")
+		if keys := m.keys.help(actLogUp, actLogDown); keys != "" {
+			line += helpStyle.Render("  " + keys + " scroll log")
+		}
+		return line + "  " + helpKeyStyle.Render(m.keys.help(actQuit)) + helpStyle.Render(" quit")
 	}
 
 	if m.state == stateFilter {
 		cursor := "█"
-		return helpStyle.Render(" /") + helpKeyStyle.Render(m.filterText) + helpStyle.Render(cursor+"  Enter accept | Esc clear")
+		prompt, toggle := " /", "Tab fuzzy"
+		if m.fuzzy {
+			prompt, toggle = " ~", "Tab query"
+		}
+		line := helpStyle.Render(prompt) + helpKeyStyle.Render(m.filterText) + helpStyle.Render(cursor)
+		if m.filterErr != nil {
+			return line + confirmStyle.Render("  "+m.filterErr.Error())
+		}
+		return line + helpStyle.Render("  Enter accept | "+toggle+" | Esc clear")
 	}
 
-	if m.state == stateConfirmKill {
-		targets := m.killTargets()
-		if len(targets) == 1 {
-			return confirmStyle.Render(fmt.Sprintf(" Kill %s? y/n ", targets[0]))
+	if m.state == stateHistory {
+		if m.history.searching {
+			return helpStyle.Render(" /") + helpKeyStyle.Render(m.history.query) + helpStyle.Render("█  Enter search | Esc cancel")
+		}
+		parts := []string{
+			helpKeyStyle.Render("↑↓") + helpStyle.Render(" line"),
+			helpKeyStyle.Render("pgup/pgdn") + helpStyle.Render(" page"),
+			helpKeyStyle.Render("home/end") + helpStyle.Render(" top/bottom"),
+			helpKeyStyle.Render("←→") + helpStyle.Render(" scroll"),
+		}
+		parts = m.appendHelp(parts, "search", actFilter)
+		parts = append(parts, helpKeyStyle.Render("n/N")+helpStyle.Render(" next/prev match"))
+		parts = m.appendHelp(parts, "follow", actFollow)
+		parts = append(parts, helpKeyStyle.Render("esc")+helpStyle.Render(" back"))
+		return wrapHelpParts(parts, m.width)
+	}
+
+	if m.state == stateGrep {
+		if m.grep.typing {
+			return helpStyle.Render(" grep: ") + helpKeyStyle.Render(m.grep.query) + helpStyle.Render("█  Enter search all sessions | Esc cancel")
+		}
+		if m.grep.running {
+			return wrapHelpParts([]string{helpKeyStyle.Render("esc") + helpStyle.Render(" stop searching")}, m.width)
+		}
+		parts := []string{
+			helpKeyStyle.Render("↑↓") + helpStyle.Render(" match"),
+			helpKeyStyle.Render("enter") + helpStyle.Render(" open in history"),
 		}
-		return confirmStyle.Render(fmt.Sprintf(" Kill %d sessions? y/n ", len(targets)))
+		parts = m.appendHelp(parts, "new search", actGrep)
+		parts = append(parts, helpKeyStyle.Render("esc")+helpStyle.Render(" back"))
+		return wrapHelpParts(parts, m.width)
 	}
 
-	parts := []string{
-		helpKeyStyle.Render("←→") + helpStyle.Render(" scroll"),
-		helpKeyStyle.Render("↑↓") + helpStyle.Render(" nav"),
-		helpKeyStyle.Render("space") + helpStyle.Render(" sel"),
-		helpKeyStyle.Render("^a") + helpStyle.Render(" all"),
-		helpKeyStyle.Render("enter") + helpStyle.Render(" attach"),
-		helpKeyStyle.Render("k") + helpStyle.Render(" kill"),
-		helpKeyStyle.Render("c") + helpStyle.Render(" copy cmd"),
-		helpKeyStyle.Render("s") + helpStyle.Render(" sort"),
+	if m.state == stateSend {
+		return m.renderSendPrompt()
 	}
+
+	if m.state == stateExport {
+		return wrapHelpParts([]string{
+			helpKeyStyle.Render("tab") + helpStyle.Render(" next field"),
+			helpKeyStyle.Render("←→") + helpStyle.Render(" format"),
+			helpKeyStyle.Render("enter") + helpStyle.Render(" export"),
+			helpKeyStyle.Render("esc") + helpStyle.Render(" cancel"),
+		}, m.width)
+	}
+
+	if m.state == stateEditMeta {
+		enter := " save"
+		if m.metaEdit.focus == metaFieldNote {
+			enter = " new line"
+		}
+		return wrapHelpParts([]string{
+			helpKeyStyle.Render("tab") + helpStyle.Render(" next field"),
+			helpKeyStyle.Render("enter") + helpStyle.Render(enter),
+			helpKeyStyle.Render("^s") + helpStyle.Render(" save"),
+			helpKeyStyle.Render("esc") + helpStyle.Render(" cancel"),
+		}, m.width)
+	}
+
+	if m.state == stateNewSession {
+		return wrapHelpParts([]string{
+			helpKeyStyle.Render("tab") + helpStyle.Render(" next field"),
+			helpKeyStyle.Render("enter") + helpStyle.Render(" create & attach"),
+			helpKeyStyle.Render("^d") + helpStyle.Render(" create detached"),
+			helpKeyStyle.Render("esc") + helpStyle.Render(" cancel"),
+		}, m.width)
+	}
+
+	if m.state == stateConfirmKill {
+		return m.renderKillConfirm()
+	}
+
+	var parts []string
+	bind := func(label string, actions ...string) {
+		parts = m.appendHelp(parts, label, actions...)
+	}
+	bind("scroll", actScrollLeft, actScrollRight)
+	bind("nav", actUp, actDown)
+	bind("sel", actSelect)
+	bind("all", actSelectAll)
+	bind(m.enterLabel(), actAttach)
+	if !m.pickMode {
+		bind("new", actNew)
+	}
+	if len(zmx.RemoteHosts()) > 0 {
+		bind("host", actHost)
+	}
+	bind("kill", actKill)
+	bind("protect", actProtect)
+	bind("tags", actTags)
+	bind("alias", actAlias)
+	bind("note", actNote)
+	bind("copy cmd", actCopy)
+	bind("export", actExport)
+	bind("send", actSend)
+	bind("sort", actSort)
+	bind("group", actGroup)
+	bind("refresh", actRefresh)
+	bind("history", actHistory)
+	bind("follow", actFollow)
+	bind("grep", actGrep)
 	if m.filterText != "" {
 		parts = append(parts, helpKeyStyle.Render("esc")+helpStyle.Render(" clear"))
 	} else {
-		parts = append(parts, helpKeyStyle.Render("/")+helpStyle.Render(" filter"))
+		bind("filter", actFilter)
 	}
-	parts = append(parts,
-		helpKeyStyle.Render("[]")+helpStyle.Render(" log"),
-		helpKeyStyle.Render("q")+helpStyle.Render(" quit"),
-	)
+	bind("log", actLogUp, actLogDown)
+	bind("quit", actQuit)
 
 	if m.status != "" {
 		parts = append(parts, statusStyle.Render(m.status))
@@ -315,6 +551,29 @@ func (m Model) renderHelp() string {
 	return wrapHelpParts(parts, m.width)
 }
 
+// appendHelp appends the help bar item for actions to parts, unless one of
+// them is unbound.
+func (m Model) appendHelp(parts []string, label string, actions ...string) []string {
+	if keys := m.keys.help(actions...); keys != "" {
+		parts = append(parts, helpKeyStyle.Render(keys)+helpStyle.Render(" "+label))
+	}
+	return parts
+}
+
+func (m Model) enterLabel() string {
+	if m.pickMode {
+		return "pick"
+	}
+	return "attach"
+}
+
+// firstLine returns the first line of err's message; zmx errors append the
+// command's full output after a newline.
+func firstLine(err error) string {
+	line, _, _ := strings.Cut(err.Error(), "\n")
+	return line
+}
+
 // wrapHelpParts joins help items with wrapping at maxWidth.
 func wrapHelpParts(parts []string, maxWidth int) string {
 	if maxWidth <= 0 {
@@ -465,8 +724,49 @@ func highlightMatch(s, query string, base, hlStyle lipgloss.Style) string {
 	if idx < 0 {
 		return base.Render(s)
 	}
-	end := idx + len(query)
-	return base.Render(s[:idx]) + hlStyle.Render(s[idx:end]) + base.Render(s[end:])
+	first := utf8.RuneCountInString(lower[:idx])
+	positions := make([]int, utf8.RuneCountInString(query))
+	for i := range positions {
+		positions[i] = first + i
+	}
+	return highlightPositions(s, positions, base, hlStyle)
+}
+
+// highlightPositions renders s with base style, highlighting the runes at
+// the given increasing offsets, which need not be contiguous, using hlStyle.
+func highlightPositions(s string, positions []int, base, hlStyle lipgloss.Style) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for start := 0; start < len(runes); {
+		hit := len(positions) > 0 && positions[0] == start
+		end := start
+		for end < len(runes) && (len(positions) > 0 && positions[0] == end) == hit {
+			if hit {
+				positions = positions[1:]
+			}
+			end++
+		}
+		style := base
+		if hit {
+			style = hlStyle
+		}
+		b.WriteString(style.Render(string(runes[start:end])))
+		start = end
+	}
+	return b.String()
+}
+
+// visiblePositions drops the match offsets, which are into the key, that
+// fall past label, the start of the key the list shows, or that truncating
+// label to name cut off or replaced with "...".
+func visiblePositions(positions []int, name, label string) []int {
+	limit := utf8.RuneCountInString(label)
+	if name != label {
+		limit = utf8.RuneCountInString(name) - 3
+	}
+	return slices.DeleteFunc(slices.Clone(positions), func(p int) bool {
+		return p >= limit
+	})
 }
 
 func padLeft(s string, width int) string {
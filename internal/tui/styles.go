@@ -1,6 +1,11 @@
 package tui
 
-import "charm.land/lipgloss/v2"
+import (
+	"image/color"
+
+	"charm.land/lipgloss/v2"
+	"github.com/mdsakalu/zmx-session-manager/internal/config"
+)
 
 var (
 	// Pane borders
@@ -69,9 +74,29 @@ var (
 	memStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("180")) // warm tan/gold
 
+	cpuStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("151")) // pale green
+
+	cpuHotStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("209")). // salmon
+			Bold(true)
+
 	uptimeStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("109")) // muted blue
 
+	// Protected session indicator
+	lockStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")) // amber
+
+	// Tag chips in the list; the background depends on the tag
+	tagChipBaseStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("255"))
+
+	// Session note above the preview
+	noteStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("180")).
+			Italic(true)
+
 	// Filter match highlight
 	filterMatchStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("228")).
@@ -83,3 +108,41 @@ var (
 			Foreground(lipgloss.Color("75")).
 			Bold(true)
 )
+
+// applyColors sets the palette from colors, which maps every entry of
+// config.ColorRoles to a color.
+func applyColors(colors map[string]string) {
+	c := func(role string) color.Color { return lipgloss.Color(colors[role]) }
+
+	listBorderStyle = listBorderStyle.BorderForeground(c("border"))
+	previewBorderStyle = previewBorderStyle.BorderForeground(c("border"))
+	logBorderStyle = logBorderStyle.BorderForeground(c("border"))
+	borderCharStyle = borderCharStyle.Foreground(c("border"))
+
+	normalStyle = normalStyle.Foreground(c("text"))
+	selectedStyle = selectedStyle.Foreground(c("selected"))
+	titleStyle = titleStyle.Foreground(c("title"))
+	helpStyle = helpStyle.Foreground(c("help"))
+	helpKeyStyle = helpKeyStyle.Foreground(c("help_key"))
+	statusStyle = statusStyle.Foreground(c("status"))
+	confirmStyle = confirmStyle.Foreground(c("error"))
+	logDimStyle = logDimStyle.Foreground(c("dim"))
+	dirStyle = dirStyle.Foreground(c("dir"))
+	filterMatchStyle = filterMatchStyle.Foreground(c("match"))
+	sortStyle = sortStyle.Foreground(c("sort"))
+
+	activeClientStyle = activeClientStyle.Foreground(c("active"))
+	inactiveClientStyle = inactiveClientStyle.Foreground(c("inactive"))
+	pidStyle = pidStyle.Foreground(c("pid"))
+	memStyle = memStyle.Foreground(c("memory"))
+	cpuStyle = cpuStyle.Foreground(c("cpu"))
+	cpuHotStyle = cpuHotStyle.Foreground(c("cpu_hot"))
+	uptimeStyle = uptimeStyle.Foreground(c("uptime"))
+	lockStyle = lockStyle.Foreground(c("lock"))
+	noteStyle = noteStyle.Foreground(c("note"))
+	tagChipBaseStyle = tagChipBaseStyle.Foreground(c("tag_text"))
+	tagChipColors = tagChipColors[:0]
+	for _, role := range config.TagChipRoles {
+		tagChipColors = append(tagChipColors, c(role))
+	}
+}
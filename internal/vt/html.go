@@ -0,0 +1,140 @@
+package vt
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Colors used for the terminal default foreground and background in HTML.
+const (
+	htmlForeground = "#d0d0d0"
+	htmlBackground = "#1c1c1c"
+)
+
+// basicColors is the xterm palette for the 16 basic ANSI colors.
+var basicColors = [16]string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// css returns c as a CSS color, or "" for the terminal default.
+func (c color) css() string {
+	switch c & colorKind {
+	case colorIndexed:
+		n := int(c & 0xff)
+		switch {
+		case n < 16:
+			return basicColors[n]
+		case n < 232: // 6×6×6 color cube
+			n -= 16
+			level := func(v int) int {
+				if v == 0 {
+					return 0
+				}
+				return 55 + v*40
+			}
+			return fmt.Sprintf("#%02x%02x%02x", level(n/36), level(n/6%6), level(n%6))
+		default: // grayscale ramp
+			v := 8 + (n-232)*10
+			return fmt.Sprintf("#%02x%02x%02x", v, v, v)
+		}
+	case colorRGB:
+		return fmt.Sprintf("#%06x", uint32(c&0xffffff))
+	}
+	return ""
+}
+
+// css returns the inline CSS declarations for s.
+func (s style) css() string {
+	fg, bg := s.fg.css(), s.bg.css()
+	if s.attrs&attrReverse != 0 {
+		if fg == "" {
+			fg = htmlForeground
+		}
+		if bg == "" {
+			bg = htmlBackground
+		}
+		fg, bg = bg, fg
+	}
+	var decls []string
+	if fg != "" {
+		decls = append(decls, "color:"+fg)
+	}
+	if bg != "" {
+		decls = append(decls, "background:"+bg)
+	}
+	if s.attrs&attrBold != 0 {
+		decls = append(decls, "font-weight:bold")
+	}
+	if s.attrs&attrFaint != 0 {
+		decls = append(decls, "opacity:.6")
+	}
+	if s.attrs&attrItalic != 0 {
+		decls = append(decls, "font-style:italic")
+	}
+	var deco []string
+	if s.attrs&attrUnderline != 0 {
+		deco = append(deco, "underline")
+	}
+	if s.attrs&attrStrike != 0 {
+		deco = append(deco, "line-through")
+	}
+	if len(deco) > 0 {
+		decls = append(decls, "text-decoration:"+strings.Join(deco, " "))
+	}
+	if s.attrs&attrHidden != 0 {
+		decls = append(decls, "visibility:hidden")
+	}
+	return strings.Join(decls, ";")
+}
+
+// HTML renders the same rows as Lines as a self-contained HTML document
+// with the given title. Colors and attributes become inline styles, so the
+// file displays the same anywhere.
+func (t *Terminal) HTML(title string) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintf(&b, "<style>body{margin:0;background:%s}pre{margin:0;padding:1em;color:%s;background:%s;"+
+		"font-family:ui-monospace,Menlo,Consolas,monospace;line-height:1.2}</style>\n", htmlBackground, htmlForeground, htmlBackground)
+	b.WriteString("</head>\n<body>\n<pre>")
+	for _, l := range t.allLines() {
+		writeHTMLLine(&b, l)
+		b.WriteByte('\n')
+	}
+	b.WriteString("</pre>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// writeHTMLLine writes l as escaped text, wrapping runs of the same
+// non-default style in spans. Trailing default-styled blanks are dropped.
+func writeHTMLLine(b *strings.Builder, l line) {
+	end := len(l)
+	for end > 0 && l[end-1].content == "" && l[end-1].width == 1 && l[end-1].style == (style{}) {
+		end--
+	}
+	var cur style
+	for _, c := range l[:end] {
+		if c.width == 0 {
+			continue // second half of a wide character
+		}
+		if c.style != cur {
+			if cur != (style{}) {
+				b.WriteString("</span>")
+			}
+			if c.style != (style{}) {
+				fmt.Fprintf(b, "<span style=\"%s\">", c.style.css())
+			}
+			cur = c.style
+		}
+		if c.content == "" {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(html.EscapeString(c.content))
+		}
+	}
+	if cur != (style{}) {
+		b.WriteString("</span>")
+	}
+}
@@ -0,0 +1,237 @@
+package vt
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// color is a cell color: the zero value is the terminal default, otherwise
+// the top byte is the kind and the rest an ANSI palette index or 0xRRGGBB.
+type color uint32
+
+const (
+	colorIndexed color = 1 << 24
+	colorRGB     color = 2 << 24
+	colorKind    color = 0xff << 24
+)
+
+func indexed(n int) color { return colorIndexed | color(n&0xff) }
+
+func rgb(r, g, b int) color {
+	return colorRGB | color(r&0xff)<<16 | color(g&0xff)<<8 | color(b&0xff)
+}
+
+type attr uint8
+
+const (
+	attrBold attr = 1 << iota
+	attrFaint
+	attrItalic
+	attrUnderline
+	attrBlink
+	attrReverse
+	attrHidden
+	attrStrike
+)
+
+// style is the graphic rendition of a cell, as set by SGR.
+type style struct {
+	fg, bg color
+	attrs  attr
+}
+
+// apply updates s with the parameters of an SGR sequence.
+func (s *style) apply(params ansi.Params) {
+	if len(params) == 0 {
+		*s = style{}
+		return
+	}
+	for i := 0; i < len(params); i++ {
+		p := params[i].Param(0)
+		switch {
+		case p == 0:
+			*s = style{}
+		case p == 1:
+			s.attrs |= attrBold
+		case p == 2:
+			s.attrs |= attrFaint
+		case p == 3:
+			s.attrs |= attrItalic
+		case p == 4:
+			// 4:0 turns underline off; 4:n picks an underline style.
+			s.attrs |= attrUnderline
+			if params[i].HasMore() && i+1 < len(params) {
+				if params[i+1].Param(1) == 0 {
+					s.attrs &^= attrUnderline
+				}
+				i += subParams(params[i:])
+			}
+		case p == 5 || p == 6:
+			s.attrs |= attrBlink
+		case p == 7:
+			s.attrs |= attrReverse
+		case p == 8:
+			s.attrs |= attrHidden
+		case p == 9:
+			s.attrs |= attrStrike
+		case p == 21:
+			s.attrs |= attrUnderline
+		case p == 22:
+			s.attrs &^= attrBold | attrFaint
+		case p == 23:
+			s.attrs &^= attrItalic
+		case p == 24:
+			s.attrs &^= attrUnderline
+		case p == 25:
+			s.attrs &^= attrBlink
+		case p == 27:
+			s.attrs &^= attrReverse
+		case p == 28:
+			s.attrs &^= attrHidden
+		case p == 29:
+			s.attrs &^= attrStrike
+		case p >= 30 && p <= 37:
+			s.fg = indexed(p - 30)
+		case p == 38:
+			c, n := readColor(params[i:])
+			s.fg = c
+			i += n
+		case p == 39:
+			s.fg = 0
+		case p >= 40 && p <= 47:
+			s.bg = indexed(p - 40)
+		case p == 48:
+			c, n := readColor(params[i:])
+			s.bg = c
+			i += n
+		case p == 49:
+			s.bg = 0
+		case p == 58: // underline color: parsed only to skip its arguments
+			_, n := readColor(params[i:])
+			i += n
+		case p >= 90 && p <= 97:
+			s.fg = indexed(p - 90 + 8)
+		case p >= 100 && p <= 107:
+			s.bg = indexed(p - 100 + 8)
+		}
+	}
+}
+
+// subParams returns how many colon-separated sub-parameters follow
+// params[0].
+func subParams(params ansi.Params) int {
+	n := 0
+	for n < len(params)-1 && params[n].HasMore() {
+		n++
+	}
+	return n
+}
+
+// readColor parses the color following 38, 48 or 58 in params[0], in either
+// the colon form (38:5:n, 38:2::r:g:b, 38:2:r:g:b) or the semicolon form
+// (38;5;n, 38;2;r;g;b). It returns the color and how many parameters after
+// params[0] it consumed.
+func readColor(params ansi.Params) (color, int) {
+	arg := func(i int) int {
+		if i < len(params) {
+			return params[i].Param(0)
+		}
+		return 0
+	}
+	if params[0].HasMore() {
+		n := subParams(params)
+		switch arg(1) {
+		case 5:
+			return indexed(arg(2)), n
+		case 2:
+			if n >= 5 { // with color space id
+				return rgb(arg(3), arg(4), arg(5)), n
+			}
+			return rgb(arg(2), arg(3), arg(4)), n
+		}
+		return 0, n
+	}
+	switch arg(1) {
+	case 5:
+		return indexed(arg(2)), min(2, len(params)-1)
+	case 2:
+		return rgb(arg(2), arg(3), arg(4)), min(4, len(params)-1)
+	}
+	return 0, min(1, len(params)-1)
+}
+
+// sgr returns the sequence that switches from any state to s.
+func (s style) sgr() string {
+	var b strings.Builder
+	b.WriteString("\x1b[0")
+	for i, code := range []string{"1", "2", "3", "4", "5", "7", "8", "9"} {
+		if s.attrs&(1<<i) != 0 {
+			b.WriteString(";" + code)
+		}
+	}
+	writeColor(&b, s.fg, 30, 90, "38")
+	writeColor(&b, s.bg, 40, 100, "48")
+	b.WriteByte('m')
+	return b.String()
+}
+
+// writeColor appends c as an SGR color parameter. The 16 basic colors use
+// their short codes so that they follow the user's palette.
+func writeColor(b *strings.Builder, c color, base, bright int, extended string) {
+	switch c & colorKind {
+	case colorIndexed:
+		n := int(c & 0xff)
+		switch {
+		case n < 8:
+			b.WriteString(";" + strconv.Itoa(base+n))
+		case n < 16:
+			b.WriteString(";" + strconv.Itoa(bright+n-8))
+		default:
+			b.WriteString(";" + extended + ";5;" + strconv.Itoa(n))
+		}
+	case colorRGB:
+		b.WriteString(";" + extended + ";2;" +
+			strconv.Itoa(int(c>>16&0xff)) + ";" +
+			strconv.Itoa(int(c>>8&0xff)) + ";" +
+			strconv.Itoa(int(c&0xff)))
+	}
+}
+
+// renderLines renders each line as text with SGR sequences, dropping
+// trailing default-styled blanks. Lines never leave a style active.
+func renderLines(lines []line) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		end := len(l)
+		for end > 0 && l[end-1].content == "" && l[end-1].width == 1 && l[end-1].style == (style{}) {
+			end--
+		}
+		var b strings.Builder
+		var cur style
+		for _, c := range l[:end] {
+			if c.width == 0 {
+				continue // second half of a wide character
+			}
+			if c.style != cur {
+				if c.style == (style{}) {
+					b.WriteString("\x1b[m")
+				} else {
+					b.WriteString(c.style.sgr())
+				}
+				cur = c.style
+			}
+			if c.content == "" {
+				b.WriteByte(' ')
+			} else {
+				b.WriteString(c.content)
+			}
+		}
+		if cur != (style{}) {
+			b.WriteString("\x1b[m")
+		}
+		out[i] = b.String()
+	}
+	return out
+}
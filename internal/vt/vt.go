@@ -0,0 +1,506 @@
+// Package vt is a small virtual terminal emulator. It applies the output of
+// terminal programs (text, cursor movement, erasing, scrolling, colors) to a
+// grid of styled cells and renders the result back as ANSI text, so that a
+// session's history can be previewed the way it looks on screen.
+//
+// Only what is needed to reproduce screen contents is implemented: there is
+// no input handling and replies to queries are never sent.
+package vt
+
+import (
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+)
+
+// cell is one grid position. A wide character occupies its own cell with
+// width 2 followed by a continuation cell with width 0.
+type cell struct {
+	content string // "" renders as a space
+	width   int
+	style   style
+}
+
+type line []cell
+
+type cursor struct {
+	x, y     int
+	pen      style
+	wrapNext bool
+	charsets [2]bool
+	gl       int
+}
+
+// Terminal is a virtual terminal. The zero value is not usable; use New.
+type Terminal struct {
+	width, height int
+
+	screen     []line
+	mainScreen []line // saved main screen while the alternate screen is active
+	alt        bool
+	scrollback []line
+	maxHistory int
+	truncated  bool // scrollback lines were dropped
+
+	cursor
+	saved    cursor
+	autowrap bool
+	top, bot int // scroll region, inclusive
+	lastRune rune
+
+	parser *ansi.Parser
+}
+
+// New returns a width×height terminal that keeps up to scrollback lines
+// scrolled off the top of the main screen.
+func New(width, height, scrollback int) *Terminal {
+	t := &Terminal{
+		width:      max(width, 1),
+		height:     max(height, 1),
+		maxHistory: max(scrollback, 0),
+	}
+	t.reset()
+	t.parser = ansi.NewParser()
+	t.parser.SetHandler(ansi.Handler{
+		Print:     t.print,
+		Execute:   t.execute,
+		HandleCsi: t.handleCSI,
+		HandleEsc: t.handleESC,
+	})
+	return t
+}
+
+func (t *Terminal) reset() {
+	t.screen = t.blankLines(t.height)
+	t.mainScreen = nil
+	t.alt = false
+	t.cursor = cursor{}
+	t.saved = cursor{}
+	t.autowrap = true
+	t.top, t.bot = 0, t.height-1
+}
+
+// Write feeds terminal output to the emulator. It never fails.
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.parser.Parse(p)
+	return len(p), nil
+}
+
+// Screen renders the visible screen, one string per row, with trailing
+// blank rows removed.
+func (t *Terminal) Screen() []string {
+	return renderLines(trimBlankLines(t.screen))
+}
+
+// Lines renders the scrollback followed by the main screen (even while the
+// alternate screen is active), with trailing blank rows removed.
+func (t *Terminal) Lines() []string {
+	return renderLines(t.allLines())
+}
+
+// allLines returns the scrollback followed by the main screen, without
+// trailing blank rows.
+func (t *Terminal) allLines() []line {
+	main := t.screen
+	if t.alt {
+		main = t.mainScreen
+	}
+	history := t.history()
+	all := make([]line, 0, len(history)+len(main))
+	all = append(all, history...)
+	all = append(all, main...)
+	return trimBlankLines(all)
+}
+
+// Truncated reports whether lines were dropped from the scrollback because
+// it was full.
+func (t *Terminal) Truncated() bool {
+	return t.truncated
+}
+
+// history returns the last maxHistory scrollback lines.
+func (t *Terminal) history() []line {
+	return t.scrollback[max(len(t.scrollback)-t.maxHistory, 0):]
+}
+
+func trimBlankLines(lines []line) []line {
+	for len(lines) > 0 && lines[len(lines)-1].blank() {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func (l line) blank() bool {
+	for _, c := range l {
+		if c.content != "" || c.style != (style{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// blankCell is what erasing leaves behind: a space in the current
+// background color.
+func (t *Terminal) blankCell() cell {
+	return cell{width: 1, style: style{bg: t.pen.bg}}
+}
+
+func (t *Terminal) blankLine() line {
+	l := make(line, t.width)
+	for i := range l {
+		l[i] = t.blankCell()
+	}
+	return l
+}
+
+func (t *Terminal) blankLines(n int) []line {
+	lines := make([]line, n)
+	for i := range lines {
+		lines[i] = t.blankLine()
+	}
+	return lines
+}
+
+// print writes r at the cursor and advances it, wrapping at the right
+// margin when autowrap is on.
+func (t *Terminal) print(r rune) {
+	if t.charsets[t.gl] {
+		r = decSpecialGraphics(r)
+	}
+	w := runewidth.RuneWidth(r)
+	if w == 0 {
+		t.appendCombining(r)
+		return
+	}
+	if t.wrapNext && t.autowrap {
+		t.x = 0
+		t.lineFeed()
+	}
+	t.wrapNext = false
+	if w == 2 && t.x == t.width-1 {
+		if !t.autowrap {
+			return
+		}
+		t.setCell(t.x, t.y, t.blankCell())
+		t.x = 0
+		t.lineFeed()
+	}
+	t.setCell(t.x, t.y, cell{content: string(r), width: w, style: t.pen})
+	if w == 2 {
+		t.setCell(t.x+1, t.y, cell{style: t.pen})
+	}
+	t.lastRune = r
+	t.x += w
+	if t.x >= t.width {
+		t.x = t.width - 1
+		t.wrapNext = true
+	}
+}
+
+// appendCombining attaches a zero-width rune to the previously printed cell.
+func (t *Terminal) appendCombining(r rune) {
+	x := t.x
+	if !t.wrapNext {
+		x--
+	}
+	row := t.screen[t.y]
+	for x > 0 && row[x].width == 0 {
+		x--
+	}
+	if x >= 0 && row[x].content != "" {
+		row[x].content += string(r)
+	}
+}
+
+// setCell writes c at (x, y), blanking the other half of any wide character
+// it overwrites.
+func (t *Terminal) setCell(x, y int, c cell) {
+	if x < 0 || x >= t.width {
+		return
+	}
+	row := t.screen[y]
+	if row[x].width == 0 && x > 0 && row[x-1].width == 2 {
+		row[x-1] = t.blankCell()
+	}
+	if row[x].width == 2 && x+1 < t.width && c.width != 2 {
+		row[x+1] = t.blankCell()
+	}
+	row[x] = c
+}
+
+func (t *Terminal) execute(b byte) {
+	switch b {
+	case ansi.CR:
+		t.x = 0
+		t.wrapNext = false
+	case ansi.LF, ansi.VT, ansi.FF:
+		// History streams and captured panes separate rows with a bare
+		// "\n", so line feed always implies carriage return (LNM).
+		t.x = 0
+		t.wrapNext = false
+		t.lineFeed()
+	case ansi.BS:
+		if t.x > 0 {
+			t.x--
+		}
+		t.wrapNext = false
+	case ansi.HT:
+		t.x = min((t.x/8+1)*8, t.width-1)
+	case ansi.SO:
+		t.gl = 1
+	case ansi.SI:
+		t.gl = 0
+	}
+}
+
+// lineFeed moves the cursor down, scrolling the region at its bottom.
+func (t *Terminal) lineFeed() {
+	switch {
+	case t.y == t.bot:
+		t.scrollUp(1)
+	case t.y < t.height-1:
+		t.y++
+	}
+}
+
+// reverseIndex moves the cursor up, scrolling the region at its top.
+func (t *Terminal) reverseIndex() {
+	switch {
+	case t.y == t.top:
+		t.scrollDown(1)
+	case t.y > 0:
+		t.y--
+	}
+}
+
+// scrollUp scrolls the scroll region up by n lines. Lines leaving the top
+// of the main screen go to the scrollback.
+func (t *Terminal) scrollUp(n int) {
+	n = min(n, t.bot-t.top+1)
+	if !t.alt && t.top == 0 && t.maxHistory > 0 {
+		t.scrollback = append(t.scrollback, t.screen[:n]...)
+		t.truncated = t.truncated || len(t.scrollback) > t.maxHistory
+		// Trim in batches so long histories stay linear.
+		if len(t.scrollback) > 2*t.maxHistory {
+			t.scrollback = append(t.scrollback[:0:0], t.history()...)
+		}
+	}
+	t.shiftUp(n)
+}
+
+// shiftUp moves the lines of the scroll region up by n, discarding the
+// top ones.
+func (t *Terminal) shiftUp(n int) {
+	n = min(n, t.bot-t.top+1)
+	region := t.screen[t.top : t.bot+1]
+	copy(region, region[n:])
+	for i := len(region) - n; i < len(region); i++ {
+		region[i] = t.blankLine()
+	}
+}
+
+// scrollDown scrolls the scroll region down by n lines.
+func (t *Terminal) scrollDown(n int) {
+	n = min(n, t.bot-t.top+1)
+	region := t.screen[t.top : t.bot+1]
+	copy(region[n:], region)
+	for i := 0; i < n; i++ {
+		region[i] = t.blankLine()
+	}
+}
+
+func (t *Terminal) moveTo(x, y int) {
+	t.x = min(max(x, 0), t.width-1)
+	t.y = min(max(y, 0), t.height-1)
+	t.wrapNext = false
+}
+
+func (t *Terminal) eraseCells(y, from, to int) {
+	for x := max(from, 0); x < min(to, t.width); x++ {
+		t.setCell(x, y, t.blankCell())
+	}
+}
+
+func (t *Terminal) handleCSI(cmd ansi.Cmd, params ansi.Params) {
+	param := func(i, def int) int {
+		v, _, _ := params.Param(i, def)
+		if v == 0 && def > 0 {
+			return def
+		}
+		return v
+	}
+
+	switch cmd.Prefix() {
+	case '?':
+		if cmd.Final() == 'h' || cmd.Final() == 'l' {
+			for i := range params {
+				t.setPrivateMode(param(i, 0), cmd.Final() == 'h')
+			}
+		}
+		return
+	case 0:
+	default:
+		return
+	}
+	if cmd.Intermediate() != 0 {
+		return
+	}
+
+	switch cmd.Final() {
+	case '@': // ICH
+		row := t.screen[t.y]
+		n := min(param(0, 1), t.width-t.x)
+		copy(row[t.x+n:], row[t.x:])
+		t.eraseCells(t.y, t.x, t.x+n)
+	case 'A': // CUU
+		t.moveTo(t.x, t.y-param(0, 1))
+	case 'B', 'e': // CUD, VPR
+		t.moveTo(t.x, t.y+param(0, 1))
+	case 'C', 'a': // CUF, HPR
+		t.moveTo(t.x+param(0, 1), t.y)
+	case 'D': // CUB
+		t.moveTo(t.x-param(0, 1), t.y)
+	case 'E': // CNL
+		t.moveTo(0, t.y+param(0, 1))
+	case 'F': // CPL
+		t.moveTo(0, t.y-param(0, 1))
+	case 'G', '`': // CHA, HPA
+		t.moveTo(param(0, 1)-1, t.y)
+	case 'H', 'f': // CUP
+		t.moveTo(param(1, 1)-1, param(0, 1)-1)
+	case 'd': // VPA
+		t.moveTo(t.x, param(0, 1)-1)
+	case 'J': // ED
+		switch param(0, 0) {
+		case 0:
+			t.eraseCells(t.y, t.x, t.width)
+			for y := t.y + 1; y < t.height; y++ {
+				t.screen[y] = t.blankLine()
+			}
+		case 1:
+			for y := 0; y < t.y; y++ {
+				t.screen[y] = t.blankLine()
+			}
+			t.eraseCells(t.y, 0, t.x+1)
+		case 2, 3:
+			t.screen = t.blankLines(t.height)
+		}
+	case 'K': // EL
+		switch param(0, 0) {
+		case 0:
+			t.eraseCells(t.y, t.x, t.width)
+		case 1:
+			t.eraseCells(t.y, 0, t.x+1)
+		case 2:
+			t.eraseCells(t.y, 0, t.width)
+		}
+	case 'L', 'M': // IL, DL
+		if t.y < t.top || t.y > t.bot {
+			return
+		}
+		top := t.top
+		t.top = t.y
+		if cmd.Final() == 'L' {
+			t.scrollDown(param(0, 1))
+		} else {
+			t.shiftUp(param(0, 1)) // deleted lines never reach the scrollback
+		}
+		t.top = top
+		t.x = 0
+	case 'P': // DCH
+		row := t.screen[t.y]
+		n := min(param(0, 1), t.width-t.x)
+		copy(row[t.x:], row[t.x+n:])
+		t.eraseCells(t.y, t.width-n, t.width)
+	case 'S': // SU
+		t.scrollUp(param(0, 1))
+	case 'T': // SD
+		t.scrollDown(param(0, 1))
+	case 'X': // ECH
+		t.eraseCells(t.y, t.x, t.x+param(0, 1))
+	case 'b': // REP
+		if t.lastRune != 0 {
+			for range min(param(0, 1), t.width*t.height) {
+				t.print(t.lastRune)
+			}
+		}
+	case 'm': // SGR
+		t.pen.apply(params)
+	case 'r': // DECSTBM
+		top, bot := param(0, 1)-1, param(1, t.height)-1
+		if top < bot && bot < t.height {
+			t.top, t.bot = top, bot
+			t.moveTo(0, 0)
+		}
+	case 's': // SCOSC
+		t.saved = t.cursor
+	case 'u': // SCORC
+		t.restoreCursor()
+	}
+}
+
+func (t *Terminal) setPrivateMode(mode int, on bool) {
+	switch mode {
+	case 7: // DECAWM
+		t.autowrap = on
+	case 47, 1047, 1049: // alternate screen
+		if on == t.alt {
+			return
+		}
+		if mode == 1049 && on {
+			t.saved = t.cursor
+		}
+		if on {
+			t.mainScreen = t.screen
+			t.screen = t.blankLines(t.height)
+		} else {
+			t.screen = t.mainScreen
+			t.mainScreen = nil
+		}
+		t.alt = on
+		if mode == 1049 && !on {
+			t.restoreCursor()
+		}
+	}
+}
+
+func (t *Terminal) restoreCursor() {
+	t.cursor = t.saved
+	t.moveTo(t.x, t.y)
+}
+
+func (t *Terminal) handleESC(cmd ansi.Cmd) {
+	switch cmd.Intermediate() {
+	case '(', ')':
+		t.charsets[cmd.Intermediate()-'('] = cmd.Final() == '0'
+		return
+	case 0:
+	default:
+		return
+	}
+	switch cmd.Final() {
+	case '7': // DECSC
+		t.saved = t.cursor
+	case '8': // DECRC
+		t.restoreCursor()
+	case 'D': // IND
+		t.lineFeed()
+	case 'E': // NEL
+		t.x = 0
+		t.lineFeed()
+	case 'M': // RI
+		t.reverseIndex()
+	case 'c': // RIS
+		t.reset()
+	}
+}
+
+// decGraphics maps 0x60–0x7e in the DEC Special Graphics charset
+// (ESC ( 0), used by curses programs for box drawing, to Unicode.
+var decGraphics = []rune("◆▒␉␌␍␊°±␤␋┘┐┌└┼⎺⎻─⎼⎽├┤┴┬│≤≥π≠£·")
+
+func decSpecialGraphics(r rune) rune {
+	if r < 0x60 || r > 0x7e {
+		return r
+	}
+	return decGraphics[r-0x60]
+}
@@ -0,0 +1,136 @@
+package vt
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func feed(t *Terminal, s string) *Terminal {
+	t.Write([]byte(s))
+	return t
+}
+
+func plain(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = ansi.Strip(l)
+	}
+	return out
+}
+
+func TestTextWrapsAndTrims(t *testing.T) {
+	term := feed(New(5, 5, 0), "hello world\n\n")
+	want := []string{"hello", " worl", "d"}
+	if got := plain(term.Screen()); !slices.Equal(got, want) {
+		t.Fatalf("Screen = %q, want %q", got, want)
+	}
+}
+
+func TestCursorMovementIsApplied(t *testing.T) {
+	// A full-screen redraw: clear, draw a status line at the bottom, then
+	// go back and overwrite part of the first row.
+	term := feed(New(10, 3, 0), "\x1b[2J\x1b[3;1Hstatus\x1b[1;1Habcdef\x1b[1;3HXY\x1b[2;5H\x1b[1K")
+	want := []string{"abXYef", "", "status"}
+	if got := plain(term.Screen()); !slices.Equal(got, want) {
+		t.Fatalf("Screen = %q, want %q", got, want)
+	}
+}
+
+func TestCarriageReturnAndEraseLine(t *testing.T) {
+	term := feed(New(20, 2, 0), "progress 10%\rprogress 100%\r\x1b[Kdone")
+	if got := plain(term.Screen()); !slices.Equal(got, []string{"done"}) {
+		t.Fatalf("Screen = %q, want [done]", got)
+	}
+}
+
+func TestColorsAreRendered(t *testing.T) {
+	term := feed(New(20, 1, 0), "\x1b[1;31mred\x1b[0m \x1b[38;5;208mor\x1b[38:2::1:2:3mrgb\x1b[m")
+	got := term.Screen()[0]
+	want := "\x1b[0;1;31mred\x1b[m \x1b[0;38;5;208mor\x1b[0;38;2;1;2;3mrgb\x1b[m"
+	if got != want {
+		t.Fatalf("Screen = %q, want %q", got, want)
+	}
+}
+
+func TestBackgroundSurvivesErase(t *testing.T) {
+	term := feed(New(4, 1, 0), "\x1b[44m\x1b[K")
+	if got := term.Screen()[0]; got != "\x1b[0;44m    \x1b[m" {
+		t.Fatalf("Screen = %q, want a blue line", got)
+	}
+}
+
+func TestScrollbackAndLines(t *testing.T) {
+	term := New(10, 2, 2)
+	for _, s := range []string{"one", "two", "three", "four", "five"} {
+		feed(term, s+"\n")
+	}
+	// "five\n" leaves the cursor on an empty last row.
+	if got := plain(term.Screen()); !slices.Equal(got, []string{"five"}) {
+		t.Fatalf("Screen = %q", got)
+	}
+	if got, want := plain(term.Lines()), []string{"three", "four", "five"}; !slices.Equal(got, want) {
+		t.Fatalf("Lines = %q, want %q (scrollback capped at 2)", got, want)
+	}
+	if !term.Truncated() {
+		t.Fatal("Truncated() = false after dropping scrollback")
+	}
+}
+
+func TestAlternateScreen(t *testing.T) {
+	term := feed(New(10, 2, 10), "shell$ vim\n\x1b[?1049h\x1b[Hediting")
+	if got := plain(term.Screen()); !slices.Equal(got, []string{"editing"}) {
+		t.Fatalf("alt Screen = %q", got)
+	}
+	if got := plain(term.Lines()); !slices.Equal(got, []string{"shell$ vim"}) {
+		t.Fatalf("Lines during alt screen = %q", got)
+	}
+	feed(term, "\x1b[?1049l")
+	if got := plain(term.Screen()); !slices.Equal(got, []string{"shell$ vim"}) {
+		t.Fatalf("Screen after leaving alt = %q", got)
+	}
+}
+
+func TestScrollRegion(t *testing.T) {
+	// Scrolling inside rows 2-3 leaves the header and footer alone.
+	term := feed(New(10, 4, 10), "head\nA\nB\nfoot\x1b[2;3r\x1b[3;1H\nC")
+	want := []string{"head", "B", "C", "foot"}
+	if got := plain(term.Screen()); !slices.Equal(got, want) {
+		t.Fatalf("Screen = %q, want %q", got, want)
+	}
+	if len(term.scrollback) != 0 {
+		t.Fatalf("region scroll leaked into scrollback: %d lines", len(term.scrollback))
+	}
+}
+
+func TestWideCharactersAndLineDrawing(t *testing.T) {
+	term := feed(New(6, 3, 0), "日本語x\n\x1b(0lqqk\x1b(B!")
+	want := []string{"日本語", "x", "┌──┐!"}
+	if got := plain(term.Screen()); !slices.Equal(got, want) {
+		t.Fatalf("Screen = %q, want %q", got, want)
+	}
+
+	// Overwriting half of a wide character blanks the other half.
+	feed(term, "\x1b[1;2HZ")
+	if got := plain(term.Screen())[0]; got != " Z本語" {
+		t.Fatalf("row 0 = %q, want %q", got, " Z本語")
+	}
+}
+
+func TestHTMLKeepsColorsAndEscapes(t *testing.T) {
+	term := feed(New(20, 2, 10), "a<b>&\n\x1b[1;31mred\x1b[m \x1b[38;5;196mx\x1b[7my\x1b[m\n")
+	got := term.HTML("demo <1>")
+	for _, want := range []string{
+		"<title>demo &lt;1&gt;</title>",
+		"<pre>a&lt;b&gt;&amp;\n",
+		`<span style="color:#cd0000;font-weight:bold">red</span> `,
+		`<span style="color:#ff0000">x</span>`,
+		`<span style="color:#1c1c1c;background:#ff0000">y</span>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package zmx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Backend is a terminal session manager whose sessions zsm can list and
+// control. zmx is the default; tmux and GNU screen are also supported.
+type Backend interface {
+	// Name identifies the backend and tags its sessions, e.g. "tmux".
+	Name() string
+	List(h Host) ([]Session, error)
+	Kill(s Session) error
+	// History returns the session's scrollback, with escape sequences where
+	// the backend provides them. Closing the reader releases the process or
+	// file behind it and reports any error it hit.
+	History(ctx context.Context, s Session) (io.ReadCloser, error)
+	// AttachArgs returns the argv that attaches to s, creating it (and
+	// running command) if it does not exist yet.
+	AttachArgs(s Session, command []string) []string
+	// Create starts a detached session running command (or a shell) in dir.
+	Create(name, dir string, command []string) error
+	// Send types in into the session as if from an attached client.
+	Send(s Session, in Input) error
+}
+
+// BackendNames lists the supported backends in display order.
+var BackendNames = []string{"zmx", "tmux", "screen"}
+
+var registry = map[string]Backend{
+	"zmx":    zmxBackend{},
+	"tmux":   tmuxBackend{},
+	"screen": screenBackend{},
+}
+
+var active = []Backend{zmxBackend{}}
+
+// UseBackends selects the active backends from a comma-separated list of
+// names. "auto" selects every backend whose binary is on PATH. Sessions
+// are listed from all active backends; the first one is used to create
+// new sessions.
+func UseBackends(spec string) error {
+	var selected []Backend
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "auto" {
+			for _, n := range BackendNames {
+				if _, err := deps.lookPath(n); err == nil {
+					selected = append(selected, registry[n])
+				}
+			}
+			continue
+		}
+		b, ok := registry[name]
+		if !ok {
+			return fmt.Errorf("unknown backend %q (want %s or auto)", name, strings.Join(BackendNames, ", "))
+		}
+		if _, err := deps.lookPath(name); err != nil {
+			return fmt.Errorf("%s not found in PATH", name)
+		}
+		selected = append(selected, b)
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("none of %s found in PATH", strings.Join(BackendNames, ", "))
+	}
+	active = selected
+	return nil
+}
+
+// ActiveBackends returns the names of the active backends.
+func ActiveBackends() []string {
+	names := make([]string, len(active))
+	for i, b := range active {
+		names[i] = b.Name()
+	}
+	return names
+}
+
+func backendFor(s Session) Backend {
+	if b, ok := registry[s.Backend]; ok {
+		return b
+	}
+	return zmxBackend{}
+}
+
+func primaryBackend() Backend {
+	return active[0]
+}
+
+// cmdReader adapts a started command's stdout into an io.ReadCloser whose
+// Close waits for the command to exit.
+type cmdReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func startReader(cmd *exec.Cmd) (io.ReadCloser, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = io.Discard
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmdReader{ReadCloser: stdout, cmd: cmd}, nil
+}
+
+func (r cmdReader) Close() error {
+	// Drain so the child never blocks on a full pipe before exiting.
+	io.Copy(io.Discard, r.ReadCloser)
+	return r.cmd.Wait()
+}
+
+// tempFileReader deletes its file once closed.
+type tempFileReader struct {
+	*os.File
+}
+
+func (r tempFileReader) Close() error {
+	err := r.File.Close()
+	os.Remove(r.File.Name())
+	return err
+}
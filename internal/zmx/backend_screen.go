@@ -0,0 +1,130 @@
+package zmx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type screenBackend struct{}
+
+func (screenBackend) Name() string { return "screen" }
+
+// List parses `screen -ls`. PID is the SCREEN server process, so memory
+// and uptime cover every window. screen does not report a start directory.
+func (screenBackend) List(h Host) ([]Session, error) {
+	// screen -ls exits non-zero both when sessions exist and when none do,
+	// depending on the version, so only fail if nothing parseable came back.
+	out, err := h.runCombinedOutput("screen", "-ls")
+	sessions := parseScreenList(string(out))
+	for i := range sessions {
+		sessions[i].Host = h.Name
+	}
+	if err != nil && len(sessions) == 0 && !strings.Contains(string(out), "No Sockets found") {
+		return nil, fmt.Errorf("screen -ls: %w\n%s", err, out)
+	}
+	return sessions, nil
+}
+
+func parseScreenList(out string) []Session {
+	var sessions []Session
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		pid, name, ok := strings.Cut(fields[0], ".")
+		if !ok || len(fields) < 2 {
+			continue
+		}
+		if _, err := strconv.Atoi(pid); err != nil {
+			continue
+		}
+		clients := 0
+		if strings.Contains(strings.ToLower(fields[len(fields)-1]), "attached") {
+			clients = 1
+		}
+		sessions = append(sessions, Session{
+			Backend: "screen",
+			Name:    name,
+			PID:     pid,
+			Clients: clients,
+		})
+	}
+	return sessions
+}
+
+// target returns the pid-qualified session id screen expects for -S/-x.
+func (screenBackend) target(s Session) string {
+	if s.PID == "" {
+		return s.Name
+	}
+	return s.PID + "." + s.Name
+}
+
+func (b screenBackend) Kill(s Session) error {
+	out, err := hostOf(s).runCombinedOutput("screen", "-S", b.target(s), "-X", "quit")
+	if err != nil {
+		return fmt.Errorf("screen quit %s: %w\n%s", s.Name, err, out)
+	}
+	return nil
+}
+
+// History has screen write its scrollback to a temporary file, since
+// hardcopy cannot write to stdout. The output carries no colors. Only local
+// screen sessions are supported.
+func (b screenBackend) History(ctx context.Context, s Session) (io.ReadCloser, error) {
+	if !hostOf(s).local() {
+		return nil, fmt.Errorf("screen history is not supported over ssh")
+	}
+	f, err := os.CreateTemp("", "zsm-screen-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	out, err := deps.commandContext(ctx, "screen", "-S", b.target(s), "-X", "hardcopy", "-h", f.Name()).CombinedOutput()
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("screen hardcopy %s: %w\n%s", s.Name, err, out)
+	}
+	r, err := os.Open(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return tempFileReader{r}, nil
+}
+
+func (b screenBackend) AttachArgs(s Session, command []string) []string {
+	if s.PID != "" {
+		return []string{"screen", "-x", b.target(s)}
+	}
+	return append([]string{"screen", "-S", s.Name}, command...)
+}
+
+func (screenBackend) Create(name, dir string, command []string) error {
+	cmd := deps.command("screen", append([]string{"-dmS", name}, command...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("screen -dmS %s: %w\n%s", name, err, out)
+	}
+	return nil
+}
+
+// screenEscaper protects text from the ^X and backslash escapes that
+// screen's stuff command applies to its argument.
+var screenEscaper = strings.NewReplacer(`\`, `\\`, "^", `\^`)
+
+// Send stuffs the input into the session's current window.
+func (b screenBackend) Send(s Session, in Input) error {
+	text := screenEscaper.Replace(in.Text) + "\r"
+	if len(in.Keys) > 0 {
+		text = in.keyBytes()
+	}
+	out, err := hostOf(s).runCombinedOutput("screen", "-S", b.target(s), "-X", "stuff", text)
+	if err != nil {
+		return fmt.Errorf("screen stuff %s: %w\n%s", s.Name, err, out)
+	}
+	return nil
+}
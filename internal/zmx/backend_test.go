@@ -0,0 +1,203 @@
+package zmx
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTmuxList(t *testing.T) {
+	out := "work\t4242\t1\t/home/me/work\tvim\nscratch\t4300\t0\t/tmp\tzsh\n"
+	got := parseTmuxList(out)
+	if len(got) != 2 {
+		t.Fatalf("parseTmuxList returned %d sessions, want 2: %+v", len(got), got)
+	}
+	want := Session{Backend: "tmux", Name: "work", PID: "4242", Clients: 1, StartedIn: "/home/me/work", Cmd: "vim"}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Fatalf("parseTmuxList[0] = %+v, want %+v", got[0], want)
+	}
+	if got[1].Key() != "tmux:scratch" {
+		t.Fatalf("Key() = %q, want tmux:scratch", got[1].Key())
+	}
+}
+
+func TestParseScreenList(t *testing.T) {
+	out := "There are screens on:\n" +
+		"\t12345.build\t(03/10/2026 10:00:00 AM)\t(Detached)\n" +
+		"\t678.pts-0.host\t(Attached)\n" +
+		"2 Sockets in /run/screen/S-me.\n"
+	got := parseScreenList(out)
+	if len(got) != 2 {
+		t.Fatalf("parseScreenList returned %d sessions, want 2: %+v", len(got), got)
+	}
+	if got[0].Name != "build" || got[0].PID != "12345" || got[0].Clients != 0 {
+		t.Fatalf("unexpected first session: %+v", got[0])
+	}
+	if got[1].Name != "pts-0.host" || got[1].Clients != 1 {
+		t.Fatalf("unexpected second session: %+v", got[1])
+	}
+	if args := AttachArgs(got[0], nil); !slices.Equal(args, []string{"screen", "-x", "12345.build"}) {
+		t.Fatalf("AttachArgs = %v", args)
+	}
+}
+
+func TestSessionKeyDefaultsToBareName(t *testing.T) {
+	for _, s := range []Session{{Name: "api"}, {Name: "api", Backend: "zmx"}} {
+		if s.Key() != "api" {
+			t.Fatalf("Key() = %q, want api", s.Key())
+		}
+	}
+}
+
+func TestUseBackendsAutoDetects(t *testing.T) {
+	origDeps, origActive := deps, active
+	defer func() { deps, active = origDeps, origActive }()
+
+	deps.lookPath = func(file string) (string, error) {
+		if file == "tmux" || file == "screen" {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	if err := UseBackends("auto"); err != nil {
+		t.Fatalf("UseBackends(auto) error: %v", err)
+	}
+	if got := ActiveBackends(); !slices.Equal(got, []string{"tmux", "screen"}) {
+		t.Fatalf("ActiveBackends() = %v, want [tmux screen]", got)
+	}
+	if err := UseBackends("zmx"); err == nil {
+		t.Fatal("UseBackends(zmx) should fail when zmx is not installed")
+	}
+	if err := UseBackends("nope"); err == nil {
+		t.Fatal("UseBackends(nope) should fail")
+	}
+}
+
+func TestFetchSessionsDegradesUnreachableHost(t *testing.T) {
+	origDeps, origActive, origHosts := deps, active, remoteHosts
+	defer func() { deps, active, remoteHosts = origDeps, origActive, origHosts }()
+
+	deps.command = func(name string, arg ...string) *exec.Cmd {
+		if name == "ssh" {
+			return exec.Command("sh", "-c", "echo 'ssh: connect to host build1: Connection refused' >&2; exit 255")
+		}
+		return exec.Command("sh", "-c", "printf 'session_name=demo\\tpid=1\\tclients=0\\n'")
+	}
+	active = []Backend{zmxBackend{}}
+	UseHosts([]string{"build1"})
+
+	got, err := FetchSessions()
+	if err != nil {
+		t.Fatalf("FetchSessions error: %v", err)
+	}
+	if len(got) != 2 || got[0].Key() != "demo" {
+		t.Fatalf("unexpected sessions: %+v", got)
+	}
+	if !got[1].Degraded() || got[1].Host != "build1" || !strings.Contains(got[1].Error, "Connection refused") {
+		t.Fatalf("expected degraded entry for build1, got %+v", got[1])
+	}
+}
+
+func TestRemoteAttachArgs(t *testing.T) {
+	s := Session{Name: "it's", Host: "build1"}
+	got := AttachArgs(s, nil)
+	want := []string{"ssh", "-t", "build1", "--", `zmx attach 'it'\''s'`}
+	if !slices.Equal(got, want) {
+		t.Fatalf("AttachArgs = %q, want %q", got, want)
+	}
+	if s.Key() != "it's@build1" {
+		t.Fatalf("Key() = %q", s.Key())
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"  htop  -d 5 ", []string{"htop", "-d", "5"}},
+		{`bash -c 'make && make test'`, []string{"bash", "-c", "make && make test"}},
+		{`echo "it's \"here\" \n" a\ b`, []string{"echo", `it's "here" \n`, "a b"}},
+		{`printf ''`, []string{"printf", ""}},
+	}
+	for _, c := range cases {
+		got, err := SplitCommand(c.in)
+		if err != nil || !slices.Equal(got, c.want) {
+			t.Errorf("SplitCommand(%q) = %q, %v; want %q", c.in, got, err, c.want)
+		}
+	}
+	for _, in := range []string{`echo 'oops`, `echo "oops`, `echo \`} {
+		if _, err := SplitCommand(in); err == nil {
+			t.Errorf("SplitCommand(%q) should fail", in)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	got, err := ParseKeys("Ctrl-C, ^d C-z ctrl+a Enter escape")
+	want := []string{"ctrl-c", "ctrl-d", "ctrl-z", "ctrl-a", "enter", "esc"}
+	if err != nil || !slices.Equal(got, want) {
+		t.Fatalf("ParseKeys = %q, %v; want %q", got, err, want)
+	}
+	if in := (Input{Keys: got[:2]}); in.keyBytes() != "\x03\x04" || in.String() != "ctrl-c ctrl-d" {
+		t.Fatalf("keyBytes = %q, String = %q", in.keyBytes(), in.String())
+	}
+	for _, bad := range []string{"", "ctrl-cc", "hyper-x"} {
+		if _, err := ParseKeys(bad); err == nil {
+			t.Errorf("ParseKeys(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSendInputArgs(t *testing.T) {
+	orig := deps
+	defer func() { deps = orig }()
+
+	var calls [][]string
+	deps.command = func(name string, arg ...string) *exec.Cmd {
+		calls = append(calls, append([]string{name}, arg...))
+		return exec.Command("true")
+	}
+
+	if err := SendInput(Session{Name: "api"}, Input{Text: "git pull"}); err != nil {
+		t.Fatalf("SendInput error: %v", err)
+	}
+	// zmx run would follow any keys with Enter.
+	if SendsKeys(Session{Name: "api"}) || !SendsKeys(Session{Name: "work", Backend: "tmux"}) {
+		t.Error("only zmx sessions should refuse keys")
+	}
+	if err := SendInput(Session{Name: "api"}, Input{Keys: []string{"ctrl-c"}}); err == nil {
+		t.Fatal("sending keys to zmx should fail")
+	}
+	if err := SendInput(Session{Name: "work", Backend: "tmux"}, Input{Keys: []string{"ctrl-c", "enter"}}); err != nil {
+		t.Fatalf("SendInput error: %v", err)
+	}
+	if err := SendInput(Session{Name: "work", Backend: "tmux"}, Input{Text: "-h;"}); err != nil {
+		t.Fatalf("SendInput error: %v", err)
+	}
+	if err := SendInput(Session{Name: "build", Backend: "screen"}, Input{Text: `echo ^C \n`}); err != nil {
+		t.Fatalf("SendInput error: %v", err)
+	}
+	want := [][]string{
+		{"zmx", "run", "api", "git pull"},
+		{"tmux", "send-keys", "-t", "=work:", "C-c", "Enter"},
+		{"tmux", "send-keys", "-t", "=work:", "-l", "--", `-h\;`, ";", "send-keys", "-t", "=work:", "Enter"},
+		{"screen", "-S", "build", "-X", "stuff", `echo \^C \\n` + "\r"},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %q", calls)
+	}
+	for i := range want {
+		if !slices.Equal(calls[i], want[i]) {
+			t.Errorf("call %d = %q, want %q", i, calls[i], want[i])
+		}
+	}
+	if err := SendInput(Session{Host: "build1", Error: "down"}, Input{Text: "x"}); err == nil {
+		t.Error("sending to an unreachable host should fail")
+	}
+}
@@ -0,0 +1,102 @@
+package zmx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type tmuxBackend struct{}
+
+func (tmuxBackend) Name() string { return "tmux" }
+
+const tmuxListFormat = "#{session_name}\t#{pane_pid}\t#{session_attached}\t#{session_path}\t#{pane_current_command}"
+
+// List reads `tmux list-sessions`. PID is the active pane's process, so
+// memory and uptime cover that pane's process tree.
+func (tmuxBackend) List(h Host) ([]Session, error) {
+	out, err := h.runCombinedOutput("tmux", "list-sessions", "-F", tmuxListFormat)
+	if err != nil {
+		// No server simply means no sessions.
+		if strings.Contains(string(out), "no server running") || strings.Contains(string(out), "error connecting") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("tmux list-sessions: %w\n%s", err, out)
+	}
+	sessions := parseTmuxList(string(out))
+	for i := range sessions {
+		sessions[i].Host = h.Name
+	}
+	return sessions, nil
+}
+
+func parseTmuxList(out string) []Session {
+	var sessions []Session
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 || fields[0] == "" {
+			continue
+		}
+		clients, _ := strconv.Atoi(fields[2])
+		sessions = append(sessions, Session{
+			Backend:   "tmux",
+			Name:      fields[0],
+			PID:       fields[1],
+			Clients:   clients,
+			StartedIn: fields[3],
+			Cmd:       fields[4],
+		})
+	}
+	return sessions
+}
+
+func (tmuxBackend) Kill(s Session) error {
+	out, err := hostOf(s).runCombinedOutput("tmux", "kill-session", "-t", "="+s.Name)
+	if err != nil {
+		return fmt.Errorf("tmux kill-session %s: %w\n%s", s.Name, err, out)
+	}
+	return nil
+}
+
+func (tmuxBackend) History(ctx context.Context, s Session) (io.ReadCloser, error) {
+	return startReader(hostOf(s).commandContext(ctx, "tmux", "capture-pane", "-p", "-e", "-J", "-S", "-", "-t", "="+s.Name+":"))
+}
+
+func (tmuxBackend) AttachArgs(s Session, command []string) []string {
+	return append([]string{"tmux", "new-session", "-A", "-s", s.Name}, command...)
+}
+
+func (tmuxBackend) Create(name, dir string, command []string) error {
+	args := append([]string{"new-session", "-d", "-s", name, "-c", dir}, command...)
+	out, err := runCombinedOutput("tmux", args...)
+	if err != nil {
+		return fmt.Errorf("tmux new-session %s: %w\n%s", name, err, out)
+	}
+	return nil
+}
+
+// Send uses `tmux send-keys`: text is sent literally followed by Enter, and
+// named keys by their tmux names. The -- keeps text starting with a dash
+// from being read as a flag, and a trailing ; is escaped so that tmux does
+// not take it as a command separator.
+func (tmuxBackend) Send(s Session, in Input) error {
+	target := "=" + s.Name + ":"
+	text := in.Text
+	if strings.HasSuffix(text, ";") {
+		text = text[:len(text)-1] + `\;`
+	}
+	args := []string{"send-keys", "-t", target, "-l", "--", text, ";", "send-keys", "-t", target, "Enter"}
+	if len(in.Keys) > 0 {
+		args = []string{"send-keys", "-t", target}
+		for _, k := range in.Keys {
+			args = append(args, namedKeys[k].tmux)
+		}
+	}
+	out, err := hostOf(s).runCombinedOutput("tmux", args...)
+	if err != nil {
+		return fmt.Errorf("tmux send-keys %s: %w\n%s", s.Name, err, out)
+	}
+	return nil
+}
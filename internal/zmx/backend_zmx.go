@@ -0,0 +1,94 @@
+package zmx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type zmxBackend struct{}
+
+func (zmxBackend) Name() string { return "zmx" }
+
+// List parses `zmx list` output: tab-separated key=value pairs per line.
+func (zmxBackend) List(h Host) ([]Session, error) {
+	out, err := h.runCombinedOutput("zmx", "list")
+	if err != nil {
+		return nil, fmt.Errorf("zmx list: %w\n%s", err, out)
+	}
+
+	var sessions []Session
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		s := Session{Backend: "zmx", Host: h.Name}
+		for _, field := range strings.Split(line, "\t") {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			switch k {
+			case "session_name":
+				s.Name = v
+			case "pid":
+				s.PID = v
+			case "clients":
+				s.Clients, _ = strconv.Atoi(v)
+			case "started_in":
+				s.StartedIn = v
+			case "cmd":
+				s.Cmd = v
+			}
+		}
+		if s.Name != "" {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (zmxBackend) Kill(s Session) error {
+	out, err := hostOf(s).runCombinedOutput("zmx", "kill", s.Name)
+	if err != nil {
+		return fmt.Errorf("zmx kill %s: %w\n%s", s.Name, err, out)
+	}
+	return nil
+}
+
+func (zmxBackend) History(ctx context.Context, s Session) (io.ReadCloser, error) {
+	return startReader(hostOf(s).commandContext(ctx, "zmx", "history", s.Name, "--vt"))
+}
+
+func (zmxBackend) AttachArgs(s Session, command []string) []string {
+	return append([]string{"zmx", "attach", s.Name}, command...)
+}
+
+// Create starts a detached session via `zmx run`.
+func (zmxBackend) Create(name, dir string, command []string) error {
+	cmd := deps.command("zmx", append([]string{"run", name}, command...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("zmx run %s: %w\n%s", name, err, out)
+	}
+	return nil
+}
+
+// Send delivers text through `zmx run`, which types its argument into the
+// session and always submits it with Enter. zmx has no way to send keys
+// without that Enter, so named keys are refused (see SendsKeys).
+func (zmxBackend) Send(s Session, in Input) error {
+	if len(in.Keys) > 0 {
+		return errKeysUnsupported
+	}
+	out, err := hostOf(s).runCombinedOutput("zmx", "run", s.Name, in.Text)
+	if err != nil {
+		return fmt.Errorf("zmx run %s: %w\n%s", s.Name, err, out)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package zmx
+
+import (
+	"strconv"
+	"time"
+)
+
+// CPUSampler turns the cumulative CPU times reported by successive
+// FetchProcessInfo calls into utilization over each interval, so a session
+// that has just started spinning shows up immediately instead of being
+// diluted by its idle history. The zero value is ready to use.
+type CPUSampler struct {
+	last map[string]cpuSample
+}
+
+type cpuSample struct {
+	cpu time.Duration
+	at  time.Time
+}
+
+// Sample sets CPU in info to the utilization since the previous sample of
+// the same session. Sessions seen for the first time, or whose CPU time went
+// backwards (e.g. the PID was reused), keep their lifetime average. Sessions
+// missing from info are forgotten.
+func (c *CPUSampler) Sample(info map[string]ProcessInfo) {
+	next := make(map[string]cpuSample, len(info))
+	for key, pi := range info {
+		if pi.Sampled.IsZero() {
+			continue
+		}
+		if prev, ok := c.last[key]; ok && pi.CPUTime >= prev.cpu && pi.Sampled.After(prev.at) {
+			pi.CPU = cpuPercent(pi.CPUTime-prev.cpu, pi.Sampled.Sub(prev.at))
+			info[key] = pi
+		}
+		next[key] = cpuSample{cpu: pi.CPUTime, at: pi.Sampled}
+	}
+	c.last = next
+}
+
+// cpuPercent returns cpu as a percentage of elapsed wall time on one core.
+func cpuPercent(cpu, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return 100 * cpu.Seconds() / elapsed.Seconds()
+}
+
+// FormatCPU formats a CPU percentage compactly, e.g. "0.4%", "12%" or "250%".
+func FormatCPU(pct float64) string {
+	if pct < 10 {
+		return strconv.FormatFloat(pct, 'f', 1, 64) + "%"
+	}
+	return strconv.FormatFloat(pct, 'f', 0, 64) + "%"
+}
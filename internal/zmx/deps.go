@@ -11,12 +11,14 @@ type runtimeDeps struct {
 	command        func(name string, arg ...string) *exec.Cmd
 	commandContext func(ctx context.Context, name string, arg ...string) *exec.Cmd
 	clipboardWrite func(text string) error
+	lookPath       func(file string) (string, error)
 }
 
 var deps = runtimeDeps{
 	command:        exec.Command,
 	commandContext: exec.CommandContext,
 	clipboardWrite: clipboard.WriteAll,
+	lookPath:       exec.LookPath,
 }
 
 func runCombinedOutput(name string, arg ...string) ([]byte, error) {
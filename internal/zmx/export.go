@@ -0,0 +1,230 @@
+package zmx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mdsakalu/zmx-session-manager/internal/vt"
+)
+
+// ExportTimeout bounds reading a session's full history for export, which
+// can be much larger than what the preview replays.
+const ExportTimeout = 30 * time.Second
+
+// Default terminal size for HTML and asciicast exports. The size a session's
+// output was written for is not known, so it should be generous.
+const (
+	ExportWidth  = 200
+	ExportHeight = 50
+)
+
+// ExportFormat selects the file format written by Export.
+type ExportFormat int
+
+const (
+	ExportText ExportFormat = iota // plain text, escape sequences removed
+	ExportANSI                     // the raw history stream
+	ExportHTML                     // self-contained HTML with colors
+	ExportCast                     // asciicast v2, playable with asciinema
+	ExportFormatCount
+)
+
+func (f ExportFormat) String() string {
+	switch f {
+	case ExportText:
+		return "text"
+	case ExportANSI:
+		return "ansi"
+	case ExportHTML:
+		return "html"
+	case ExportCast:
+		return "cast"
+	}
+	return ""
+}
+
+// Ext returns the file name extension for f, including the dot.
+func (f ExportFormat) Ext() string {
+	switch f {
+	case ExportText:
+		return ".txt"
+	case ExportANSI:
+		return ".ansi"
+	case ExportHTML:
+		return ".html"
+	case ExportCast:
+		return ".cast"
+	}
+	return ""
+}
+
+// ParseExportFormat returns the ExportFormat whose String() equals s.
+func ParseExportFormat(s string) (ExportFormat, error) {
+	for f := ExportFormat(0); f < ExportFormatCount; f++ {
+		if f.String() == s {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown export format %q (want text, ansi, html or cast)", s)
+}
+
+// ExportFormatForPath guesses the format from the extension of path.
+func ExportFormatForPath(path string) (ExportFormat, bool) {
+	ext := filepath.Ext(path)
+	for f := ExportFormat(0); f < ExportFormatCount; f++ {
+		if f.Ext() == ext {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// ExportOptions sizes the virtual terminal that HTML exports are rendered
+// through and that asciicast exports declare.
+type ExportOptions struct {
+	Width, Height int
+}
+
+// ReadHistory returns the session's complete history stream as the backend
+// provides it (`zmx history <name> --vt` for zmx sessions).
+func ReadHistory(s Session) ([]byte, error) {
+	if s.Degraded() {
+		return nil, fmt.Errorf("%s unreachable: %s", s.Host, s.Error)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ExportTimeout)
+	defer cancel()
+
+	r, err := backendFor(s).History(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	data, readErr := io.ReadAll(r)
+	closeErr := r.Close()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, errors.New("timed out")
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return data, closeErr
+}
+
+// Export writes the history stream of session s to w in format f.
+func Export(w io.Writer, s Session, history []byte, f ExportFormat, opts ExportOptions) error {
+	switch f {
+	case ExportText:
+		_, err := io.WriteString(w, stripANSI(string(history)))
+		return err
+	case ExportANSI:
+		_, err := w.Write(history)
+		return err
+	case ExportHTML:
+		term := vt.New(opts.Width, opts.Height, bytes.Count(history, []byte("\n"))+opts.Height)
+		term.Write(history)
+		_, err := io.WriteString(w, term.HTML(s.Key()))
+		return err
+	case ExportCast:
+		return writeCast(w, s, history, opts)
+	}
+	return fmt.Errorf("unknown export format %d", f)
+}
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title"`
+	Env       map[string]string `json:"env"`
+}
+
+// writeCast writes history as an asciicast v2 recording. The history has no
+// timing information, so every line is an output event at time zero and
+// playback shows the final state at once. Bare line feeds get a carriage
+// return, since players do not imply one.
+func writeCast(w io.Writer, s Session, history []byte, opts ExportOptions) error {
+	enc := json.NewEncoder(w)
+	err := enc.Encode(castHeader{
+		Version:   2,
+		Width:     opts.Width,
+		Height:    opts.Height,
+		Timestamp: time.Now().Unix(),
+		Title:     s.Key(),
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	})
+	if err != nil {
+		return err
+	}
+	for chunk := range strings.SplitAfterSeq(string(history), "\n") {
+		if chunk == "" {
+			continue
+		}
+		if strings.HasSuffix(chunk, "\n") && !strings.HasSuffix(chunk, "\r\n") {
+			chunk = chunk[:len(chunk)-1] + "\r\n"
+		}
+		if err := enc.Encode([]any{0.0, "o", chunk}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportFileName returns a file name for exporting s in format f, with
+// characters that are awkward in file names replaced, e.g.
+// "tmux_work@build1.html".
+func ExportFileName(s Session, f ExportFormat) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("._-@", r):
+			return r
+		}
+		return '_'
+	}, s.Key())
+	return name + f.Ext()
+}
+
+// ExportPaths returns a path in dir for each session, named by
+// ExportFileName. Names that collide within the batch, or with a file
+// already in dir, get a numeric suffix such as "work-2.txt", so a bulk
+// export never overwrites anything.
+func ExportPaths(dir string, sessions []Session, f ExportFormat) []string {
+	taken := make(map[string]bool)
+	paths := make([]string, len(sessions))
+	for i, s := range sessions {
+		base := strings.TrimSuffix(ExportFileName(s, f), f.Ext())
+		path := filepath.Join(dir, base+f.Ext())
+		for n := 2; taken[path] || fileExists(path); n++ {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, n, f.Ext()))
+		}
+		taken[path] = true
+		paths[i] = path
+	}
+	return paths
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// ExportToFile reads the history of s and writes it to path in format f.
+func ExportToFile(path string, s Session, f ExportFormat, opts ExportOptions) error {
+	history, err := ReadHistory(s)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := Export(&buf, s, history, f, opts); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
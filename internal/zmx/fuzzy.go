@@ -0,0 +1,166 @@
+package zmx
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Fuzzy scoring, loosely following fzf: every matched character scores,
+// more so at the start of a word or right after another match, and gaps
+// between matches cost a little.
+const (
+	fuzzyScoreMatch          = 16
+	fuzzyGapStart            = -3
+	fuzzyGapExtension        = -1
+	fuzzyBonusBoundary       = 8
+	fuzzyBonusCamel          = 7
+	fuzzyBonusConsecutive    = 4
+	fuzzyFirstCharMultiplier = 2
+)
+
+// FuzzyResult is a session matched by FuzzyFilter.
+type FuzzyResult struct {
+	Session Session
+	Score   int
+	// Positions are the rune offsets in Session.Key() of the characters
+	// that matched, in increasing order.
+	Positions []int
+}
+
+// FuzzyFilter returns the sessions that fuzzy-match pattern, best first.
+// Each space-separated word of pattern must match, in order but not
+// necessarily contiguously, the session's key, alias, tags, directory or
+// command; key matches count double. An empty pattern matches everything in the
+// original order.
+func FuzzyFilter(sessions []Session, pattern string) []FuzzyResult {
+	words := strings.Fields(strings.ToLower(pattern))
+	var results []FuzzyResult
+	for _, s := range sessions {
+		if r, ok := fuzzyMatchSession(s, words); ok {
+			results = append(results, r)
+		}
+	}
+	if len(words) > 0 {
+		slices.SortStableFunc(results, func(a, b FuzzyResult) int {
+			if a.Score != b.Score {
+				return b.Score - a.Score
+			}
+			if c := cmp.Compare(len(a.Session.Key()), len(b.Session.Key())); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Session.Key(), b.Session.Key())
+		})
+	}
+	return results
+}
+
+func fuzzyMatchSession(s Session, words []string) (FuzzyResult, bool) {
+	r := FuzzyResult{Session: s}
+	key := []rune(s.Key())
+	for _, w := range words {
+		pattern := []rune(w)
+		best, ok := 0, false
+		if score, positions, matched := fuzzyMatch(pattern, key); matched {
+			best, ok = 2*score, true
+			r.Positions = append(r.Positions, positions...)
+		}
+		for _, field := range []string{s.Alias, strings.Join(s.Tags, " "), s.StartedIn, s.Cmd} {
+			if score, _, matched := fuzzyMatch(pattern, []rune(field)); matched && (!ok || score > best) {
+				best, ok = score, true
+			}
+		}
+		if !ok {
+			return FuzzyResult{}, false
+		}
+		r.Score += best
+	}
+	slices.Sort(r.Positions)
+	r.Positions = slices.Compact(r.Positions)
+	return r, true
+}
+
+// fuzzyMatch finds the shortest window of text containing pattern's runes
+// in order, case-insensitively, and scores the match within it. pattern
+// must be lowercase.
+func fuzzyMatch(pattern, text []rune) (int, []int, bool) {
+	if len(pattern) == 0 {
+		return 0, nil, true
+	}
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Scan forward for the earliest end, then back for the latest start.
+	pi, end := 0, -1
+	for i, r := range lower {
+		if r == pattern[pi] {
+			pi++
+			if pi == len(pattern) {
+				end = i + 1
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	pi, start := len(pattern)-1, 0
+	for i := end - 1; i >= 0; i-- {
+		if lower[i] == pattern[pi] {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	score, pi := 0, 0
+	var positions []int
+	inGap, prevMatched, firstBonus := false, false, 0
+	for i := start; i < end; i++ {
+		if pi < len(pattern) && lower[i] == pattern[pi] {
+			bonus := fuzzyBonusAt(text, i)
+			if prevMatched {
+				bonus = max(bonus, firstBonus, fuzzyBonusConsecutive)
+			} else {
+				firstBonus = bonus
+			}
+			if pi == 0 {
+				bonus *= fuzzyFirstCharMultiplier
+			}
+			score += fuzzyScoreMatch + bonus
+			positions = append(positions, i)
+			pi++
+			inGap, prevMatched = false, true
+			continue
+		}
+		if inGap {
+			score += fuzzyGapExtension
+		} else {
+			score += fuzzyGapStart
+		}
+		inGap, prevMatched = true, false
+	}
+	return score, positions, true
+}
+
+// fuzzyBonusAt rewards matching text[i] at the start of a word.
+func fuzzyBonusAt(text []rune, i int) int {
+	if i == 0 {
+		return fuzzyBonusBoundary
+	}
+	prev, cur := text[i-1], text[i]
+	switch {
+	case strings.ContainsRune(" /-_.:@", prev):
+		return fuzzyBonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return fuzzyBonusCamel
+	case !unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		return fuzzyBonusCamel
+	}
+	return 0
+}
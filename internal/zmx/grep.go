@@ -0,0 +1,115 @@
+package zmx
+
+import (
+	"context"
+	"sync"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// GrepWorkers is the default number of session histories fetched at once.
+const GrepWorkers = 8
+
+// GrepOptions controls Grep. Histories are replayed through a Width×Height
+// virtual terminal keeping up to Lines rows, as FetchHistory does, so line
+// numbers match what history mode shows at the same size.
+type GrepOptions struct {
+	Width, Height int
+	Lines         int
+	Context       int // lines of context kept before and after each match
+	Workers       int // histories fetched at once; GrepWorkers if <= 0
+}
+
+// GrepMatch is a history line that matched, without escape sequences.
+type GrepMatch struct {
+	Line   int // index into the session's history rows, oldest first
+	Text   string
+	Before []string // up to Context lines preceding Text
+	After  []string // up to Context lines following Text
+}
+
+// GrepResult holds the matches found in one session's history.
+type GrepResult struct {
+	Session Session
+	Matches []GrepMatch
+	Err     error
+}
+
+// Grep searches the history of each session for lines for which match
+// returns true. Histories are fetched by a pool of opts.Workers goroutines,
+// each fetch bounded by HistoryTimeout. Results keep the order of sessions
+// and only include sessions that matched or failed.
+func Grep(sessions []Session, match func(line string) bool, opts GrepOptions) []GrepResult {
+	return GrepContext(context.Background(), sessions, match, opts)
+}
+
+// GrepContext is like Grep, but stops when ctx is done: running fetches are
+// abandoned and the remaining sessions are not searched.
+func GrepContext(ctx context.Context, sessions []Session, match func(line string) bool, opts GrepOptions) []GrepResult {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = GrepWorkers
+	}
+	results := make([]GrepResult, len(sessions))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(sessions)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				s := sessions[i]
+				results[i].Session = s
+				rows, _, err := FetchHistoryContext(ctx, s, opts.Width, opts.Height, opts.Lines)
+				if err != nil {
+					results[i].Err = err
+					continue
+				}
+				plain := make([]string, len(rows))
+				for j, r := range rows {
+					plain[j] = ansi.Strip(r)
+				}
+				results[i].Matches = grepLines(plain, match, opts.Context)
+			}
+		}()
+	}
+feed:
+	for i := range sessions {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var out []GrepResult
+	for _, r := range results {
+		if r.Err != nil || len(r.Matches) > 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// grepLines returns the lines for which match returns true, each with up to
+// context lines around it.
+func grepLines(lines []string, match func(string) bool, context int) []GrepMatch {
+	var matches []GrepMatch
+	for i, l := range lines {
+		if !match(l) {
+			continue
+		}
+		matches = append(matches, GrepMatch{
+			Line:   i,
+			Text:   l,
+			Before: lines[max(i-context, 0):i],
+			After:  lines[i+1 : min(i+1+context, len(lines))],
+		})
+	}
+	return matches
+}
@@ -0,0 +1,124 @@
+package zmx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GroupMode selects how sessions are grouped in the TUI.
+type GroupMode int
+
+const (
+	GroupNone GroupMode = iota
+	GroupByDir
+	GroupByRepo
+	GroupByPrefix
+	GroupByTag
+	GroupModeCount
+)
+
+func (g GroupMode) String() string {
+	switch g {
+	case GroupNone:
+		return "none"
+	case GroupByDir:
+		return "dir"
+	case GroupByRepo:
+		return "repo"
+	case GroupByPrefix:
+		return "prefix"
+	case GroupByTag:
+		return "tag"
+	}
+	return ""
+}
+
+// ParseGroupMode parses a group mode name as printed by GroupMode.String.
+func ParseGroupMode(s string) (GroupMode, error) {
+	for g := GroupNone; g < GroupModeCount; g++ {
+		if g.String() == s {
+			return g, nil
+		}
+	}
+	return GroupNone, fmt.Errorf("unknown group mode %q (want none, dir, repo, prefix or tag)", s)
+}
+
+// Grouper assigns sessions to groups. It caches repository lookups, so a
+// Grouper should be replaced when the sessions are re-listed.
+type Grouper struct {
+	Mode GroupMode
+	// Delimiter splits names for GroupByPrefix: a session belongs to the
+	// group named by everything before the last delimiter, e.g. "proj" for
+	// "proj/api".
+	Delimiter string
+
+	repoRoots map[string]string
+}
+
+// Key returns the group s belongs to, or "" when it has none, such as a
+// name without the delimiter, a directory outside any repository or a
+// session without tags.
+func (g *Grouper) Key(s Session) string {
+	switch g.Mode {
+	case GroupByDir:
+		return withHost(s.DisplayDir(), s)
+	case GroupByRepo:
+		// Repositories can only be found on this machine; remote sessions
+		// are grouped by directory instead.
+		if s.Host != "" {
+			return withHost(s.DisplayDir(), s)
+		}
+		return displayPath(g.repoRoot(s.StartedIn))
+	case GroupByPrefix:
+		if g.Delimiter == "" {
+			return ""
+		}
+		if i := strings.LastIndex(s.Name, g.Delimiter); i > 0 {
+			return withHost(s.Name[:i], s)
+		}
+	case GroupByTag:
+		// Sessions sharing the same set of tags; tags are sorted.
+		return strings.Join(s.Tags, ", ")
+	}
+	return ""
+}
+
+func withHost(key string, s Session) string {
+	if key == "" || s.Host == "" {
+		return key
+	}
+	return key + "@" + s.Host
+}
+
+// repoRoot returns the closest directory at or above dir containing .git,
+// or "" if there is none.
+func (g *Grouper) repoRoot(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	if root, ok := g.repoRoots[dir]; ok {
+		return root
+	}
+	root := ""
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			root = d
+			break
+		}
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+	if g.repoRoots == nil {
+		g.repoRoots = make(map[string]string)
+	}
+	g.repoRoots[dir] = root
+	return root
+}
+
+// displayPath replaces a leading $HOME in path with ~.
+func displayPath(path string) string {
+	return Session{StartedIn: path}.DisplayDir()
+}
@@ -0,0 +1,139 @@
+package zmx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Host is a machine whose sessions zsm manages. The zero Host is the local
+// machine; others are ssh destinations.
+type Host struct {
+	Name string
+}
+
+// sshOptions keep a dead host from hanging a refresh or prompting for a
+// password in the middle of the TUI.
+var sshOptions = []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=5"}
+
+var remoteHosts []Host
+
+// UseHosts sets the remote hosts (ssh destinations) whose zmx sessions are
+// listed alongside the local ones.
+func UseHosts(names []string) {
+	remoteHosts = nil
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			remoteHosts = append(remoteHosts, Host{Name: name})
+		}
+	}
+}
+
+// RemoteHosts returns the names of the configured remote hosts.
+func RemoteHosts() []string {
+	names := make([]string, len(remoteHosts))
+	for i, h := range remoteHosts {
+		names[i] = h.Name
+	}
+	return names
+}
+
+func hostOf(s Session) Host {
+	return Host{Name: s.Host}
+}
+
+func (h Host) local() bool {
+	return h.Name == ""
+}
+
+func (h Host) command(name string, arg ...string) *exec.Cmd {
+	if h.local() {
+		return deps.command(name, arg...)
+	}
+	return deps.command("ssh", h.sshArgs(name, arg)...)
+}
+
+func (h Host) commandContext(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	if h.local() {
+		return deps.commandContext(ctx, name, arg...)
+	}
+	return deps.commandContext(ctx, "ssh", h.sshArgs(name, arg)...)
+}
+
+func (h Host) runCombinedOutput(name string, arg ...string) ([]byte, error) {
+	return h.command(name, arg...).CombinedOutput()
+}
+
+func (h Host) sshArgs(name string, arg []string) []string {
+	args := append([]string{}, sshOptions...)
+	return append(args, h.Name, "--", shellJoin(append([]string{name}, arg...)))
+}
+
+// shellJoin quotes argv for the remote shell that ssh hands the command to.
+func shellJoin(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, a := range argv {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// SplitCommand splits a command line into arguments the way a POSIX shell
+// does: single quotes keep everything literally, double quotes keep all but
+// backslash escapes of " \ $ and `, and a backslash outside quotes escapes
+// the next character. Expansions and operators are not interpreted.
+func SplitCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				cur.WriteRune('\\')
+			}
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
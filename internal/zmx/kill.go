@@ -0,0 +1,197 @@
+package zmx
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// KillStage is one step of a graceful kill. Stages run in order until the
+// session is gone, so that programs such as databases and dev servers get a
+// chance to shut down cleanly before the session is torn down.
+type KillStage int
+
+const (
+	// KillInterrupt sends SIGINT to the foreground process group of the
+	// session's terminal, as if the user had pressed Ctrl-C.
+	KillInterrupt KillStage = iota
+	// KillTerminate sends SIGTERM to the same processes.
+	KillTerminate
+	// KillBackend runs the backend's kill, e.g. `zmx kill`.
+	KillBackend
+	// KillForce sends SIGKILL to the session's whole process tree.
+	KillForce
+	KillStageCount
+)
+
+// Label names the stage for s in logs, e.g. "SIGTERM" or "zmx kill".
+func (st KillStage) Label(s Session) string {
+	switch st {
+	case KillInterrupt:
+		return "SIGINT"
+	case KillTerminate:
+		return "SIGTERM"
+	case KillBackend:
+		return backendFor(s).Name() + " kill"
+	case KillForce:
+		return "SIGKILL"
+	}
+	return "unknown"
+}
+
+// KillStep runs one stage of a graceful kill on s and waits for it to take
+// effect. After a signal, it waits up to grace, or until the signalled
+// processes have exited; after the backend's kill or SIGKILL, up to
+// KillPollAttempts polls. It reports whether s is no longer listed.
+//
+// The signal stages are skipped when grace is zero or nothing is running in
+// the foreground of the session's terminal, i.e. its shell is waiting at a
+// prompt: shells ignore SIGINT and SIGTERM, so signalling them would only
+// wait out the grace period.
+func KillStep(s Session, stage KillStage, grace time.Duration) (bool, error) {
+	if s.Degraded() {
+		return false, fmt.Errorf("%s is unreachable", s.Host)
+	}
+	switch stage {
+	case KillInterrupt, KillTerminate:
+		if grace <= 0 {
+			return false, nil
+		}
+		pids := foregroundProcesses(s)
+		if len(pids) == 0 {
+			return false, nil
+		}
+		sig := "INT"
+		if stage == KillTerminate {
+			sig = "TERM"
+		}
+		signalProcesses(s, sig, pids)
+		return waitGone(s, grace, pids)
+	case KillBackend:
+		if err := KillSession(s); err != nil {
+			return false, err
+		}
+	case KillForce:
+		signalProcesses(s, "KILL", sessionProcesses(s, true))
+	}
+	return waitGone(s, KillPollAttempts*KillPollInterval, nil)
+}
+
+// KillGracefully runs each KillStage on s in turn until it is gone and
+// returns the stage that removed it. If s survives every stage, the error
+// says so, or reports the last stage's failure.
+func KillGracefully(s Session, grace time.Duration) (KillStage, error) {
+	var lastErr error
+	for stage := KillStage(0); stage < KillStageCount; stage++ {
+		gone, err := KillStep(s, stage, grace)
+		if gone {
+			return stage, nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return KillStageCount, lastErr
+	}
+	return KillStageCount, fmt.Errorf("%s is still running after SIGKILL", s.Key())
+}
+
+// sessionProcesses returns the processes running under s's root process,
+// including the root itself if withRoot is set.
+func sessionProcesses(s Session, withRoot bool) []int {
+	root, err := strconv.Atoi(s.PID)
+	if err != nil {
+		return nil
+	}
+	table := readProcessTable(hostOf(s))
+	if _, ok := table.procs[root]; !ok {
+		return nil
+	}
+	pids := table.descendants(root)
+	if withRoot {
+		pids = append([]int{root}, pids...)
+	}
+	return pids
+}
+
+// foregroundProcesses returns the processes of s that are in the
+// foreground process group of a terminal in the session, i.e. what Ctrl-C
+// in an attached client would interrupt. The session's shells, the first
+// processes down the tree with a controlling terminal, are never included:
+// when a shell's own group is in the foreground it is idle at a prompt.
+func foregroundProcesses(s Session) []int {
+	root, err := strconv.Atoi(s.PID)
+	if err != nil {
+		return nil
+	}
+	table := readProcessTable(hostOf(s))
+	if _, ok := table.procs[root]; !ok {
+		return nil
+	}
+	var pids []int
+	var walk func(pid int)
+	walk = func(pid int) {
+		shell := table.procs[pid]
+		if shell.tpgid <= 0 {
+			for _, child := range table.children[pid] {
+				walk(child)
+			}
+			return
+		}
+		if shell.tpgid == shell.pgid {
+			return
+		}
+		for _, p := range table.descendants(pid) {
+			if table.procs[p].pgid == shell.tpgid {
+				pids = append(pids, p)
+			}
+		}
+	}
+	walk(root)
+	return pids
+}
+
+// signalProcesses sends sig (e.g. "TERM") to pids on s's host. Errors are
+// ignored: some processes may already have exited, and the caller checks
+// whether the session went away.
+func signalProcesses(s Session, sig string, pids []int) {
+	if len(pids) == 0 {
+		return
+	}
+	args := []string{"-" + sig}
+	for _, pid := range pids {
+		args = append(args, strconv.Itoa(pid))
+	}
+	hostOf(s).runCombinedOutput("kill", args...)
+}
+
+// waitGone polls until s is no longer listed, for up to timeout. If pids
+// is non-nil it also stops once none of them are running, since the
+// session may outlive the processes that were signalled.
+func waitGone(s Session, timeout time.Duration, pids []int) (bool, error) {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		time.Sleep(KillPollInterval)
+		alive, err := StillAlive([]string{s.Key()})
+		if err != nil {
+			return false, fmt.Errorf("could not confirm %s exited: %w", s.Key(), err)
+		}
+		if len(alive) == 0 {
+			return true, nil
+		}
+		if pids != nil && !anyRunning(hostOf(s), pids) {
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+func anyRunning(h Host, pids []int) bool {
+	table := readProcessTable(h)
+	for _, pid := range pids {
+		if _, ok := table.procs[pid]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package zmx
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Meta is what the user recorded about a session, which zmx itself has no
+// place for.
+type Meta struct {
+	Alias string   `json:"alias,omitempty"` // shown instead of the name
+	Tags  []string `json:"tags,omitempty"`  // sorted, without duplicates
+	Note  string   `json:"note,omitempty"`  // free-form, may span lines
+}
+
+func (m Meta) empty() bool {
+	return m.Alias == "" && len(m.Tags) == 0 && m.Note == ""
+}
+
+// metaEntry is how Meta is saved. It records where the session lives so
+// that Prune only judges sessions it could have seen.
+type metaEntry struct {
+	Meta
+	Backend string `json:"backend,omitempty"`
+	Host    string `json:"host,omitempty"`
+}
+
+// MetaStore keeps session metadata in a JSON file, keyed by session key
+// (see Session.Key), so that it survives restarts of zsm but not of the
+// sessions: Prune forgets sessions that are gone.
+type MetaStore struct {
+	path    string // "" keeps metadata in memory only
+	entries map[string]metaEntry
+}
+
+// LoadMetaStore reads the metadata saved at path. A missing file means
+// nothing was recorded yet.
+func LoadMetaStore(path string) (*MetaStore, error) {
+	st := &MetaStore{path: path, entries: make(map[string]metaEntry)}
+	if path == "" {
+		return st, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &st.entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return st, nil
+}
+
+// Get returns the metadata recorded for the session with the given key. A
+// nil MetaStore has none.
+func (st *MetaStore) Get(key string) Meta {
+	if st == nil {
+		return Meta{}
+	}
+	return st.entries[key].Meta
+}
+
+// Set replaces the metadata of s and saves the store. Empty metadata
+// removes the entry.
+func (st *MetaStore) Set(s Session, m Meta) error {
+	m.Alias = strings.TrimSpace(m.Alias)
+	m.Tags = normalizeTags(m.Tags)
+	m.Note = strings.TrimRight(m.Note, " \t\n")
+	if m.empty() {
+		delete(st.entries, s.Key())
+	} else {
+		st.entries[s.Key()] = metaEntry{Meta: m, Backend: s.Backend, Host: s.Host}
+	}
+	return st.save()
+}
+
+// Apply copies the recorded alias, tags and note into sessions.
+func (st *MetaStore) Apply(sessions []Session) {
+	for i := range sessions {
+		m := st.Get(sessions[i].Key())
+		sessions[i].Alias, sessions[i].Tags, sessions[i].Note = m.Alias, m.Tags, m.Note
+	}
+}
+
+// Prune forgets the sessions not in live, which must be a complete listing
+// of the active backends and hosts, and saves the store if anything
+// changed. Sessions of other backends and hosts are kept. It returns the
+// keys it removed.
+func (st *MetaStore) Prune(live []Session) ([]string, error) {
+	keep := make(map[string]bool, len(live))
+	for _, s := range live {
+		keep[s.Key()] = true
+	}
+	var removed []string
+	for key, e := range st.entries {
+		if !keep[key] && sourceListed(e.Backend, e.Host) {
+			delete(st.entries, key)
+			removed = append(removed, key)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	slices.Sort(removed)
+	return removed, st.save()
+}
+
+// sourceListed reports whether sessions of backend ("" meaning zmx) on host
+// ("" meaning local) are included when listing sessions.
+func sourceListed(backend, host string) bool {
+	return slices.Contains(ActiveBackends(), cmp.Or(backend, "zmx")) &&
+		(host == "" || slices.Contains(RemoteHosts(), host))
+}
+
+func (st *MetaStore) save() error {
+	if st.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(st.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(st.path, append(data, '\n'), 0o644)
+}
+
+// ParseTags splits a list of tags separated by commas or spaces, as typed
+// by the user. A leading # is dropped, so "#ci" and "ci" are the same tag.
+func ParseTags(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	for i, f := range fields {
+		fields[i] = strings.TrimPrefix(f, "#")
+	}
+	return normalizeTags(fields)
+}
+
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
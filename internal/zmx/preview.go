@@ -1,84 +1,98 @@
 package zmx
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"time"
 
-	"github.com/mattn/go-runewidth"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mdsakalu/zmx-session-manager/internal/vt"
 )
 
-// FetchPreview returns the last `lines` lines of `zmx history <name> --vt`,
-// with all ANSI escape sequences stripped. Lines are NOT truncated so that
-// the caller can apply horizontal scrolling before display.
-func FetchPreview(name string, lines int) string {
-	if lines < 1 {
-		lines = 1
-	}
+// HistoryTimeout bounds each call for a session's history, so that one
+// hung session (or ssh host) cannot stall the preview or a grep.
+const HistoryTimeout = 2 * time.Second
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+// FetchPreview returns what a width×height terminal showing the session
+// would display: its history (`zmx history <name> --vt` for zmx sessions) is
+// replayed through a virtual terminal, so cursor movement is applied and
+// colors and attributes are kept as SGR sequences. Rows are not padded;
+// see ScrollPreview.
+func FetchPreview(s Session, width, height int) string {
+	return FetchPreviewContext(context.Background(), s, width, height)
+}
 
-	cmd := deps.commandContext(ctx, "zmx", "history", name, "--vt")
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Sprintf("(preview unavailable: %v)", err)
+// FetchPreviewContext is like FetchPreview, but stops reading the history
+// (and kills the process producing it) when ctx is done.
+func FetchPreviewContext(ctx context.Context, s Session, width, height int) string {
+	if s.Degraded() {
+		return fmt.Sprintf("(%s unreachable: %s)", s.Host, s.Error)
 	}
-	cmd.Stderr = io.Discard
-	if err := cmd.Start(); err != nil {
+	term := vt.New(width, height, 0)
+	if err := replayHistory(ctx, s, term); err != nil {
 		return fmt.Sprintf("(preview unavailable: %v)", err)
 	}
+	return strings.Join(term.Screen(), "\n")
+}
 
-	preview, readErr := tailLinesFromReader(stdout, lines)
-	waitErr := cmd.Wait()
-	if ctx.Err() == context.DeadlineExceeded {
-		return "(preview unavailable: timed out)"
-	}
-	if readErr != nil {
-		return fmt.Sprintf("(preview unavailable: %v)", readErr)
+// FetchHistory replays the session's history like FetchPreview, but returns
+// up to lines rows of scrollback followed by the final screen, oldest first.
+// more reports whether older rows were dropped to stay within lines.
+func FetchHistory(s Session, width, height, lines int) (rows []string, more bool, err error) {
+	return FetchHistoryContext(context.Background(), s, width, height, lines)
+}
+
+// FetchHistoryContext is like FetchHistory, but gives up when ctx is done.
+func FetchHistoryContext(ctx context.Context, s Session, width, height, lines int) (rows []string, more bool, err error) {
+	if s.Degraded() {
+		return nil, false, fmt.Errorf("%s unreachable: %s", s.Host, s.Error)
 	}
-	if waitErr != nil {
-		return fmt.Sprintf("(preview unavailable: %v)", waitErr)
+	term := vt.New(width, height, lines)
+	if err := replayHistory(ctx, s, term); err != nil {
+		return nil, false, err
 	}
-	return preview
+	return term.Lines(), term.Truncated(), nil
 }
 
-func tailLinesFromReader(r io.Reader, lines int) (string, error) {
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+// replayHistory feeds the session's history into term, giving up after
+// HistoryTimeout or when parent is done.
+func replayHistory(parent context.Context, s Session, term *vt.Terminal) error {
+	ctx, cancel := context.WithTimeout(parent, HistoryTimeout)
+	defer cancel()
 
-	tail := make([]string, 0, lines)
-	for scanner.Scan() {
-		tail = append(tail, stripANSI(scanner.Text()))
-		if len(tail) > lines {
-			tail = tail[1:]
-		}
+	r, err := backendFor(s).History(ctx, s)
+	if err != nil {
+		return err
 	}
-	if err := scanner.Err(); err != nil {
-		return "", err
+	_, readErr := io.Copy(term, r)
+	closeErr := r.Close()
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.New("timed out")
+	}
+	if readErr != nil {
+		return readErr
 	}
-	return strings.Join(tail, "\n"), nil
+	return closeErr
 }
 
-// ScrollPreview applies a horizontal offset and width to raw preview text,
-// truncating and padding each line for display in the preview pane.
+// ScrollPreview applies a horizontal offset and width to preview text,
+// cutting and padding each line to exactly maxWidth cells for display in the
+// preview pane. Offsets count cells, and SGR sequences are kept intact so
+// styles carry across the cut.
 func ScrollPreview(raw string, offsetX, maxWidth int) string {
 	lines := strings.Split(raw, "\n")
 	for i, line := range lines {
-		// Skip offsetX cells from the left
-		skipped := 0
-		runeIdx := 0
-		runes := []rune(line)
-		for runeIdx < len(runes) && skipped < offsetX {
-			w := runewidth.RuneWidth(runes[runeIdx])
-			skipped += w
-			runeIdx++
+		cut := ansi.Cut(line, offsetX, offsetX+maxWidth)
+		if pad := maxWidth - ansi.StringWidth(cut); pad > 0 {
+			cut += strings.Repeat(" ", pad)
 		}
-		rest := string(runes[runeIdx:])
-		lines[i] = runewidth.FillRight(runewidth.Truncate(rest, maxWidth, ""), maxWidth)
+		lines[i] = cut
 	}
 	return strings.Join(lines, "\n")
 }
@@ -0,0 +1,207 @@
+//go:build linux
+
+package zmx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// atClockTicks is AT_CLKTCK, the auxiliary vector entry holding USER_HZ.
+const atClockTicks = 17
+
+// clockTicks returns USER_HZ, the unit of the times in /proc/<pid>/stat,
+// which the kernel hands every process in its auxiliary vector. It falls
+// back to 100, the value on most architectures, if that cannot be read.
+var clockTicks = sync.OnceValue(func() time.Duration {
+	if data, err := os.ReadFile("/proc/self/auxv"); err == nil {
+		if hz := parseAuxvClockTicks(data); hz > 0 {
+			return time.Duration(hz)
+		}
+	}
+	return 100
+})
+
+// procStat holds the fields of /proc/<pid>/stat that we use.
+type procStat struct {
+	ppid      int
+	pgid      int
+	tpgid     int
+	cpu       uint64 // utime + stime, in clock ticks
+	startTime uint64 // clock ticks after boot
+}
+
+// procStatus holds the fields of /proc/<pid>/status that we use.
+type procStatus struct {
+	state   byte
+	threads int
+	rss     uint64 // bytes
+}
+
+// readLocalProcessTable reads the local process table from /proc: ppid,
+// process groups, CPU time and start time from /proc/<pid>/stat, and state, thread count and
+// resident memory from /proc/<pid>/status. It is much cheaper than running
+// ps, so it can be sampled on every refresh. It reports false if /proc is
+// unusable.
+func readLocalProcessTable() (processTable, bool) {
+	boot, err := bootTime()
+	if err != nil {
+		return processTable{}, false
+	}
+	dirs, err := os.ReadDir("/proc")
+	if err != nil {
+		return processTable{}, false
+	}
+
+	hz := clockTicks()
+	t := newProcessTable()
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil {
+			continue
+		}
+		// Processes can exit between ReadDir and here; skip them.
+		data, err := os.ReadFile("/proc/" + d.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		st, err := parseProcStat(data)
+		if err != nil {
+			continue
+		}
+		status, err := os.ReadFile("/proc/" + d.Name() + "/status")
+		if err != nil {
+			continue
+		}
+		ss := parseProcStatus(status)
+		t.add(pid, procEntry{
+			ppid:    st.ppid,
+			pgid:    st.pgid,
+			tpgid:   st.tpgid,
+			rss:     ss.rss,
+			start:   boot.Add(time.Duration(st.startTime) * time.Second / hz),
+			threads: ss.threads,
+			state:   ss.state,
+			cpu:     time.Duration(st.cpu) * time.Second / hz,
+		})
+	}
+	return t, true
+}
+
+// parseProcStat parses the contents of /proc/<pid>/stat. The command name
+// (field 2) is parenthesized and may itself contain spaces and parentheses,
+// so fields are counted from the last ')'.
+func parseProcStat(data []byte) (procStat, error) {
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return procStat{}, fmt.Errorf("malformed stat: no command name")
+	}
+	// fields[0] is field 3 (state) in proc(5) numbering.
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 20 {
+		return procStat{}, fmt.Errorf("malformed stat: %d fields", len(fields)+2)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat ppid: %w", err)
+	}
+	pgid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat pgrp: %w", err)
+	}
+	tpgid, err := strconv.Atoi(fields[5])
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat tpgid: %w", err)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat utime: %w", err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat stime: %w", err)
+	}
+	start, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat starttime: %w", err)
+	}
+	return procStat{
+		ppid:      ppid,
+		pgid:      pgid,
+		tpgid:     tpgid,
+		cpu:       utime + stime,
+		startTime: start,
+	}, nil
+}
+
+// parseProcStatus parses the State, Threads and VmRSS lines of
+// /proc/<pid>/status. Fields it cannot find, such as VmRSS of a kernel
+// thread, are left zero.
+func parseProcStatus(data []byte) procStatus {
+	var st procStatus
+	for line := range strings.SplitSeq(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "State":
+			if value != "" {
+				st.state = value[0]
+			}
+		case "Threads":
+			st.threads, _ = strconv.Atoi(value)
+		case "VmRSS":
+			kb, _ := strconv.ParseUint(strings.TrimSuffix(value, " kB"), 10, 64)
+			st.rss = kb * 1024
+		}
+	}
+	return st
+}
+
+// parseAuxvClockTicks returns AT_CLKTCK from an auxiliary vector, as read
+// from /proc/self/auxv: pairs of native words ending with a zero key. It
+// returns 0 if the entry is missing.
+func parseAuxvClockTicks(data []byte) uint64 {
+	word := strconv.IntSize / 8
+	read := func(b []byte) uint64 {
+		if word == 4 {
+			return uint64(binary.NativeEndian.Uint32(b))
+		}
+		return binary.NativeEndian.Uint64(b)
+	}
+	for i := 0; i+2*word <= len(data); i += 2 * word {
+		switch read(data[i:]) {
+		case 0:
+			return 0
+		case atClockTicks:
+			return read(data[i+word:])
+		}
+	}
+	return 0
+}
+
+// bootTime reads the system boot time from the btime line of /proc/stat.
+func bootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for line := range strings.SplitSeq(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, "btime "); ok {
+			secs, err := strconv.ParseInt(strings.TrimSpace(rest), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("malformed btime: %w", err)
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no btime in /proc/stat")
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+package zmx
+
+import (
+	"encoding/binary"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	// Command names may contain spaces and parentheses.
+	data := []byte("4242 (my (odd) cmd) S 4200 4242 4200 0 -1 4194304 82 0 0 0 7 3 0 0 20 0 5 0 159014 2703360 286 18446744073709551615 0 0\n")
+	got, err := parseProcStat(data)
+	if err != nil {
+		t.Fatalf("parseProcStat error: %v", err)
+	}
+	want := procStat{ppid: 4200, pgid: 4242, tpgid: -1, cpu: 10, startTime: 159014}
+	if got != want {
+		t.Fatalf("parseProcStat = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"", "4242 no parens", "4242 (cmd) S 1 2 3"} {
+		if _, err := parseProcStat([]byte(bad)); err == nil {
+			t.Errorf("parseProcStat(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestParseProcStatus(t *testing.T) {
+	data := []byte("Name:\tpostgres\nState:\tS (sleeping)\nPPid:\t4200\nVmRSS:\t    1144 kB\nThreads:\t5\n")
+	want := procStatus{state: 'S', threads: 5, rss: 1144 * 1024}
+	if got := parseProcStatus(data); got != want {
+		t.Errorf("parseProcStatus = %+v, want %+v", got, want)
+	}
+	// Kernel threads have no VmRSS.
+	if got := parseProcStatus([]byte("Name:\tkthreadd\nState:\tI (idle)\nThreads:\t1\n")); got != (procStatus{state: 'I', threads: 1}) {
+		t.Errorf("parseProcStatus(kernel thread) = %+v", got)
+	}
+}
+
+func TestParseAuxvClockTicks(t *testing.T) {
+	word := strconv.IntSize / 8
+	auxv := func(pairs ...uint64) []byte {
+		var b []byte
+		for _, v := range pairs {
+			if word == 4 {
+				b = binary.NativeEndian.AppendUint32(b, uint32(v))
+			} else {
+				b = binary.NativeEndian.AppendUint64(b, v)
+			}
+		}
+		return b
+	}
+	if got := parseAuxvClockTicks(auxv(6, 4096, atClockTicks, 250, 0, 0)); got != 250 {
+		t.Errorf("parseAuxvClockTicks = %d, want 250", got)
+	}
+	if got := parseAuxvClockTicks(auxv(6, 4096, 0, 0, atClockTicks, 250)); got != 0 {
+		t.Errorf("parseAuxvClockTicks should stop at AT_NULL, got %d", got)
+	}
+	if clockTicks() <= 0 {
+		t.Errorf("clockTicks() = %d", clockTicks())
+	}
+}
+
+func TestReadLocalProcessTableFindsSelf(t *testing.T) {
+	table, ok := readLocalProcessTable()
+	if !ok {
+		t.Skip("/proc not available")
+	}
+	self, ok := table.procs[os.Getpid()]
+	if !ok {
+		t.Fatalf("own pid %d missing from /proc table", os.Getpid())
+	}
+	if self.threads < 1 || self.rss == 0 || self.start.IsZero() {
+		t.Errorf("own entry incomplete: %+v", self)
+	}
+	if time.Since(self.start) < 0 || time.Since(self.start) > time.Hour {
+		t.Errorf("own start time %v implausible", self.start)
+	}
+}
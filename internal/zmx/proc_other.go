@@ -0,0 +1,9 @@
+//go:build !linux
+
+package zmx
+
+// readLocalProcessTable reports false: there is no /proc to read, so the
+// process table comes from ps.
+func readLocalProcessTable() (processTable, bool) {
+	return processTable{}, false
+}
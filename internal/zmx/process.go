@@ -4,61 +4,220 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // ProcessInfo holds per-session process data fetched asynchronously.
 type ProcessInfo struct {
-	Memory uint64
-	Uptime int // seconds
+	Memory  uint64
+	Uptime  int       // seconds
+	Started time.Time // start of the session process; zero if unknown
+	Threads int       // threads across the process tree; 0 if unknown
+	State   string    // state of the session process, e.g. "sleeping"
+
+	// CPUTime is the cumulative CPU time of the process tree as of Sampled.
+	// CPU is its utilization in percent of one core: averaged over the
+	// session's lifetime by FetchProcessInfo, and over the time since the
+	// previous sample once passed through a CPUSampler.
+	CPUTime time.Duration
+	CPU     float64
+	Sampled time.Time
 }
 
-// FetchProcessInfo returns a map of session name → ProcessInfo.
-// Uses a single `ps` call to read all processes, then walks the tree in memory.
-func FetchProcessInfo(sessions []Session) map[string]ProcessInfo {
-	rssMap, childMap, etimeMap := readProcessTable()
+// procEntry is one process from a host's process table.
+type procEntry struct {
+	ppid    int
+	pgid    int       // process group
+	tpgid   int       // foreground process group of its terminal; <= 0 without one
+	rss     uint64    // bytes
+	start   time.Time // zero if unknown
+	threads int       // 0 if unknown
+	state   byte      // ps/proc state letter, e.g. 'S'; 0 if unknown
+	cpu     time.Duration
+}
 
-	result := make(map[string]ProcessInfo, len(sessions))
-	for _, s := range sessions {
-		pid, err := strconv.Atoi(s.PID)
-		if err != nil {
-			continue
+// processTable is a snapshot of every process on a host.
+type processTable struct {
+	procs    map[int]procEntry
+	rss      map[int]uint64
+	children map[int][]int
+}
+
+// descendants returns every process below pid, parents before children.
+func (t processTable) descendants(pid int) []int {
+	var pids []int
+	var walk func(pid int)
+	walk = func(pid int) {
+		for _, child := range t.children[pid] {
+			pids = append(pids, child)
+			walk(child)
 		}
-		result[s.Name] = ProcessInfo{
-			Memory: sumTreeRSS(pid, rssMap, childMap),
-			Uptime: etimeMap[pid],
+	}
+	walk(pid)
+	return pids
+}
+
+func newProcessTable() processTable {
+	return processTable{
+		procs:    make(map[int]procEntry),
+		rss:      make(map[int]uint64),
+		children: make(map[int][]int),
+	}
+}
+
+func (t processTable) add(pid int, e procEntry) {
+	t.procs[pid] = e
+	t.rss[pid] = e.rss
+	t.children[e.ppid] = append(t.children[e.ppid], pid)
+}
+
+// FetchProcessInfo returns a map of session key (see Session.Key) → ProcessInfo.
+// Reads the whole process table once per host, then walks the tree in memory.
+// Hosts are queried concurrently.
+func FetchProcessInfo(sessions []Session) map[string]ProcessInfo {
+	byHost := make(map[Host][]Session)
+	for _, s := range sessions {
+		if !s.Degraded() {
+			byHost[hostOf(s)] = append(byHost[hostOf(s)], s)
 		}
 	}
+
+	result := make(map[string]ProcessInfo, len(sessions))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for h, hostSessions := range byHost {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			table := readProcessTable(h)
+			now := time.Now()
+			mu.Lock()
+			defer mu.Unlock()
+			for _, s := range hostSessions {
+				pid, err := strconv.Atoi(s.PID)
+				if err != nil {
+					continue
+				}
+				result[s.Key()] = table.info(pid, now)
+			}
+		}()
+	}
+	wg.Wait()
 	return result
 }
 
-// readProcessTable parses `ps -eo pid,ppid,rss,etime` into RSS, children, and etime maps.
-// RSS values from ps are in KiB. Etime is parsed into seconds.
-func readProcessTable() (rss map[int]uint64, children map[int][]int, etime map[int]int) {
-	rss = make(map[int]uint64)
-	children = make(map[int][]int)
-	etime = make(map[int]int)
+// info summarizes the process tree rooted at pid.
+func (t processTable) info(pid int, now time.Time) ProcessInfo {
+	info := ProcessInfo{Memory: sumTreeRSS(pid, t.rss, t.children), Sampled: now}
+	root, ok := t.procs[pid]
+	if !ok {
+		return info
+	}
+	info.Threads = int(sumTree(pid, t.children, func(p int) uint64 {
+		return uint64(t.procs[p].threads)
+	}))
+	info.CPUTime = time.Duration(sumTree(pid, t.children, func(p int) uint64 {
+		return uint64(t.procs[p].cpu)
+	}))
+	info.State = stateName(root.state)
+	if !root.start.IsZero() {
+		info.Started = root.start
+		info.Uptime = int(now.Sub(root.start) / time.Second)
+		info.CPU = cpuPercent(info.CPUTime, now.Sub(root.start))
+	}
+	return info
+}
+
+// ApplyProcessInfo copies the process data in info onto the matching
+// sessions. It reports whether any session was updated.
+func ApplyProcessInfo(sessions []Session, info map[string]ProcessInfo) bool {
+	updated := false
+	for i := range sessions {
+		if pi, ok := info[sessions[i].Key()]; ok {
+			sessions[i].Memory = pi.Memory
+			sessions[i].Uptime = pi.Uptime
+			sessions[i].StartedAt = pi.Started
+			sessions[i].Threads = pi.Threads
+			sessions[i].State = pi.State
+			sessions[i].CPU = pi.CPU
+			updated = true
+		}
+	}
+	return updated
+}
 
-	out, err := runCombinedOutput("ps", "-eo", "pid,ppid,rss,etime")
+// readProcessTable reads the process table on h, natively from /proc where
+// supported (see readLocalProcessTable) and from ps otherwise.
+func readProcessTable(h Host) processTable {
+	if h.local() {
+		if t, ok := readLocalProcessTable(); ok {
+			return t
+		}
+	}
+	return readPSTable(h)
+}
+
+// readPSTable parses `ps -eo pid,ppid,pgid,tpgid,rss,etime,time,state` on h. RSS values
+// from ps are in KiB. Etime only has second resolution, so start times are
+// approximate and thread counts are unknown.
+func readPSTable(h Host) processTable {
+	t := newProcessTable()
+	out, err := h.runCombinedOutput("ps", "-eo", "pid,ppid,pgid,tpgid,rss,etime,time,state")
 	if err != nil {
-		return
+		return t
 	}
 
+	now := time.Now()
 	for _, line := range strings.Split(string(out), "\n") {
 		fields := strings.Fields(line)
-		if len(fields) != 4 {
+		if len(fields) != 8 {
 			continue
 		}
 		pid, err1 := strconv.Atoi(fields[0])
 		ppid, err2 := strconv.Atoi(fields[1])
-		kib, err3 := strconv.ParseUint(fields[2], 10, 64)
+		kib, err3 := strconv.ParseUint(fields[4], 10, 64)
 		if err1 != nil || err2 != nil || err3 != nil {
 			continue
 		}
-		rss[pid] = kib * 1024 // KiB → bytes
-		children[ppid] = append(children[ppid], pid)
-		etime[pid] = parseEtime(fields[3])
+		pgid, _ := strconv.Atoi(fields[2])
+		tpgid, _ := strconv.Atoi(fields[3])
+		t.add(pid, procEntry{
+			ppid:  ppid,
+			pgid:  pgid,
+			tpgid: tpgid,
+			rss:   kib * 1024, // KiB → bytes
+			start: now.Add(-time.Duration(parseEtime(fields[5])) * time.Second),
+			cpu:   parseCPUTime(fields[6]),
+			state: fields[7][0],
+		})
 	}
-	return
+	return t
+}
+
+// stateName expands a ps/proc state letter.
+func stateName(c byte) string {
+	switch c {
+	case 'R':
+		return "running"
+	case 'S':
+		return "sleeping"
+	case 'D':
+		return "disk sleep"
+	case 'I':
+		return "idle"
+	case 'T':
+		return "stopped"
+	case 't':
+		return "tracing stop"
+	case 'Z':
+		return "zombie"
+	case 'X':
+		return "dead"
+	case 'U':
+		return "uninterruptible"
+	}
+	return ""
 }
 
 // parseEtime parses ps etime format into seconds.
@@ -78,6 +237,22 @@ func parseEtime(s string) int {
 	return total + days*86400
 }
 
+// parseCPUTime parses ps's cumulative CPU time column: "[dd-]hh:mm:ss" on
+// Linux, "mm:ss.ss" on macOS and the BSDs.
+func parseCPUTime(s string) time.Duration {
+	var days int64
+	if i := strings.Index(s, "-"); i >= 0 {
+		days, _ = strconv.ParseInt(s[:i], 10, 64)
+		s = s[i+1:]
+	}
+	var secs float64
+	for _, p := range strings.Split(s, ":") {
+		n, _ := strconv.ParseFloat(p, 64)
+		secs = secs*60 + n
+	}
+	return time.Duration(days)*24*time.Hour + time.Duration(secs*float64(time.Second))
+}
+
 // FormatUptime formats seconds as a compact human-readable duration.
 func FormatUptime(secs int) string {
 	switch {
@@ -94,9 +269,14 @@ func FormatUptime(secs int) string {
 
 // sumTreeRSS sums RSS for a process and all its descendants.
 func sumTreeRSS(pid int, rss map[int]uint64, children map[int][]int) uint64 {
-	total := rss[pid]
+	return sumTree(pid, children, func(p int) uint64 { return rss[p] })
+}
+
+// sumTree sums value over a process and all its descendants.
+func sumTree(pid int, children map[int][]int, value func(pid int) uint64) uint64 {
+	total := value(pid)
 	for _, child := range children[pid] {
-		total += sumTreeRSS(child, rss, children)
+		total += sumTree(child, children, value)
 	}
 	return total
 }
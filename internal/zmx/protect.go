@@ -0,0 +1,149 @@
+package zmx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Protection tracks the sessions that zsm refuses to kill: those whose
+// name or key matches a configured glob, and those the user marked, which
+// are saved one key per line so that they stay protected across runs.
+type Protection struct {
+	patterns []*regexp.Regexp
+	path     string // "" keeps marks in memory only
+	marked   map[string]bool
+}
+
+// NewProtection compiles patterns and loads the marks saved at path. A
+// missing file means nothing is marked yet.
+func NewProtection(patterns []string, path string) (*Protection, error) {
+	p := &Protection{path: path, marked: make(map[string]bool)}
+	for _, pat := range patterns {
+		re, err := regexp.Compile(GlobRegexp(pat))
+		if err != nil {
+			return nil, fmt.Errorf("protect: invalid pattern %q: %v", pat, err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	if path == "" {
+		return p, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if key := strings.TrimSpace(sc.Text()); key != "" {
+			p.marked[key] = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Protected reports whether s must not be killed without confirmation. A
+// nil Protection protects nothing.
+func (p *Protection) Protected(s Session) bool {
+	return p.Pinned(s) || (p != nil && p.marked[s.Key()])
+}
+
+// Pinned reports whether s matches a configured pattern, so that it stays
+// protected whatever the marks say.
+func (p *Protection) Pinned(s Session) bool {
+	if p == nil {
+		return false
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(s.Name) || re.MatchString(s.Key()) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMarked marks or unmarks the sessions with the given keys and saves
+// the marks.
+func (p *Protection) SetMarked(keys []string, on bool) error {
+	for _, key := range keys {
+		if on {
+			p.marked[key] = true
+		} else {
+			delete(p.marked, key)
+		}
+	}
+	return p.save()
+}
+
+// Prune forgets the marks of sessions that no longer exist, like
+// MetaStore.Prune: live must list every session of the active backends and
+// hosts, and marks of sessions from other sources are kept. It returns the
+// keys it removed.
+func (p *Protection) Prune(live []Session) ([]string, error) {
+	if p == nil {
+		return nil, nil
+	}
+	keep := make(map[string]bool, len(live))
+	for _, s := range live {
+		keep[s.Key()] = true
+	}
+	var removed []string
+	for key := range p.marked {
+		if !keep[key] && sourceListed(keySource(key)) {
+			delete(p.marked, key)
+			removed = append(removed, key)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	slices.Sort(removed)
+	return removed, p.save()
+}
+
+// keySource returns the backend and host a session key (see Session.Key)
+// refers to. A local session with "@" in its name is taken to be remote,
+// so its mark is kept rather than pruned by mistake.
+func keySource(key string) (backend, host string) {
+	if b, rest, ok := strings.Cut(key, ":"); ok {
+		if _, known := registry[b]; known {
+			backend, key = b, rest
+		}
+	}
+	if i := strings.LastIndexByte(key, '@'); i >= 0 {
+		host = key[i+1:]
+	}
+	return backend, host
+}
+
+func (p *Protection) save() error {
+	if p.path == "" {
+		return nil
+	}
+	keys := make([]string, 0, len(p.marked))
+	for key := range p.marked {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key + "\n")
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, []byte(b.String()), 0o644)
+}
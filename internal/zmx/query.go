@@ -0,0 +1,445 @@
+package zmx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query is a parsed session filter (see ParseQuery). The zero Query, and a
+// nil *Query, match every session.
+type Query struct {
+	root  queryNode
+	terms []string
+}
+
+// ParseQuery parses a filter such as
+//
+//	api dir:~/work -cmd:vim (clients:0 OR mem:>1G) uptime:>2d
+//
+// A bare word matches sessions whose key, alias or directory contains it.
+// Field terms are name: (key or alias), dir:, cmd: and note: (substrings,
+// case-insensitive, ~ expands to $HOME), tag: (one of the session's tags,
+// exactly) and clients:, mem:, uptime:, cpu: and pid:, which take a
+// number, size or duration optionally prefixed by >, >=, <, <= or =.
+// A word that starts with a backend name, such as the key tmux:work, is a
+// bare word. Terms are ANDed; OR (or |) joins
+// alternatives, a leading - or ! (or NOT) negates a term, parentheses
+// group, and double quotes keep spaces, colons and keywords in a value.
+func ParseQuery(s string) (*Query, error) {
+	toks, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return &Query{}, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+	q := &Query{root: root}
+	collectTerms(root, false, &q.terms)
+	return q, nil
+}
+
+// Match reports whether s satisfies the query.
+func (q *Query) Match(s Session) bool {
+	return q == nil || q.root == nil || q.root.match(s)
+}
+
+// Terms returns the values of the query's non-negated bare and name:
+// terms, lowercased, for highlighting matches in session names.
+func (q *Query) Terms() []string {
+	if q == nil {
+		return nil
+	}
+	return q.terms
+}
+
+type queryNode interface {
+	match(s Session) bool
+}
+
+type andNode []queryNode
+
+func (n andNode) match(s Session) bool {
+	for _, c := range n {
+		if !c.match(s) {
+			return false
+		}
+	}
+	return true
+}
+
+type orNode []queryNode
+
+func (n orNode) match(s Session) bool {
+	for _, c := range n {
+		if c.match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+type notNode struct{ node queryNode }
+
+func (n notNode) match(s Session) bool { return !n.node.match(s) }
+
+// textTerm matches a substring of one or more text fields. field is "" for
+// a bare word.
+type textTerm struct {
+	field string
+	value string // lowercased
+}
+
+func (t textTerm) match(s Session) bool {
+	switch t.field {
+	case "name":
+		return contains(s.Key(), t.value) || contains(s.Alias, t.value)
+	case "dir":
+		return contains(s.StartedIn, t.value)
+	case "cmd":
+		return contains(s.Cmd, t.value)
+	case "note":
+		return contains(s.Note, t.value)
+	case "tag":
+		return slices.ContainsFunc(s.Tags, func(tag string) bool {
+			return strings.ToLower(tag) == t.value
+		})
+	}
+	return contains(s.Key(), t.value) || contains(s.Alias, t.value) || contains(s.StartedIn, t.value)
+}
+
+func contains(s, lowerSub string) bool {
+	return strings.Contains(strings.ToLower(s), lowerSub)
+}
+
+// numTerm compares a numeric field against a value.
+type numTerm struct {
+	field string
+	op    string // one of < <= > >= =
+	value float64
+}
+
+func (t numTerm) match(s Session) bool {
+	var v float64
+	switch t.field {
+	case "clients":
+		v = float64(s.Clients)
+	case "mem":
+		v = float64(s.Memory)
+	case "uptime":
+		v = float64(s.Uptime)
+	case "cpu":
+		v = s.CPU
+	case "pid":
+		pid, err := strconv.Atoi(s.PID)
+		if err != nil {
+			return false
+		}
+		v = float64(pid)
+	}
+	switch t.op {
+	case "<":
+		return v < t.value
+	case "<=":
+		return v <= t.value
+	case ">":
+		return v > t.value
+	case ">=":
+		return v >= t.value
+	}
+	return v == t.value
+}
+
+func collectTerms(n queryNode, negated bool, terms *[]string) {
+	switch n := n.(type) {
+	case andNode:
+		for _, c := range n {
+			collectTerms(c, negated, terms)
+		}
+	case orNode:
+		for _, c := range n {
+			collectTerms(c, negated, terms)
+		}
+	case notNode:
+		collectTerms(n.node, !negated, terms)
+	case textTerm:
+		if !negated && (n.field == "" || n.field == "name") {
+			*terms = append(*terms, n.value)
+		}
+	}
+}
+
+// newTerm builds the term for field:value, or a bare word when field is "".
+func newTerm(field, value string) (queryNode, error) {
+	if field != "" && value == "" {
+		return nil, fmt.Errorf("%s: missing value", field)
+	}
+	switch field {
+	case "", "name", "cmd", "note":
+		return textTerm{field: field, value: strings.ToLower(value)}, nil
+	case "dir":
+		if value == "~" || strings.HasPrefix(value, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				value = home + value[1:]
+			}
+		}
+		return textTerm{field: field, value: strings.ToLower(value)}, nil
+	case "tag":
+		return textTerm{field: field, value: strings.ToLower(strings.TrimPrefix(value, "#"))}, nil
+	case "clients", "mem", "uptime", "cpu", "pid":
+		op := "="
+		for _, prefix := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(value, prefix) {
+				op, value = prefix, value[len(prefix):]
+				break
+			}
+		}
+		if value == "" {
+			return nil, fmt.Errorf("%s: missing value after %s", field, op)
+		}
+		n, err := parseQueryNumber(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		return numTerm{field: field, op: op, value: n}, nil
+	}
+	return nil, fmt.Errorf("unknown field %q (quote the value to search for a colon)", field)
+}
+
+func parseQueryNumber(field, value string) (float64, error) {
+	switch field {
+	case "mem":
+		b, err := ParseBytes(value)
+		return float64(b), err
+	case "uptime":
+		d, err := ParseDuration(value)
+		return float64(d / time.Second), err
+	case "cpu":
+		f, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid percentage %q", value)
+		}
+		return f, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return float64(n), nil
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokLParen
+	tokRParen
+	tokOr
+	tokAnd
+	tokNot
+)
+
+type token struct {
+	kind  tokenKind
+	field string // tokWord: the part before the first unquoted colon
+	value string // tokWord: the rest, with quotes removed
+	text  string // as written, for error messages
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of filter"
+	}
+	return strconv.Quote(t.text)
+}
+
+func lexQuery(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen, text: "("})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")"})
+			i++
+		case c == '|':
+			toks = append(toks, token{kind: tokOr, text: "|"})
+			i++
+		case c == '&':
+			toks = append(toks, token{kind: tokAnd, text: "&"})
+			i++
+		case c == '-' || c == '!':
+			toks = append(toks, token{kind: tokNot, text: string(c)})
+			i++
+		default:
+			tok, n, err := lexWord(s[i:])
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, tok)
+			i += n
+		}
+	}
+	return toks, nil
+}
+
+// lexWord reads a word, which runs until a space or parenthesis outside of
+// double quotes, and returns it along with the number of bytes consumed.
+// The word is split into a field and value at the first unquoted colon,
+// unless what precedes it is a backend name, as in the key "tmux:work".
+func lexWord(s string) (token, int, error) {
+	var value strings.Builder
+	field := ""
+	quoted := false
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		if c == ' ' || c == '\t' || c == '(' || c == ')' || c == '|' {
+			break
+		}
+		switch {
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return token{}, 0, errors.New("unterminated quote")
+			}
+			value.WriteString(s[i+1 : i+1+end])
+			quoted = true
+			i += end + 2
+			continue
+		case c == ':' && field == "" && !quoted && value.Len() > 0 && !slices.Contains(BackendNames, strings.ToLower(value.String())):
+			field = strings.ToLower(value.String())
+			value.Reset()
+		default:
+			value.WriteByte(c)
+		}
+		i++
+	}
+	tok := token{kind: tokWord, field: field, value: value.String(), text: s[:i]}
+	if !quoted && field == "" {
+		switch tok.value {
+		case "OR":
+			tok.kind = tokOr
+		case "AND":
+			tok.kind = tokAnd
+		case "NOT":
+			tok.kind = tokNot
+		}
+	}
+	return tok, i, nil
+}
+
+// Parser
+
+type queryParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *queryParser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{kind: tokEOF}
+}
+
+func (p *queryParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// parseOr parses terms joined by OR.
+func (p *queryParser) parseOr() (queryNode, error) {
+	var nodes orNode
+	for {
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if p.peek().kind != tokOr {
+			break
+		}
+		p.next()
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+// parseAnd parses a run of terms, optionally separated by AND.
+func (p *queryParser) parseAnd() (queryNode, error) {
+	var nodes andNode
+	for {
+		switch p.peek().kind {
+		case tokEOF, tokRParen, tokOr:
+			if len(nodes) == 0 {
+				return nil, fmt.Errorf("expected a term before %s", p.peek())
+			}
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return nodes, nil
+		case tokAnd:
+			if len(nodes) == 0 {
+				return nil, fmt.Errorf("expected a term before %s", p.peek())
+			}
+			and := p.next()
+			if k := p.peek().kind; k != tokWord && k != tokLParen && k != tokNot {
+				return nil, fmt.Errorf("expected a term after %s", and)
+			}
+		}
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNot:
+		if k := p.peek().kind; k != tokWord && k != tokLParen && k != tokNot {
+			return nil, fmt.Errorf("expected a term after %s", t)
+		}
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, errors.New(`missing ")"`)
+		}
+		return n, nil
+	case tokWord:
+		return newTerm(t.field, t.value)
+	}
+	return nil, fmt.Errorf("unexpected %s", t)
+}
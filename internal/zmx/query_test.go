@@ -0,0 +1,121 @@
+package zmx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseQueryMatches(t *testing.T) {
+	sessions := []Session{
+		{Name: "api-server", PID: "1234", Clients: 0, StartedIn: "/home/me/work/api", Cmd: "npm run dev", Memory: 2 << 30, Uptime: 8 * 86400, Tags: []string{"ci", "prod"}},
+		{Name: "editor", PID: "2000", Clients: 1, StartedIn: "/home/me/work/web", Cmd: "vim", Memory: 200 << 20, Uptime: 3600, Note: "Alice's review.\nAsk before killing."},
+		{Name: "my notes", PID: "3000", Clients: 0, StartedIn: "/tmp", Cmd: "zsh", Memory: 50 << 20, Uptime: 10 * 86400, Backend: "tmux", Alias: "scratch"},
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"api-server", "editor", "my notes"}},
+		{"API", []string{"api-server"}},
+		{"work", []string{"api-server", "editor"}},
+		{"name:edit", []string{"editor"}},
+		{"dir:/home/me/work cmd:vim", []string{"editor"}},
+		{"clients:0 mem:>1G uptime:>7d", []string{"api-server"}},
+		{"clients:0 mem:>=50M", []string{"api-server", "my notes"}},
+		{"pid:1234", []string{"api-server"}},
+		{"-cmd:vim", []string{"api-server", "my notes"}},
+		{"!clients:0", []string{"editor"}},
+		{"NOT clients:0", []string{"editor"}},
+		{"cmd:vim OR cmd:zsh", []string{"editor", "my notes"}},
+		{"cmd:vim | uptime:<2h", []string{"editor"}},
+		{"clients:0 AND (cmd:npm OR cmd:zsh)", []string{"api-server", "my notes"}},
+		{`"my notes"`, []string{"my notes"}},
+		{`name:"tmux:my"`, []string{"my notes"}},
+		{"tmux:my", []string{"my notes"}},
+		{"TMUX:my -zmx:api", []string{"my notes"}},
+		{"-(clients:0 mem:<100M)", []string{"api-server", "editor"}},
+		{"tag:prod", []string{"api-server"}},
+		{"tag:#CI", []string{"api-server"}},
+		{"tag:pro", nil},
+		{"note:alice", []string{"editor"}},
+		{"scratch", []string{"my notes"}},
+		{"name:scr -tag:ci", []string{"my notes"}},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, s := range FilterSessions(sessions, q) {
+			got = append(got, s.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := map[string]string{
+		"mem:>":          "mem: missing value after >",
+		"name:":          "name: missing value",
+		"mem:lots":       "mem:",
+		"(api":           `missing ")"`,
+		"api)":           `unexpected ")"`,
+		`"api`:           "unterminated quote",
+		"api OR":         "expected a term before end of filter",
+		"nme:api":        `unknown field "nme"`,
+		"uptme:>2d":      `unknown field "uptme"`,
+		"api AND":        `expected a term after "AND"`,
+		"api & ) web":    `expected a term after "&"`,
+		"OR api":         `expected a term before "OR"`,
+		"clients:lots":   `clients: invalid number "lots"`,
+		"-":              "expected a term after",
+		"uptime:>2 days": "uptime:",
+	}
+	for query, want := range tests {
+		_, err := ParseQuery(query)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseQuery(%q) error = %v, want %q", query, err, want)
+		}
+	}
+}
+
+func TestQueryTermsSkipNegatedAndNonNameFields(t *testing.T) {
+	q, err := ParseQuery(`api name:Srv -old cmd:vim (web OR "my notes") tmux:Work`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"api", "srv", "web", "my notes", "tmux:work"}; !slices.Equal(q.Terms(), want) {
+		t.Fatalf("Terms = %q, want %q", q.Terms(), want)
+	}
+}
+
+func TestFuzzyFilterRanksAndReportsPositions(t *testing.T) {
+	sessions := []Session{
+		{Name: "papi-server"},
+		{Name: "api-server"},
+		{Name: "web", StartedIn: "/home/me/apisrv"},
+		{Name: "db"},
+	}
+	results := FuzzyFilter(sessions, "apisrv")
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Session.Name)
+	}
+	if want := []string{"api-server", "papi-server", "web"}; !slices.Equal(names, want) {
+		t.Fatalf("ranking = %v, want %v", names, want)
+	}
+	if want := []int{0, 1, 2, 4, 6, 7}; !slices.Equal(results[0].Positions, want) {
+		t.Fatalf("positions = %v, want %v", results[0].Positions, want)
+	}
+	if results[2].Positions != nil {
+		t.Fatalf("a directory match should not highlight the name, got %v", results[2].Positions)
+	}
+	if got := FuzzyFilter(sessions, "srv db"); len(got) != 0 {
+		t.Fatalf("every word must match, got %d results", len(got))
+	}
+}
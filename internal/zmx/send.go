@@ -0,0 +1,104 @@
+package zmx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errKeysUnsupported = errors.New("zmx sessions take text only, as zmx run submits all input with Enter")
+
+// Input is what SendInput delivers to a session: a line of text, typed and
+// submitted with Enter, or a sequence of named keys (see ParseKeys).
+type Input struct {
+	Text string
+	Keys []string
+}
+
+// String describes the input for logs, e.g. `"git pull"` or "ctrl-c enter".
+func (in Input) String() string {
+	if len(in.Keys) > 0 {
+		return strings.Join(in.Keys, " ")
+	}
+	return fmt.Sprintf("%q", in.Text)
+}
+
+// keyBytes returns what a terminal sends for the keys.
+func (in Input) keyBytes() string {
+	var b strings.Builder
+	for _, k := range in.Keys {
+		b.WriteString(namedKeys[k].seq)
+	}
+	return b.String()
+}
+
+// namedKey is a key accepted by ParseKeys: the bytes a terminal sends for
+// it and its name in tmux's send-keys.
+type namedKey struct {
+	seq  string
+	tmux string
+}
+
+var namedKeys = map[string]namedKey{
+	"enter":     {"\r", "Enter"},
+	"tab":       {"\t", "Tab"},
+	"esc":       {"\x1b", "Escape"},
+	"space":     {" ", "Space"},
+	"backspace": {"\x7f", "BSpace"},
+	"up":        {"\x1b[A", "Up"},
+	"down":      {"\x1b[B", "Down"},
+	"right":     {"\x1b[C", "Right"},
+	"left":      {"\x1b[D", "Left"},
+}
+
+func init() {
+	for c := 'a'; c <= 'z'; c++ {
+		namedKeys["ctrl-"+string(c)] = namedKey{string(c - 'a' + 1), "C-" + string(c)}
+	}
+	namedKeys["ctrl-\\"] = namedKey{"\x1c", `C-\`}
+}
+
+// ParseKeys parses a space- or comma-separated list of key names such as
+// "ctrl-c", "C-d", "^z", "enter", "esc" or "up" into canonical names.
+func ParseKeys(spec string) ([]string, error) {
+	var keys []string
+	for _, tok := range strings.FieldsFunc(spec, func(r rune) bool { return r == ' ' || r == ',' }) {
+		name := strings.ToLower(tok)
+		for _, prefix := range []string{"^", "c-", "ctrl+", "control-"} {
+			if rest, ok := strings.CutPrefix(name, prefix); ok && len(rest) == 1 {
+				name = "ctrl-" + rest
+			}
+		}
+		switch name {
+		case "return", "cr":
+			name = "enter"
+		case "escape":
+			name = "esc"
+		}
+		if _, ok := namedKeys[name]; !ok {
+			return nil, fmt.Errorf("unknown key %q (try ctrl-c, enter, esc, tab or up)", tok)
+		}
+		keys = append(keys, name)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys given")
+	}
+	return keys, nil
+}
+
+// SendsKeys reports whether named keys can be sent to s. zmx sessions only
+// take input through `zmx run`, which follows it with Enter, so they are
+// limited to lines of text.
+func SendsKeys(s Session) bool {
+	_, ok := backendFor(s).(zmxBackend)
+	return !ok
+}
+
+// SendInput types in into s through its backend, as if from an attached
+// client.
+func SendInput(s Session, in Input) error {
+	if s.Degraded() {
+		return fmt.Errorf("%s is unreachable", s.Host)
+	}
+	return backendFor(s).Send(s, in)
+}
@@ -1,9 +1,16 @@
 package zmx
 
 import (
+	"context"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestFormatBytes(t *testing.T) {
@@ -87,6 +94,95 @@ func TestSumTreeRSS_DisjointTrees(t *testing.T) {
 	}
 }
 
+func TestProcessTableInfo(t *testing.T) {
+	now := time.Now()
+	table := newProcessTable()
+	table.add(10, procEntry{ppid: 1, rss: 100, start: now.Add(-90 * time.Second), threads: 2, state: 'S'})
+	table.add(11, procEntry{ppid: 10, rss: 50, threads: 3, state: 'R'})
+	table.add(12, procEntry{ppid: 11, rss: 25, threads: 1, state: 'Z'})
+
+	got := table.info(10, now)
+	if got.Memory != 175 || got.Threads != 6 || got.State != "sleeping" || got.Uptime != 90 {
+		t.Fatalf("info(10) = %+v, want memory 175, 6 threads, sleeping, uptime 90", got)
+	}
+	if got := table.info(99, now); got.Memory != 0 || got.Threads != 0 || got.State != "" || !got.Started.IsZero() {
+		t.Fatalf("info(99) = %+v, want empty info for unknown pid", got)
+	}
+}
+
+func TestReadPSTable(t *testing.T) {
+	orig := deps
+	defer func() { deps = orig }()
+
+	deps.command = func(name string, arg ...string) *exec.Cmd {
+		script := "printf '  PID  PPID  PGID TPGID   RSS     ELAPSED     TIME S\n    1     0     1    -1  1024  1-00:00:00 00:00:10 S\n   20     1    20    -1  2048       01:30 00:01:05 R\n'"
+		return exec.Command("sh", "-c", script)
+	}
+
+	table := readPSTable(Host{Name: "build1"})
+	if len(table.procs) != 2 {
+		t.Fatalf("parsed %d processes, want 2: %+v", len(table.procs), table.procs)
+	}
+	info := table.info(1, time.Now())
+	if info.Memory != 3*1024*1024 || info.State != "sleeping" || info.CPUTime != 75*time.Second {
+		t.Fatalf("info(1) = %+v, want 3MiB sleeping with 75s CPU", info)
+	}
+	if info.Uptime < 86400 || info.Uptime > 86402 {
+		t.Fatalf("info(1).Uptime = %d, want ~86400", info.Uptime)
+	}
+}
+
+func TestParseCPUTime(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"00:00:05", 5 * time.Second},                         // Linux
+		{"1-02:00:00", 26 * time.Hour},                        // Linux, over a day
+		{"0:01.50", 1500 * time.Millisecond},                  // macOS
+		{"12:30.25", 12*time.Minute + 30250*time.Millisecond}, // macOS
+	}
+	for _, tt := range tests {
+		if got := parseCPUTime(tt.input); got != tt.want {
+			t.Errorf("parseCPUTime(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCPUSampler(t *testing.T) {
+	start := time.Now()
+	var sampler CPUSampler
+
+	// First sample: lifetime average is kept.
+	info := map[string]ProcessInfo{"api": {CPUTime: time.Second, CPU: 5, Sampled: start}}
+	sampler.Sample(info)
+	if info["api"].CPU != 5 {
+		t.Fatalf("first sample CPU = %g, want lifetime average 5", info["api"].CPU)
+	}
+
+	// 1.5s of CPU over 2s of wall time is 75%.
+	info = map[string]ProcessInfo{"api": {CPUTime: 2500 * time.Millisecond, CPU: 5, Sampled: start.Add(2 * time.Second)}}
+	sampler.Sample(info)
+	if got := info["api"].CPU; got < 74.9 || got > 75.1 {
+		t.Fatalf("interval CPU = %g, want 75", got)
+	}
+
+	// CPU time going backwards (reused PID) falls back to the average.
+	info = map[string]ProcessInfo{"api": {CPUTime: 0, CPU: 1, Sampled: start.Add(4 * time.Second)}}
+	sampler.Sample(info)
+	if info["api"].CPU != 1 {
+		t.Fatalf("reset CPU = %g, want lifetime average 1", info["api"].CPU)
+	}
+}
+
+func TestFormatCPU(t *testing.T) {
+	for pct, want := range map[float64]string{0: "0.0%", 0.42: "0.4%", 12.4: "12%", 250: "250%"} {
+		if got := FormatCPU(pct); got != want {
+			t.Errorf("FormatCPU(%g) = %q, want %q", pct, got, want)
+		}
+	}
+}
+
 func TestParseEtime(t *testing.T) {
 	tests := []struct {
 		input string
@@ -131,24 +227,53 @@ func TestFormatUptime(t *testing.T) {
 	}
 }
 
-func TestTailLinesFromReader(t *testing.T) {
-	input := "one\n\x1b[31mtwo\x1b[0m\nthree\nfour\n"
-	got, err := tailLinesFromReader(strings.NewReader(input), 2)
-	if err != nil {
-		t.Fatalf("tailLinesFromReader error: %v", err)
+func TestSortSessionsBreaksTiesByKey(t *testing.T) {
+	sessions := []Session{
+		{Name: "work", Host: "build1", Clients: 1},
+		{Name: "work", Backend: "tmux", Clients: 1},
+		{Name: "work", Clients: 1},
+		{Name: "api", Clients: 1},
+	}
+	for _, mode := range []SortMode{SortByName, SortByClients} {
+		SortSessions(sessions, mode, true)
+		var got []string
+		for _, s := range sessions {
+			got = append(got, s.Key())
+		}
+		want := []string{"api", "tmux:work", "work", "work@build1"}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %q, want %q", mode, got, want)
+		}
 	}
-	if got != "three\nfour" {
-		t.Fatalf("tailLinesFromReader = %q, want %q", got, "three\nfour")
+}
+
+func TestFetchPreviewAppliesCursorMovementAndKeepsColor(t *testing.T) {
+	orig := deps
+	defer func() { deps = orig }()
+
+	deps.commandContext = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		// A progress line redrawn in place, then a red word.
+		script := `printf 'build 10%%\rbuild 100%%\n\033[31mdone\033[0m\n'`
+		return exec.CommandContext(ctx, "sh", "-c", script)
+	}
+
+	got := FetchPreview(Session{Name: "demo"}, 20, 5)
+	want := "build 100%\n\x1b[0;31mdone\x1b[m"
+	if got != want {
+		t.Fatalf("FetchPreview = %q, want %q", got, want)
 	}
 }
 
-func TestTailLinesFromReader_AtLeastOneLine(t *testing.T) {
-	got, err := tailLinesFromReader(strings.NewReader("solo\n"), 1)
-	if err != nil {
-		t.Fatalf("tailLinesFromReader error: %v", err)
+func TestScrollPreviewIsCellAware(t *testing.T) {
+	raw := "\x1b[0;31mab日本\x1b[m"
+	got := ScrollPreview(raw, 1, 4)
+	// Cutting at cell 1 keeps the color, and the wide 本 straddling the right
+	// edge is dropped and replaced with padding.
+	if plain := stripANSI(got); plain != "b日 " {
+		t.Fatalf("ScrollPreview plain = %q, want %q", plain, "b日 ")
 	}
-	if got != "solo" {
-		t.Fatalf("tailLinesFromReader = %q, want %q", got, "solo")
+	if !strings.HasPrefix(got, "\x1b[0;31m") {
+		t.Fatalf("ScrollPreview dropped the leading SGR: %q", got)
 	}
 }
 
@@ -187,3 +312,360 @@ func TestCopyToClipboardUsesInjectedDeps(t *testing.T) {
 		t.Fatalf("clipboard text = %q, want %q", copied, "zmx attach demo")
 	}
 }
+
+func TestGrepSearchesEverySessionWithContext(t *testing.T) {
+	orig := deps
+	defer func() { deps = orig }()
+
+	deps.commandContext = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		// arg is "history <name> --vt"; each session prints its name on the
+		// second line, and "down" fails.
+		script := `[ "$1" = down ] && exit 1; printf 'start\n\033[31mport 8080 in use by %s\033[m\nend\n' "$1"`
+		return exec.CommandContext(ctx, "sh", "-c", script, "sh", arg[1])
+	}
+
+	sessions := []Session{{Name: "api"}, {Name: "down"}, {Name: "web"}}
+	results := Grep(sessions, func(line string) bool {
+		return strings.Contains(line, "8080")
+	}, GrepOptions{Width: 80, Height: 10, Lines: 100, Context: 1, Workers: 2})
+
+	if len(results) != 3 {
+		t.Fatalf("Grep returned %d results, want 3: %+v", len(results), results)
+	}
+	if results[1].Session.Name != "down" || results[1].Err == nil {
+		t.Fatalf("results[1] = %+v, want an error for down", results[1])
+	}
+	got := results[2]
+	if got.Session.Name != "web" || len(got.Matches) != 1 {
+		t.Fatalf("results[2] = %+v", got)
+	}
+	want := GrepMatch{Line: 1, Text: "port 8080 in use by web", Before: []string{"start"}, After: []string{"end"}}
+	if m := got.Matches[0]; m.Line != want.Line || m.Text != want.Text ||
+		!slices.Equal(m.Before, want.Before) || !slices.Equal(m.After, want.After) {
+		t.Fatalf("match = %+v, want %+v", m, want)
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	s := Session{Name: "demo", Backend: "tmux", Host: "build1"}
+	history := []byte("one\r\n\x1b[31mtwo\x1b[m\n")
+	opts := ExportOptions{Width: 20, Height: 5}
+	export := func(f ExportFormat) string {
+		var b strings.Builder
+		if err := Export(&b, s, history, f, opts); err != nil {
+			t.Fatalf("Export(%s) error: %v", f, err)
+		}
+		return b.String()
+	}
+
+	if got := export(ExportText); got != "one\ntwo\n" {
+		t.Errorf("text = %q", got)
+	}
+	if got := export(ExportANSI); got != string(history) {
+		t.Errorf("ansi = %q", got)
+	}
+	if got := export(ExportHTML); !strings.Contains(got, `<span style="color:#cd0000">two</span>`) {
+		t.Errorf("html missing the red line:\n%s", got)
+	}
+	cast := strings.Split(strings.TrimSpace(export(ExportCast)), "\n")
+	if len(cast) != 3 || !strings.HasPrefix(cast[0], `{"version":2,"width":20,"height":5,`) {
+		t.Fatalf("cast = %q", cast)
+	}
+	if cast[1] != `[0,"o","one\r\n"]` || cast[2] != `[0,"o","\u001b[31mtwo\u001b[m\r\n"]` {
+		t.Errorf("cast events = %q", cast[1:])
+	}
+
+	if got := ExportFileName(s, ExportHTML); got != "tmux_demo@build1.html" {
+		t.Errorf("ExportFileName = %q", got)
+	}
+	if f, ok := ExportFormatForPath("out/x.cast"); !ok || f != ExportCast {
+		t.Errorf("ExportFormatForPath(x.cast) = %v, %v", f, ok)
+	}
+	if _, err := ParseExportFormat("pdf"); err == nil {
+		t.Error("ParseExportFormat(pdf) should fail")
+	}
+}
+
+func TestExportPathsNeverReuseAName(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "proj_api.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sessions := []Session{{Name: "proj/api"}, {Name: "proj_api"}, {Name: "proj:api"}, {Name: "web"}}
+	var got []string
+	for _, p := range ExportPaths(dir, sessions, ExportText) {
+		got = append(got, filepath.Base(p))
+	}
+	want := []string{"proj_api-2.txt", "proj_api-3.txt", "proj_api-4.txt", "web.txt"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ExportPaths = %q, want %q", got, want)
+	}
+}
+
+func TestFetchPreviewContextStopsWhenCancelled(t *testing.T) {
+	orig := deps
+	defer func() { deps = orig }()
+
+	deps.commandContext = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", "echo partial; exec sleep 5")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	got := FetchPreviewContext(ctx, Session{Name: "slow"}, 20, 5)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("FetchPreviewContext took %v after cancel", elapsed)
+	}
+	if !strings.Contains(got, "canceled") {
+		t.Fatalf("FetchPreviewContext = %q, want a cancellation message", got)
+	}
+}
+
+func TestGrepContextStopsWhenCancelled(t *testing.T) {
+	orig := deps
+	defer func() { deps = orig }()
+
+	var mu sync.Mutex
+	started := 0
+	deps.commandContext = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		mu.Lock()
+		started++
+		mu.Unlock()
+		return exec.CommandContext(ctx, "sh", "-c", "exec sleep 5")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	sessions := []Session{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+	GrepContext(ctx, sessions, func(string) bool { return true }, GrepOptions{Width: 20, Height: 5, Workers: 1})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("GrepContext took %v after cancel", elapsed)
+	}
+	if started != 1 {
+		t.Fatalf("GrepContext fetched %d histories, want only the one running when cancelled", started)
+	}
+}
+
+func TestKillGracefullyStopsAtFirstEffectiveStage(t *testing.T) {
+	orig, origHosts := deps, remoteHosts
+	defer func() { deps, remoteHosts = orig, origHosts }()
+	UseHosts([]string{"build1"})
+
+	// The zmx daemon (pid 100) runs a shell (150) whose terminal's
+	// foreground group is postgres (200) and its worker (201). postgres
+	// ignores SIGINT and shuts down, ending the session, on SIGTERM.
+	var mu sync.Mutex
+	var signals []string
+	terminated := false
+	deps.command = func(name string, arg ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		remote := name == "ssh"
+		cmd := strings.Join(arg, " ")
+		if remote {
+			cmd = arg[len(arg)-1]
+		}
+		out := ""
+		switch {
+		case strings.HasPrefix(cmd, "kill "):
+			signals = append(signals, cmd)
+			terminated = terminated || strings.HasPrefix(cmd, "kill -TERM")
+		case remote && strings.HasPrefix(cmd, "zmx list") && !terminated:
+			out = "session_name=db\tpid=100\tclients=0\n"
+		case remote && strings.HasPrefix(cmd, "ps "):
+			out = "  PID  PPID  PGID TPGID   RSS ELAPSED     TIME S\n" +
+				"  100     1   100    -1  1024   01:00 00:00:01 S\n"
+			if !terminated {
+				out += "  150   100   150   200   512   01:00 00:00:00 S\n" +
+					"  200   150   200   200  2048   01:00 00:00:01 S\n" +
+					"  201   200   200   200  1024   01:00 00:00:01 S\n"
+			}
+		}
+		return exec.Command("printf", "%s", out)
+	}
+
+	s := Session{Name: "db", PID: "100", Backend: "zmx", Host: "build1"}
+	stage, err := KillGracefully(s, 500*time.Millisecond)
+	if err != nil || stage != KillTerminate {
+		t.Fatalf("KillGracefully = %v, %v; want SIGTERM", stage.Label(s), err)
+	}
+	if want := []string{"kill -INT 200 201", "kill -TERM 200 201"}; !slices.Equal(signals, want) {
+		t.Fatalf("signals = %q, want %q", signals, want)
+	}
+}
+
+func TestKillGracefullySkipsSignalsForIdleShell(t *testing.T) {
+	orig, origHosts := deps, remoteHosts
+	defer func() { deps, remoteHosts = orig, origHosts }()
+	UseHosts([]string{"build1"})
+
+	// The shell (150) is at a prompt: its own group is in the foreground.
+	var mu sync.Mutex
+	var calls []string
+	killed := false
+	deps.command = func(name string, arg ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		cmd := arg[len(arg)-1]
+		calls = append(calls, cmd)
+		out := ""
+		switch {
+		case strings.HasPrefix(cmd, "zmx kill"):
+			killed = true
+		case strings.HasPrefix(cmd, "zmx list") && !killed:
+			out = "session_name=db\tpid=100\tclients=0\n"
+		case strings.HasPrefix(cmd, "ps "):
+			out = "  PID  PPID  PGID TPGID   RSS ELAPSED     TIME S\n" +
+				"  100     1   100    -1  1024   01:00 00:00:01 S\n" +
+				"  150   100   150   150   512   01:00 00:00:00 S\n"
+		}
+		return exec.Command("printf", "%s", out)
+	}
+
+	s := Session{Name: "db", PID: "100", Backend: "zmx", Host: "build1"}
+	start := time.Now()
+	stage, err := KillGracefully(s, 5*time.Second)
+	if err != nil || stage != KillBackend {
+		t.Fatalf("KillGracefully = %v, %v; want zmx kill", stage.Label(s), err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("KillGracefully took %v, should not wait out the grace period", elapsed)
+	}
+	for _, c := range calls {
+		if strings.HasPrefix(c, "kill ") {
+			t.Fatalf("an idle shell should not be signalled, ran %q", c)
+		}
+	}
+}
+
+func TestProtectionPatternsAndSavedMarks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zsm", "protected")
+	p, err := NewProtection([]string{"prod-*"}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tunnel := Session{Name: "prod-tunnel", Host: "build1"}
+	migrate := Session{Name: "migrate", Backend: "tmux"}
+	if !p.Protected(tunnel) || !p.Pinned(tunnel) || p.Protected(migrate) {
+		t.Fatal("only the session matching prod-* should start protected")
+	}
+	if err := p.SetMarked([]string{migrate.Key()}, true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "tmux:migrate\n" {
+		t.Fatalf("saved marks = %q", data)
+	}
+
+	reloaded, err := NewProtection(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.Protected(migrate) || reloaded.Protected(tunnel) {
+		t.Fatal("marks should survive a reload, patterns should not")
+	}
+
+	// Only marks of sessions the listing covers are pruned: tmux is not an
+	// active backend, nor build1 a configured host, until it is.
+	origHosts := remoteHosts
+	defer func() { remoteHosts = origHosts }()
+	gone, remote := Session{Name: "gone"}, Session{Name: "gone", Host: "build1"}
+	if err := reloaded.SetMarked([]string{gone.Key(), remote.Key()}, true); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := reloaded.Prune(nil)
+	if err != nil || !slices.Equal(removed, []string{"gone"}) {
+		t.Fatalf("Prune = %q, %v; want only gone", removed, err)
+	}
+	UseHosts([]string{"build1"})
+	if removed, _ := reloaded.Prune(nil); !slices.Equal(removed, []string{"gone@build1"}) {
+		t.Fatalf("Prune with build1 listed = %q", removed)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "tmux:migrate\n" {
+		t.Fatalf("pruned marks = %q", data)
+	}
+	if _, err := NewProtection([]string{"[z-a]"}, ""); err == nil {
+		t.Fatal("expected invalid pattern error")
+	}
+}
+
+func TestMetaStoreSavesAppliesAndPrunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zsm", "meta.json")
+	st, err := LoadMetaStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp3 := Session{Name: "tmp3"}
+	remote := Session{Name: "build", Host: "build1"}
+	if err := st.Set(tmp3, Meta{Alias: " deploy fix ", Tags: ParseTags("#alice, ci alice"), Note: "Safe to kill after the release.\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Set(remote, Meta{Tags: []string{"ci"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadMetaStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := []Session{tmp3, {Name: "other"}}
+	reloaded.Apply(sessions)
+	want := Session{Name: "tmp3", Alias: "deploy fix", Tags: []string{"alice", "ci"}, Note: "Safe to kill after the release."}
+	if !reflect.DeepEqual(sessions[0], want) || sessions[1].Tags != nil {
+		t.Fatalf("applied sessions = %+v", sessions)
+	}
+	if got := sessions[0].DisplayName(); got != "deploy fix" {
+		t.Fatalf("DisplayName = %q, want the alias", got)
+	}
+
+	// build1 is not a configured host, so its entry must survive.
+	removed, err := reloaded.Prune([]Session{{Name: "other"}})
+	if err != nil || !slices.Equal(removed, []string{"tmp3"}) {
+		t.Fatalf("Prune = %v, %v; want [tmp3]", removed, err)
+	}
+	if reloaded.Get(remote.Key()).Tags == nil {
+		t.Fatal("Prune forgot a session of a host that was not listed")
+	}
+	if err := reloaded.Set(remote, Meta{}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); strings.TrimSpace(string(data)) != "{}" {
+		t.Fatalf("empty metadata should remove the entry, saved %s", data)
+	}
+}
+
+func TestGrouperKeys(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(repo, "cmd", "api")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+
+	tests := []struct {
+		mode GroupMode
+		s    Session
+		want string
+	}{
+		{GroupByPrefix, Session{Name: "proj/api"}, "proj"},
+		{GroupByPrefix, Session{Name: "proj/web/v2"}, "proj/web"},
+		{GroupByPrefix, Session{Name: "scratch"}, ""},
+		{GroupByPrefix, Session{Name: "proj/api", Host: "build1"}, "proj@build1"},
+		{GroupByDir, Session{Name: "a", StartedIn: outside}, outside},
+		{GroupByRepo, Session{Name: "a", StartedIn: sub}, repo},
+		{GroupByRepo, Session{Name: "b", StartedIn: outside}, ""},
+		{GroupByRepo, Session{Name: "c", StartedIn: "/srv", Host: "build1"}, "/srv@build1"},
+		{GroupByTag, Session{Name: "a", Tags: []string{"ci", "prod"}}, "ci, prod"},
+		{GroupByTag, Session{Name: "b"}, ""},
+	}
+	for _, tt := range tests {
+		g := Grouper{Mode: tt.mode, Delimiter: "/"}
+		if got := g.Key(tt.s); got != tt.want {
+			t.Errorf("%s key of %+v = %q, want %q", tt.mode, tt.s, got, tt.want)
+		}
+	}
+}
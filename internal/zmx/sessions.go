@@ -1,20 +1,75 @@
 package zmx
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
+	"sync"
+	"time"
 )
 
-// Session represents a zmx session parsed from `zmx list`.
+// After `zmx kill` returns, a session can linger in `zmx list` while it shuts
+// down. KillStep polls StillAlive every KillPollInterval, up to
+// KillPollAttempts times, before escalating to the next stage.
+const (
+	KillPollInterval = 200 * time.Millisecond
+	KillPollAttempts = 20
+)
+
+// Session represents a session listed by one of the backends.
 type Session struct {
-	Name      string
-	PID       string
-	Clients   int
-	StartedIn string
-	Cmd       string
-	Memory    uint64 // RSS of process tree in bytes
-	Uptime    int    // elapsed seconds from ps etime
+	Name      string `json:"name"`
+	PID       string `json:"pid"`
+	Clients   int    `json:"clients"`
+	StartedIn string `json:"started_in"`
+	Cmd       string `json:"cmd"`
+	Memory    uint64 `json:"memory"`         // RSS of process tree in bytes
+	Uptime    int    `json:"uptime"`         // elapsed seconds since the session process started
+	Backend   string `json:"backend"`        // source backend; empty means zmx
+	Host      string `json:"host,omitempty"` // ssh destination; empty means local
+
+	StartedAt time.Time `json:"started_at,omitzero"` // start of the session process
+	Threads   int       `json:"threads,omitempty"`   // threads across the process tree
+	State     string    `json:"state,omitempty"`     // state of the session process, e.g. "sleeping"
+	CPU       float64   `json:"cpu"`                 // CPU utilization of the process tree, % of one core
+
+	// Recorded by the user in the metadata store (see MetaStore.Apply).
+	Alias string   `json:"alias,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Note  string   `json:"note,omitempty"`
+
+	// Error is set on a placeholder entry standing in for a remote host
+	// whose sessions could not be listed.
+	Error string `json:"error,omitempty"`
+}
+
+// Key identifies the session across backends and hosts: local zmx sessions
+// are keyed by their bare name, other backends get a "<backend>:" prefix
+// and remote sessions an "@<host>" suffix, e.g. "tmux:work@build1".
+func (s Session) Key() string {
+	key := s.Name
+	if s.Backend != "" && s.Backend != "zmx" {
+		key = s.Backend + ":" + key
+	}
+	if s.Host != "" {
+		key += "@" + s.Host
+	}
+	return key
+}
+
+// DisplayName returns the session's alias, or its key if it has none.
+func (s Session) DisplayName() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Key()
+}
+
+// Degraded reports whether s is a placeholder for an unreachable host.
+func (s Session) Degraded() bool {
+	return s.Error != ""
 }
 
 // DisplayDir returns a shortened version of StartedIn, replacing $HOME with ~.
@@ -26,62 +81,111 @@ func (s Session) DisplayDir() string {
 	return s.StartedIn
 }
 
-// FetchSessions parses `zmx list` output into a slice of Session.
-// Format: tab-separated key=value pairs per line.
+// FetchSessions lists sessions from every active backend on the local
+// machine and from zmx on each remote host, concurrently. If a local
+// backend fails, the sessions from the others are still returned along
+// with an error naming the failures. A remote host that fails is reported
+// as a single degraded entry (see Session.Degraded) instead.
 func FetchSessions() ([]Session, error) {
-	out, err := runCombinedOutput("zmx", "list")
-	if err != nil {
-		return nil, fmt.Errorf("zmx list: %w\n%s", err, out)
+	type source struct {
+		host    Host
+		backend Backend
+	}
+	var sources []source
+	for _, b := range active {
+		sources = append(sources, source{Host{}, b})
+	}
+	for _, h := range remoteHosts {
+		sources = append(sources, source{h, zmxBackend{}})
 	}
 
+	lists := make([][]Session, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lists[i], errs[i] = src.backend.List(src.host)
+		}()
+	}
+	wg.Wait()
+
 	var sessions []Session
-	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+	var localErrs []error
+	for i, src := range sources {
+		switch {
+		case errs[i] == nil:
+			sessions = append(sessions, lists[i]...)
+		case src.host.local():
+			localErrs = append(localErrs, errs[i])
+		default:
+			// Keep ssh's stderr (appended after a newline) on one line.
+			msg := strings.Join(strings.Fields(strings.ReplaceAll(strings.TrimSpace(errs[i].Error()), "\n", " — ")), " ")
+			sessions = append(sessions, Session{Backend: src.backend.Name(), Host: src.host.Name, Error: msg})
 		}
+	}
+	return sessions, errors.Join(localErrs...)
+}
 
-		s := Session{}
-		for _, field := range strings.Split(line, "\t") {
-			k, v, ok := strings.Cut(field, "=")
-			if !ok {
-				continue
-			}
-			switch k {
-			case "session_name":
-				s.Name = v
-			case "pid":
-				s.PID = v
-			case "clients":
-				if v == "0" {
-					s.Clients = 0
-				} else {
-					n := 0
-					for _, c := range v {
-						n = n*10 + int(c-'0')
-					}
-					s.Clients = n
-				}
-			case "started_in":
-				s.StartedIn = v
-			case "cmd":
-				s.Cmd = v
-			}
-		}
-		if s.Name != "" {
-			sessions = append(sessions, s)
-		}
+// KillSession kills s through its backend.
+func KillSession(s Session) error {
+	if s.Degraded() {
+		return fmt.Errorf("%s is unreachable", s.Host)
 	}
-	return sessions, nil
+	return backendFor(s).Kill(s)
+}
+
+// CreateSession starts a detached session with the primary backend, running
+// command (if any) from dir.
+func CreateSession(name, dir string, command []string) error {
+	return primaryBackend().Create(name, dir, command)
+}
+
+// NewSession returns a not-yet-created Session for the primary backend,
+// suitable for AttachArgs.
+func NewSession(name string) Session {
+	return Session{Name: name, Backend: primaryBackend().Name()}
 }
 
-// KillSession runs `zmx kill <name>`.
-func KillSession(name string) error {
-	out, err := runCombinedOutput("zmx", "kill", name)
+// AttachArgs returns the argv for attaching to (or creating) s, optionally
+// running command in a new session. Remote sessions attach through
+// `ssh -t <host>`.
+func AttachArgs(s Session, command []string) []string {
+	argv := backendFor(s).AttachArgs(s, command)
+	if !hostOf(s).local() {
+		return []string{"ssh", "-t", s.Host, "--", shellJoin(argv)}
+	}
+	return argv
+}
+
+// AttachCommand returns an unstarted attach command for running the backend
+// as a child process, e.g. from a TUI that resumes after the user detaches.
+func AttachCommand(s Session, dir string, command []string) *exec.Cmd {
+	argv := AttachArgs(s, command)
+	cmd := deps.command(argv[0], argv[1:]...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// StillAlive returns the subset of keys (see Session.Key) that are still
+// listed by their backends.
+func StillAlive(keys []string) ([]string, error) {
+	sessions, err := FetchSessions()
 	if err != nil {
-		return fmt.Errorf("zmx kill %s: %w\n%s", name, err, out)
+		return nil, err
+	}
+	live := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		live[s.Key()] = true
+	}
+	var alive []string
+	for _, key := range keys {
+		if live[key] {
+			alive = append(alive, key)
+		}
 	}
-	return nil
+	return alive, nil
 }
 
 // CopyToClipboard copies text to the system clipboard.
@@ -0,0 +1,113 @@
+package zmx
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// SortMode selects the ordering applied by SortSessions.
+type SortMode int
+
+const (
+	SortByName SortMode = iota
+	SortByClients
+	SortByPID
+	SortByMemory
+	SortByUptime
+	SortByCPU
+	SortModeCount
+)
+
+func (s SortMode) String() string {
+	switch s {
+	case SortByName:
+		return "name"
+	case SortByClients:
+		return "clients"
+	case SortByPID:
+		return "pid"
+	case SortByMemory:
+		return "memory"
+	case SortByUptime:
+		return "uptime"
+	case SortByCPU:
+		return "cpu"
+	}
+	return ""
+}
+
+// ParseSortMode returns the SortMode whose String() equals s.
+func ParseSortMode(s string) (SortMode, error) {
+	for m := SortMode(0); m < SortModeCount; m++ {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown sort mode %q", s)
+}
+
+// FilterSessions returns the sessions matching q (see ParseQuery). The
+// result is always a fresh slice, so callers may sort it in place.
+func FilterSessions(sessions []Session, q *Query) []Session {
+	var filtered []Session
+	for _, s := range sessions {
+		if q.Match(s) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// SortSessions sorts sessions in place by mode. Ties are broken by key,
+// so sessions sharing a name across backends or hosts keep a stable order.
+func SortSessions(sessions []Session, mode SortMode, asc bool) {
+	dir := 1
+	if !asc {
+		dir = -1
+	}
+	switch mode {
+	case SortByName:
+		slices.SortFunc(sessions, func(a, b Session) int {
+			return cmp.Or(dir*cmp.Compare(a.Name, b.Name), cmp.Compare(a.Key(), b.Key()))
+		})
+	case SortByClients:
+		slices.SortFunc(sessions, func(a, b Session) int {
+			if a.Clients != b.Clients {
+				return dir * (a.Clients - b.Clients)
+			}
+			return cmp.Compare(a.Key(), b.Key())
+		})
+	case SortByPID:
+		slices.SortFunc(sessions, func(a, b Session) int {
+			ai, _ := strconv.Atoi(a.PID)
+			bi, _ := strconv.Atoi(b.PID)
+			if ai != bi {
+				return dir * (ai - bi)
+			}
+			return cmp.Compare(a.Key(), b.Key())
+		})
+	case SortByMemory:
+		slices.SortFunc(sessions, func(a, b Session) int {
+			if a.Memory != b.Memory {
+				return dir * cmp.Compare(a.Memory, b.Memory)
+			}
+			return cmp.Compare(a.Key(), b.Key())
+		})
+	case SortByUptime:
+		slices.SortFunc(sessions, func(a, b Session) int {
+			if a.Uptime != b.Uptime {
+				return dir * (a.Uptime - b.Uptime)
+			}
+			return cmp.Compare(a.Key(), b.Key())
+		})
+	case SortByCPU:
+		slices.SortFunc(sessions, func(a, b Session) int {
+			if a.CPU != b.CPU {
+				return dir * cmp.Compare(a.CPU, b.CPU)
+			}
+			return cmp.Compare(a.Key(), b.Key())
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package zmx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseBytes parses a size such as "512", "100K", "1.5G" or "2GiB" into bytes.
+// Suffixes are binary (K = 1024), matching FormatBytes.
+func ParseBytes(s string) (uint64, error) {
+	orig := s
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	mult := uint64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", orig)
+	}
+	return uint64(v * float64(mult)), nil
+}
+
+// ParseDuration parses a duration such as "90s", "3d", "2w" or "1d12h".
+// In addition to the units time.ParseDuration accepts, it understands
+// d (24h) and w (7d).
+func ParseDuration(s string) (time.Duration, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	var total time.Duration
+	for s != "" {
+		i := 0
+		for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+			i++
+		}
+		j := i
+		for j < len(s) && !(s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+			j++
+		}
+		num, unit := s[:i], s[i:j]
+		s = s[j:]
+
+		var d time.Duration
+		switch unit {
+		case "d", "w":
+			v, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+			d = time.Duration(v * float64(24*time.Hour))
+			if unit == "w" {
+				d *= 7
+			}
+		case "":
+			return 0, fmt.Errorf("invalid duration %q: missing unit", orig)
+		default:
+			var err error
+			d, err = time.ParseDuration(num + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+		}
+		total += d
+	}
+	return total, nil
+}
+
+// GlobRegexp converts a shell glob (*, ?, [...]) into an anchored regular
+// expression. Unlike path.Match, * also matches '/'.
+func GlobRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
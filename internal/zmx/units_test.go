@@ -0,0 +1,64 @@
+package zmx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"100K", 100 << 10},
+		{"2G", 2 << 30},
+		{"1.5g", 3 << 29},
+		{"2GiB", 2 << 30},
+		{"64MB", 64 << 20},
+	}
+	for _, tt := range tests {
+		got, err := ParseBytes(tt.input)
+		if err != nil {
+			t.Errorf("ParseBytes(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBytes(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "G", "abc", "-1G"} {
+		if _, err := ParseBytes(bad); err == nil {
+			t.Errorf("ParseBytes(%q) should fail", bad)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"90s", 90 * time.Second},
+		{"3d", 72 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.input)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "3", "d", "3x"} {
+		if _, err := ParseDuration(bad); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", bad)
+		}
+	}
+}
@@ -3,11 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/exec"
-	"syscall"
+	"strings"
 
-	tea "charm.land/bubbletea/v2"
-	"github.com/mdsakalu/zmx-session-manager/internal/tui"
+	"github.com/mdsakalu/zmx-session-manager/internal/cli"
 )
 
 var (
@@ -17,27 +15,15 @@ var (
 )
 
 func main() {
-	if len(os.Args) > 1 && (os.Args[1] == "-v" || os.Args[1] == "--version") {
-		fmt.Printf("zsm %s (%s, %s)\n", version, commit, date)
-		return
-	}
-
-	zmxPath, err := exec.LookPath("zmx")
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error: zmx not found in PATH")
-		os.Exit(1)
-	}
-
-	p := tea.NewProgram(tui.NewModel())
-	finalModel, err := p.Run()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-
-	// If the user pressed Enter to attach, exec into zmx attach
-	if m, ok := finalModel.(tui.Model); ok && m.AttachTarget() != "" {
-		env := os.Environ()
-		syscall.Exec(zmxPath, []string{"zmx", "attach", m.AttachTarget()}, env)
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch arg := args[0]; {
+		case arg == "-v" || arg == "--version":
+			fmt.Printf("zsm %s (%s, %s)\n", version, commit, date)
+			return
+		case !strings.HasPrefix(arg, "-"):
+			os.Exit(cli.Run(arg, args[1:]))
+		}
 	}
+	os.Exit(cli.RunTUI(args))
 }